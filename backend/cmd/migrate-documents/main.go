@@ -0,0 +1,45 @@
+// Command migrate-documents moves document content stored inside the
+// database into the configured document storage and backfills the
+// checksums used to deduplicate stored files. Run it from the backend
+// directory so .env and database/sibestie.db are found:
+//
+//	go run ./cmd/migrate-documents [-dry-run]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+
+	"sibestie/config"
+	"sibestie/controllers"
+	"sibestie/models"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report what would be moved")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	config.ConnectDatabase()
+	config.ConnectStorage()
+
+	if err := config.DB.AutoMigrate(&models.SourceFile{}, &models.VerifikasiDocument{}); err != nil {
+		log.Fatal("Migration error: ", err)
+	}
+
+	report, err := controllers.MigrateInlineDocuments(*dryRun)
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+	if err != nil {
+		log.Printf("Document migration failed: %v", err)
+		os.Exit(1)
+	}
+}
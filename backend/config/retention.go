@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// RetentionPolicy holds how long each category of personal data is kept.
+// A zero duration disables purging for that category.
+type RetentionPolicy struct {
+	// Uploaded documents of rejected applicants
+	RejectedDocuments time.Duration
+	// Personal data of rejected applications
+	RejectedRecords time.Duration
+	// Personal data of withdrawn applications
+	WithdrawnRecords time.Duration
+	// Personal data of approved applications
+	ApprovedRecords time.Duration
+	// Profile data (UserData, Family, SourceFile) not updated for this long,
+	// except the data a verification that is not anonymized was made on
+	InactiveProfiles time.Duration
+
+	// How often the scheduled job runs
+	Interval time.Duration
+}
+
+// LoadRetentionPolicy reads the retention periods from the environment
+func LoadRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		RejectedDocuments: envDays("RETENTION_REJECTED_DOCUMENTS_DAYS", 365),
+		RejectedRecords:   envDays("RETENTION_REJECTED_RECORDS_DAYS", 730),
+		WithdrawnRecords:  envDays("RETENTION_WITHDRAWN_RECORDS_DAYS", 90),
+		ApprovedRecords:   envDays("RETENTION_APPROVED_RECORDS_DAYS", 0),
+		InactiveProfiles:  envDays("RETENTION_INACTIVE_PROFILES_DAYS", 1095),
+		Interval:          time.Duration(envInt("RETENTION_INTERVAL_HOURS", 24)) * time.Hour,
+	}
+}
+
+// envDays reads a number of days from the environment as a duration
+func envDays(key string, fallback int) time.Duration {
+	return time.Duration(envInt(key, fallback)) * 24 * time.Hour
+}
+
+// envInt reads a non-negative integer from the environment
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
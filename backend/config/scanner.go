@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"sibestie/scanner"
+)
+
+var Scanner scanner.Scanner
+
+func ConnectScanner() {
+	switch driver := os.Getenv("SCANNER_DRIVER"); driver {
+	case "", "clamav":
+		network := os.Getenv("CLAMAV_NETWORK")
+		if network == "" {
+			network = "unix"
+		}
+		address := os.Getenv("CLAMAV_ADDRESS")
+		if address == "" {
+			address = "/var/run/clamav/clamd.ctl"
+		}
+		Scanner = scanner.NewClamAV(network, address)
+		fmt.Println("Malware scanning through clamd at " + address)
+	case "fake":
+		Scanner = scanner.NewFake()
+		fmt.Println("WARNING: malware scanning uses the fake scanner, uploads are not really scanned")
+	default:
+		fmt.Printf("Scanner initialization error: unknown SCANNER_DRIVER %q\n", driver)
+		panic("Gagal menyiapkan pemindai dokumen")
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"sibestie/storage"
+)
+
+var Storage storage.Storage
+
+func ConnectStorage() {
+	var err error
+
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		Storage, err = storage.NewLocal(dir)
+		if err == nil {
+			fmt.Println("Document storage ready at " + dir)
+		}
+	case "s3":
+		Storage, err = storage.NewS3(
+			os.Getenv("S3_ENDPOINT"),
+			os.Getenv("S3_REGION"),
+			os.Getenv("S3_BUCKET"),
+			os.Getenv("S3_ACCESS_KEY"),
+			os.Getenv("S3_SECRET_KEY"),
+			os.Getenv("S3_PATH_STYLE") == "true",
+		)
+		if err == nil {
+			fmt.Println("Document storage ready in bucket " + os.Getenv("S3_BUCKET"))
+		}
+	default:
+		err = fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+	}
+
+	if err != nil {
+		fmt.Printf("Storage initialization error: %v\n", err)
+		panic("Gagal menyiapkan penyimpanan dokumen")
+	}
+}
@@ -0,0 +1,777 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sibestie/config"
+	"sibestie/models"
+	"sibestie/tools/identity"
+
+	"gorm.io/gorm"
+)
+
+// maxSiblings caps the number of siblings of one submission
+const maxSiblings = 20
+
+// maxSemester is the highest semester number a grade can be recorded for
+const maxSemester = 14
+
+var (
+	errInvalidSiblings = errors.New("saudara must be a list of siblings")
+)
+
+// FieldError reports a problem with one field of a submission
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// SiblingData is one sibling of the applicant. Usia and Tanggungan are
+// pointers so that missing values can be reported.
+type SiblingData struct {
+	Nama             string `json:"nama"`
+	Usia             *int   `json:"usia"`
+	StatusPendidikan string `json:"status_pendidikan"`
+	Tanggungan       *bool  `json:"tanggungan"`
+	// Free-text status of siblings entered before they were structured
+	Keterangan string `json:"keterangan,omitempty"`
+
+	// legacy marks an entry of an older client, which only has a name and
+	// a free-text status
+	legacy bool
+}
+
+// SiblingList is the saudara field of a submission. Older clients send it
+// as a string holding a JSON list of {nama, status} entries; those are
+// accepted with status kept as keterangan and usia, status_pendidikan and
+// tanggungan left unset, and blank rows are dropped. Structured entries in
+// the string are read like the list itself.
+type SiblingList []SiblingData
+
+// UnmarshalJSON accepts the list itself or a string containing it
+func (l *SiblingList) UnmarshalJSON(b []byte) error {
+	var encoded string
+	if err := json.Unmarshal(b, &encoded); err != nil {
+		var siblings []SiblingData
+		if err := json.Unmarshal(b, &siblings); err != nil {
+			return errInvalidSiblings
+		}
+		*l = siblings
+		return nil
+	}
+
+	if strings.TrimSpace(encoded) == "" {
+		*l = nil
+		return nil
+	}
+	var entries []struct {
+		SiblingData
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(encoded), &entries); err != nil {
+		return errInvalidSiblings
+	}
+	siblings := make(SiblingList, 0, len(entries))
+	for _, entry := range entries {
+		sibling := entry.SiblingData
+		if sibling.Usia == nil && sibling.StatusPendidikan == "" && sibling.Tanggungan == nil {
+			// Older clients leave rows the applicant added but did not fill
+			if strings.TrimSpace(sibling.Nama) == "" && strings.TrimSpace(entry.Status) == "" {
+				continue
+			}
+			sibling.legacy = true
+			if sibling.Keterangan == "" {
+				sibling.Keterangan = strings.TrimSpace(entry.Status)
+			}
+		}
+		siblings = append(siblings, sibling)
+	}
+	*l = siblings
+	return nil
+}
+
+// Dependents counts the siblings the family still supports
+func (l SiblingList) Dependents() int {
+	count := 0
+	for _, sibling := range l {
+		if sibling.Tanggungan != nil && *sibling.Tanggungan {
+			count++
+		}
+	}
+	return count
+}
+
+// SemesterGradeData is the average grade of one semester. NilaiNormal is
+// the grade on the common 0-100 scale and is ignored on input.
+type SemesterGradeData struct {
+	Semester    int      `json:"semester"`
+	Nilai       *float64 `json:"nilai"`
+	Skala       string   `json:"skala"`
+	NilaiNormal float64  `json:"nilai_normal"`
+}
+
+// legacyGradeScale guesses the scale of a nilai_semester_1/2 value, which
+// older clients sent without one: above 10 it is 0-100, otherwise 0-10
+func legacyGradeScale(score float64) string {
+	if score > 10 {
+		return models.GradeScale100
+	}
+	return models.GradeScale10
+}
+
+// gradesFromData validates the semester grades of a submission and fills
+// in their normalized values. Older clients send nilai_semester_1 and
+// nilai_semester_2 instead of the nilai_semester list; those are used when
+// the list is empty. It returns one FieldError per invalid field.
+func gradesFromData(data *VerifikasiData) []FieldError {
+	var fieldErrors []FieldError
+
+	if len(data.NilaiSemester) == 0 {
+		for i, value := range []string{data.NilaiSemester1, data.NilaiSemester2} {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			score, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+			// ParseFloat also reads "NaN" and "Inf", which are no grades
+			if err != nil || math.IsNaN(score) || math.IsInf(score, 0) {
+				fieldErrors = append(fieldErrors, FieldError{Field: fmt.Sprintf("nilai_semester_%d", i+1), Message: "must be a number"})
+				continue
+			}
+			data.NilaiSemester = append(data.NilaiSemester, SemesterGradeData{Semester: i + 1, Nilai: &score, Skala: legacyGradeScale(score)})
+		}
+	}
+
+	seen := make(map[int]bool, len(data.NilaiSemester))
+	valid := make([]SemesterGradeData, 0, len(data.NilaiSemester))
+	for i, grade := range data.NilaiSemester {
+		field := func(name string) string {
+			return fmt.Sprintf("nilai_semester[%d].%s", i, name)
+		}
+		ok := true
+		switch {
+		case grade.Semester < 1 || grade.Semester > maxSemester:
+			fieldErrors = append(fieldErrors, FieldError{Field: field("semester"), Message: fmt.Sprintf("must be between 1 and %d", maxSemester)})
+			ok = false
+		case seen[grade.Semester]:
+			fieldErrors = append(fieldErrors, FieldError{Field: field("semester"), Message: "is listed more than once"})
+			ok = false
+		}
+		seen[grade.Semester] = true
+
+		if _, known := models.GradeScales[grade.Skala]; !known {
+			fieldErrors = append(fieldErrors, FieldError{Field: field("skala"),
+				Message: fmt.Sprintf("must be one of %s, %s, %s", models.GradeScale100, models.GradeScale10, models.GradeScale4)})
+			ok = false
+		} else if grade.Nilai == nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: field("nilai"), Message: "is required"})
+			ok = false
+		} else if normalized, inRange := models.NormalizeGrade(*grade.Nilai, grade.Skala); !inRange {
+			fieldErrors = append(fieldErrors, FieldError{Field: field("nilai"), Message: "must lie within the " + grade.Skala + " scale"})
+			ok = false
+		} else {
+			grade.NilaiNormal = normalized
+		}
+
+		if ok {
+			valid = append(valid, grade)
+		}
+	}
+	sort.Slice(valid, func(i, j int) bool { return valid[i].Semester < valid[j].Semester })
+	data.NilaiSemester = valid
+	return fieldErrors
+}
+
+// averageGrade returns the mean normalized grade of a grade history and
+// false when it is empty
+func averageGrade(grades []SemesterGradeData) (float64, bool) {
+	if len(grades) == 0 {
+		return 0, false
+	}
+	total := 0.0
+	for _, grade := range grades {
+		total += grade.NilaiNormal
+	}
+	return total / float64(len(grades)), true
+}
+
+// gradesFromHistory converts stored semester grades to the API form
+func gradesFromHistory(history []models.SemesterGrade) []SemesterGradeData {
+	grades := make([]SemesterGradeData, 0, len(history))
+	for _, grade := range history {
+		score := grade.Score
+		grades = append(grades, SemesterGradeData{
+			Semester:    grade.Semester,
+			Nilai:       &score,
+			Skala:       grade.Scale,
+			NilaiNormal: grade.Normalized,
+		})
+	}
+	return grades
+}
+
+// validateSiblings checks every sibling and returns one FieldError per
+// invalid field, named like saudara[0].usia. Entries of older clients only
+// need a name.
+func validateSiblings(siblings SiblingList) []FieldError {
+	var fieldErrors []FieldError
+	if len(siblings) > maxSiblings {
+		return append(fieldErrors, FieldError{Field: "saudara", Message: fmt.Sprintf("at most %d siblings can be listed", maxSiblings)})
+	}
+	for i, sibling := range siblings {
+		field := func(name string) string {
+			return fmt.Sprintf("saudara[%d].%s", i, name)
+		}
+		name := strings.TrimSpace(sibling.Nama)
+		switch {
+		case name == "":
+			fieldErrors = append(fieldErrors, FieldError{Field: field("nama"), Message: "is required"})
+		case len(name) > 100:
+			fieldErrors = append(fieldErrors, FieldError{Field: field("nama"), Message: "must be at most 100 characters"})
+		}
+		if sibling.legacy {
+			continue
+		}
+		switch {
+		case sibling.Usia == nil:
+			fieldErrors = append(fieldErrors, FieldError{Field: field("usia"), Message: "is required"})
+		case *sibling.Usia < 0 || *sibling.Usia > 100:
+			fieldErrors = append(fieldErrors, FieldError{Field: field("usia"), Message: "must be between 0 and 100"})
+		}
+		if !models.IsEducationStatus(sibling.StatusPendidikan) {
+			fieldErrors = append(fieldErrors, FieldError{Field: field("status_pendidikan"),
+				Message: "must be one of " + strings.Join(models.EducationStatuses, ", ")})
+		}
+		if sibling.Tanggungan == nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: field("tanggungan"), Message: "is required"})
+		}
+	}
+	return fieldErrors
+}
+
+// withApplicant preloads the applicant data and attached documents of the
+// verifications loaded by db
+func withApplicant(db *gorm.DB) *gorm.DB {
+	return db.Preload("UserData.SocialMedia").
+		Preload("UserData.Academic.Grades", func(db *gorm.DB) *gorm.DB {
+			return db.Order("semester")
+		}).
+		Preload("UserData.Family.Children").
+		Preload("Documents")
+}
+
+// parseBirthDate parses tanggal_lahir; an empty value means unknown
+func parseBirthDate(value string) (*time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	date, err := identity.ParseDate(value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+// bindingFieldErrors describes a request body that could not be decoded
+// as field errors
+func bindingFieldErrors(err error) []FieldError {
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, errInvalidSiblings):
+		return []FieldError{{Field: "saudara", Message: "must be a list of siblings"}}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return []FieldError{{Field: typeErr.Field, Message: "must be " + jsonTypeName(typeErr.Type)}}
+	default:
+		return []FieldError{{Field: "", Message: "request body must be a JSON object"}}
+	}
+}
+
+// jsonTypeName names a Go type the way it appears in a JSON body
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	default:
+		return "an object"
+	}
+}
+
+// validateSubmission checks the applicant data of a submission and
+// normalizes it in place: NIK and NISN are trimmed, the phone number is
+// converted to E.164, the sex is taken from the NIK when not given and the
+// grades are normalized. Empty fields are left to the completeness score.
+// It returns one FieldError per invalid field.
+func validateSubmission(data *VerifikasiData) []FieldError {
+	var fieldErrors []FieldError
+	add := func(field string, err error) {
+		fieldErrors = append(fieldErrors, FieldError{Field: field, Message: err.Error()})
+	}
+
+	var birthDate *time.Time
+	if data.TanggalLahir != "" {
+		date, err := identity.ParseDate(data.TanggalLahir)
+		if err != nil {
+			add("tanggal_lahir", err)
+		} else {
+			birthDate = &date
+		}
+	}
+
+	data.JenisKelamin = strings.ToUpper(strings.TrimSpace(data.JenisKelamin))
+	if data.JenisKelamin != "" && data.JenisKelamin != identity.Male && data.JenisKelamin != identity.Female {
+		add("jenis_kelamin", fmt.Errorf("must be %s or %s", identity.Male, identity.Female))
+	}
+
+	data.NIK = strings.TrimSpace(data.NIK)
+	if data.NIK != "" {
+		nik, err := identity.ParseNIK(data.NIK)
+		switch {
+		case err != nil:
+			add("nik", err)
+		case birthDate != nil && !nik.MatchesBirthDate(*birthDate):
+			add("nik", errors.New("does not match tanggal_lahir"))
+		case data.JenisKelamin == "":
+			data.JenisKelamin = nik.Sex
+		case data.JenisKelamin != nik.Sex:
+			add("jenis_kelamin", errors.New("does not match the sex encoded in nik"))
+		}
+	}
+
+	data.NISN = strings.TrimSpace(data.NISN)
+	if data.NISN != "" {
+		if err := identity.ValidateNISN(data.NISN); err != nil {
+			add("nisn", err)
+		}
+	}
+
+	if strings.TrimSpace(data.NomorTelepon) != "" {
+		phone, err := identity.NormalizePhone(data.NomorTelepon)
+		if err != nil {
+			add("nomor_telepon", err)
+		} else {
+			data.NomorTelepon = phone
+		}
+	}
+
+	fieldErrors = append(fieldErrors, validateSiblings(data.Saudara)...)
+	fieldErrors = append(fieldErrors, gradesFromData(data)...)
+	return fieldErrors
+}
+
+// childrenFromSiblings converts validated siblings to children of the family
+func childrenFromSiblings(siblings SiblingList) []models.Children {
+	children := make([]models.Children, 0, len(siblings))
+	for _, sibling := range siblings {
+		child := models.Children{
+			FullName:        strings.TrimSpace(sibling.Nama),
+			EducationStatus: sibling.StatusPendidikan,
+			Status:          sibling.Keterangan,
+		}
+		if sibling.Usia != nil {
+			child.Age = *sibling.Usia
+		}
+		if sibling.Tanggungan != nil {
+			child.Dependent = *sibling.Tanggungan
+		}
+		children = append(children, child)
+	}
+	return children
+}
+
+// siblingsFromChildren converts the children of a family to the saudara
+// field of the API
+func siblingsFromChildren(children []models.Children) SiblingList {
+	siblings := make(SiblingList, 0, len(children))
+	for _, child := range children {
+		age, dependent := child.Age, child.Dependent
+		siblings = append(siblings, SiblingData{
+			Nama:             child.FullName,
+			Usia:             &age,
+			StatusPendidikan: child.EducationStatus,
+			Tanggungan:       &dependent,
+			Keterangan:       child.Status,
+		})
+	}
+	return siblings
+}
+
+// legacySiblings reads the saudara JSON string of legacy verifications,
+// a list of {nama, status} entries. Unreadable values yield no siblings.
+func legacySiblings(value string) SiblingList {
+	var entries []struct {
+		Nama   string `json:"nama"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(value), &entries); err != nil {
+		return nil
+	}
+	siblings := make(SiblingList, 0, len(entries))
+	for _, entry := range entries {
+		if strings.TrimSpace(entry.Nama) == "" && strings.TrimSpace(entry.Status) == "" {
+			continue
+		}
+		siblings = append(siblings, SiblingData{Nama: entry.Nama, Keterangan: entry.Status})
+	}
+	return siblings
+}
+
+// applicantFromData builds the normalized applicant records of a submission
+// whose grades went through gradesFromData. A birth date that cannot be
+// parsed is left unknown; submissions are validated before.
+func applicantFromData(data VerifikasiData) models.UserData {
+	userID := uint(data.UserID)
+	birthDate, _ := parseBirthDate(data.TanggalLahir)
+
+	grades := make([]models.SemesterGrade, 0, len(data.NilaiSemester))
+	for _, grade := range data.NilaiSemester {
+		grades = append(grades, models.SemesterGrade{
+			Semester:   grade.Semester,
+			Score:      *grade.Nilai,
+			Scale:      grade.Skala,
+			Normalized: grade.NilaiNormal,
+		})
+	}
+
+	return models.UserData{
+		UserID:       userID,
+		NIK:          data.NIK,
+		NISN:         data.NISN,
+		FullName:     data.NamaLengkap,
+		BirthDate:    birthDate,
+		Gender:       data.JenisKelamin,
+		BirthPlace:   data.TempatLahir,
+		NomorTelepon: data.NomorTelepon,
+		Address:      data.Alamat,
+		Email:        data.Email,
+		SocialMedia: models.SocialMedia{
+			UserID:    userID,
+			Instagram: data.Instagram,
+			Facebook:  data.Facebook,
+			Tiktok:    data.Tiktok,
+			Website:   data.Website,
+			LinkedIn:  data.LinkedIn,
+			Twitter:   data.Twitter,
+			Youtube:   data.Youtube,
+			Whatsapp:  data.Whatsapp,
+			Telegram:  data.Telegram,
+			Other:     data.Other,
+		},
+		Academic: models.Academic{
+			UserID:         userID,
+			SchoolName:     data.AsalSekolah,
+			GraduationYear: data.TahunLulus,
+			Grades:         grades,
+		},
+		Family: models.Family{
+			FatherName:   data.NamaAyah,
+			FatherJob:    data.PekerjaanAyah,
+			FatherSalary: data.PendapatanAyah,
+			MotherName:   data.NamaIbu,
+			MotherJob:    data.PekerjaanIbu,
+			MotherSalary: data.PendapatanIbu,
+			Address:      data.AlamatKeluarga,
+			Children:     childrenFromSiblings(data.Saudara),
+		},
+	}
+}
+
+// deleteUserData hard-deletes an applicant data snapshot with its social
+// media, academic and family records
+func deleteUserData(tx *gorm.DB, userDataID uint) error {
+	if userDataID == 0 {
+		return nil
+	}
+	var userData models.UserData
+	if err := tx.Unscoped().First(&userData, userDataID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := tx.Unscoped().Delete(&userData).Error; err != nil {
+		return err
+	}
+	if userData.FamilyID != 0 {
+		if err := tx.Unscoped().Where("family_id = ?", userData.FamilyID).Delete(&models.Children{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&models.Family{}, userData.FamilyID).Error; err != nil {
+			return err
+		}
+	}
+	if userData.AcademicID != 0 {
+		if err := tx.Unscoped().Where("academic_id = ?", userData.AcademicID).Delete(&models.SemesterGrade{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&models.Academic{}, userData.AcademicID).Error; err != nil {
+			return err
+		}
+	}
+	if userData.SocialMediaID != 0 {
+		if err := tx.Unscoped().Delete(&models.SocialMedia{}, userData.SocialMediaID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyVerifikasi reads the applicant columns verifikasis carried before
+// the data moved into UserData. The columns are left in place so the
+// migration can be checked against them.
+type legacyVerifikasi struct {
+	ID             uint
+	UserID         uint
+	NIK            string
+	NISN           string
+	NamaLengkap    string
+	TanggalLahir   string
+	TempatLahir    string
+	Alamat         string
+	NomorTelepon   string
+	Email          string
+	Instagram      string
+	Facebook       string
+	Tiktok         string
+	Website        string
+	LinkedIn       string
+	Twitter        string
+	Youtube        string
+	Whatsapp       string
+	Telegram       string
+	Other          string
+	NamaIbu        string
+	PekerjaanIbu   string
+	PendapatanIbu  int
+	NamaAyah       string
+	PekerjaanAyah  string
+	PendapatanAyah int
+	AlamatKeluarga string
+	Saudara        string
+	AsalSekolah    string
+	TahunLulus     string
+	NilaiSemester1 string
+	NilaiSemester2 string
+	FotoKTP        string
+	FotoKK         string
+	FotoIjazah     string
+	FotoSKL        string
+	FotoSertifikat string
+}
+
+func (legacyVerifikasi) TableName() string {
+	return "verifikasis"
+}
+
+// toData converts the legacy columns to the API representation
+func (v legacyVerifikasi) toData() VerifikasiData {
+	return VerifikasiData{
+		ID:             int(v.ID),
+		UserID:         int(v.UserID),
+		NIK:            v.NIK,
+		NISN:           v.NISN,
+		NamaLengkap:    v.NamaLengkap,
+		TanggalLahir:   v.TanggalLahir,
+		TempatLahir:    v.TempatLahir,
+		Alamat:         v.Alamat,
+		NomorTelepon:   v.NomorTelepon,
+		Email:          v.Email,
+		Instagram:      v.Instagram,
+		Facebook:       v.Facebook,
+		Tiktok:         v.Tiktok,
+		Website:        v.Website,
+		LinkedIn:       v.LinkedIn,
+		Twitter:        v.Twitter,
+		Youtube:        v.Youtube,
+		Whatsapp:       v.Whatsapp,
+		Telegram:       v.Telegram,
+		Other:          v.Other,
+		NamaIbu:        v.NamaIbu,
+		PekerjaanIbu:   v.PekerjaanIbu,
+		PendapatanIbu:  v.PendapatanIbu,
+		NamaAyah:       v.NamaAyah,
+		PekerjaanAyah:  v.PekerjaanAyah,
+		PendapatanAyah: v.PendapatanAyah,
+		AlamatKeluarga: v.AlamatKeluarga,
+		Saudara:        legacySiblings(v.Saudara),
+		AsalSekolah:    v.AsalSekolah,
+		TahunLulus:     v.TahunLulus,
+		NilaiSemester1: v.NilaiSemester1,
+		NilaiSemester2: v.NilaiSemester2,
+	}
+}
+
+// Legacy verifikasis columns holding personal data and documents
+var (
+	legacyApplicantColumns = []string{
+		"nik", "nisn", "nama_lengkap", "tanggal_lahir", "tempat_lahir", "alamat",
+		"nomor_telepon", "email", "instagram", "facebook", "tiktok", "website",
+		"linked_in", "twitter", "youtube", "whatsapp", "telegram", "other",
+		"nama_ibu", "pekerjaan_ibu", "nama_ayah", "pekerjaan_ayah", "alamat_keluarga",
+		"saudara", "asal_sekolah",
+	}
+	legacyDocumentColumns = []string{"foto_ktp", "foto_kk", "foto_ijazah", "foto_skl", "foto_sertifikat"}
+)
+
+// legacyColumnsPresent reports whether verifikasis still has the applicant
+// columns, which databases created after the normalization do not
+func legacyColumnsPresent(db *gorm.DB, column string) bool {
+	return db.Migrator().HasColumn(&models.Verifikasi{}, column)
+}
+
+// blankLegacyColumns clears legacy columns of a verification so that
+// anonymized and purged records keep no copy of the removed data
+func blankLegacyColumns(tx *gorm.DB, verifikasiID uint, columns []string) error {
+	if !legacyColumnsPresent(tx, columns[0]) {
+		return nil
+	}
+	blank := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		blank[column] = ""
+	}
+	return tx.Table("verifikasis").Where("id = ?", verifikasiID).Updates(blank).Error
+}
+
+// LegacyMigrationReport summarizes a MigrateLegacyVerifikasi run
+type LegacyMigrationReport struct {
+	DryRun        bool `json:"dry_run"`
+	Verifikasi    int  `json:"verifikasi"`
+	Children      int  `json:"children"`
+	UnparsedDates int  `json:"unparsed_dates"`
+	// Grades moved out of legacy columns, and values that could not be read
+	Grades         int `json:"grades"`
+	UnparsedGrades int `json:"unparsed_grades"`
+}
+
+// MigrateLegacyVerifikasi copies the applicant data of verifications created
+// before the normalization from the legacy verifikasis columns into
+// UserData, SocialMedia, Academic, SemesterGrade, Family and Children, and
+// moves grades kept in the legacy academics.range_semester column into
+// SemesterGrade. Anonymized records and records already migrated are
+// skipped, so it is safe to run on every start.
+func MigrateLegacyVerifikasi(dryRun bool) (LegacyMigrationReport, error) {
+	report := LegacyMigrationReport{DryRun: dryRun}
+	if err := migrateRangeSemester(dryRun, &report); err != nil {
+		return report, err
+	}
+	if !legacyColumnsPresent(config.DB, "nik") {
+		return report, nil
+	}
+
+	var legacy []legacyVerifikasi
+	err := config.DB.Where("(user_data_id = 0 OR user_data_id IS NULL) AND anonymized_at IS NULL").
+		Find(&legacy).Error
+	if err != nil {
+		return report, err
+	}
+
+	for _, v := range legacy {
+		data := v.toData()
+		report.UnparsedGrades += len(gradesFromData(&data))
+		report.Grades += len(data.NilaiSemester)
+		applicant := applicantFromData(data)
+		if _, err := parseBirthDate(data.TanggalLahir); err != nil {
+			report.UnparsedDates++
+		}
+
+		report.Verifikasi++
+		report.Children += len(applicant.Family.Children)
+		if dryRun {
+			continue
+		}
+
+		err = config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&applicant).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.Verifikasi{}).Where("id = ?", v.ID).Update("user_data_id", applicant.ID).Error
+		})
+		if err != nil {
+			return report, fmt.Errorf("verification %d: %w", v.ID, err)
+		}
+	}
+	return report, nil
+}
+
+// migrateRangeSemester moves the grades of academics that still only have
+// the legacy range_semester column, a JSON list of semester grades
+func migrateRangeSemester(dryRun bool, report *LegacyMigrationReport) error {
+	if !config.DB.Migrator().HasColumn(&models.Academic{}, "range_semester") {
+		return nil
+	}
+
+	var rows []struct {
+		ID            uint
+		RangeSemester string
+	}
+	err := config.DB.Table("academics").Select("id, range_semester").
+		Where("range_semester <> '' AND range_semester <> 'null' AND range_semester <> '[]'").
+		Where("id NOT IN (?)", config.DB.Model(&models.SemesterGrade{}).Select("academic_id")).
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		var values []string
+		if err := json.Unmarshal([]byte(row.RangeSemester), &values); err != nil {
+			report.UnparsedGrades++
+			continue
+		}
+		data := VerifikasiData{}
+		if len(values) > 0 {
+			data.NilaiSemester1 = values[0]
+		}
+		if len(values) > 1 {
+			data.NilaiSemester2 = values[1]
+		}
+		report.UnparsedGrades += len(gradesFromData(&data))
+		report.Grades += len(data.NilaiSemester)
+		if dryRun || len(data.NilaiSemester) == 0 {
+			continue
+		}
+
+		grades := applicantFromData(data).Academic.Grades
+		for i := range grades {
+			grades[i].AcademicID = row.ID
+		}
+		if err := config.DB.Create(&grades).Error; err != nil {
+			return fmt.Errorf("academic %d: %w", row.ID, err)
+		}
+	}
+	return nil
+}
+
+// MigrateApplicantData runs MigrateLegacyVerifikasi at startup and logs the
+// outcome
+func MigrateApplicantData() {
+	report, err := MigrateLegacyVerifikasi(false)
+	if err != nil {
+		log.Printf("Error migrating legacy verification data: %v", err)
+		return
+	}
+	if report.Verifikasi > 0 || report.Grades > 0 {
+		log.Printf("[MIGRATE] Moved applicant data of %d verifications into UserData (%d children, %d grades, %d unparsed birth dates, %d unparsed grades)",
+			report.Verifikasi, report.Children, report.Grades, report.UnparsedDates, report.UnparsedGrades)
+	}
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSiblingListInput(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		siblings   int
+		dependents int
+		errors     []string
+	}{
+		{
+			name:       "structured list",
+			input:      `[{"nama":"Ani","usia":12,"status_pendidikan":"smp","tanggungan":true}]`,
+			siblings:   1,
+			dependents: 1,
+		},
+		{
+			name:     "structured list with a missing field",
+			input:    `[{"nama":"Ani","usia":12,"status_pendidikan":"smp"}]`,
+			siblings: 1,
+			errors:   []string{"saudara[0].tanggungan"},
+		},
+		{
+			name:     "legacy string",
+			input:    `"[{\"nama\":\"Ani\",\"status\":\"Pelajar\"},{\"nama\":\"Budi\",\"status\":\"\"}]"`,
+			siblings: 2,
+		},
+		{
+			name:     "legacy string with a blank row",
+			input:    `"[{\"nama\":\"Ani\",\"status\":\"Pelajar\"},{\"nama\":\"\",\"status\":\"\"}]"`,
+			siblings: 1,
+		},
+		{
+			name:     "legacy string without a name",
+			input:    `"[{\"nama\":\"\",\"status\":\"Bekerja\"}]"`,
+			siblings: 1,
+			errors:   []string{"saudara[0].nama"},
+		},
+		{
+			name:       "structured entries in a string",
+			input:      `"[{\"nama\":\"Ani\",\"usia\":20,\"status_pendidikan\":\"kuliah\",\"tanggungan\":true}]"`,
+			siblings:   1,
+			dependents: 1,
+		},
+		{
+			name:     "structured entry with a bad status in a string",
+			input:    `"[{\"nama\":\"Ani\",\"usia\":20,\"status_pendidikan\":\"s3\",\"tanggungan\":false}]"`,
+			siblings: 1,
+			errors:   []string{"saudara[0].status_pendidikan"},
+		},
+		{
+			name:  "empty string",
+			input: `""`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var siblings SiblingList
+			if err := json.Unmarshal([]byte(tt.input), &siblings); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if len(siblings) != tt.siblings {
+				t.Errorf("got %d siblings, want %d", len(siblings), tt.siblings)
+			}
+			if got := siblings.Dependents(); got != tt.dependents {
+				t.Errorf("Dependents = %d, want %d", got, tt.dependents)
+			}
+
+			fieldErrors := validateSiblings(siblings)
+			if len(fieldErrors) != len(tt.errors) {
+				t.Fatalf("validateSiblings = %v, want errors for %v", fieldErrors, tt.errors)
+			}
+			for i, field := range tt.errors {
+				if fieldErrors[i].Field != field {
+					t.Errorf("error %d is for %s, want %s", i, fieldErrors[i].Field, field)
+				}
+			}
+		})
+	}
+}
+
+func TestSiblingListRejectsOtherValues(t *testing.T) {
+	for _, input := range []string{`"not json"`, `{"nama":"Ani"}`, `42`} {
+		var siblings SiblingList
+		if err := json.Unmarshal([]byte(input), &siblings); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded", input)
+		}
+	}
+}
+
+func TestLegacyGradesMustBeNumbers(t *testing.T) {
+	for _, value := range []string{"NaN", "nan", "Inf", "-Inf", "+Infinity", "delapan"} {
+		data := VerifikasiData{NilaiSemester1: value}
+		fieldErrors := gradesFromData(&data)
+		if len(fieldErrors) != 1 || fieldErrors[0].Field != "nilai_semester_1" {
+			t.Errorf("nilai_semester_1 %q: errors = %v, want one for nilai_semester_1", value, fieldErrors)
+		}
+	}
+
+	data := VerifikasiData{NilaiSemester1: "85,5", NilaiSemester2: "8.5"}
+	if fieldErrors := gradesFromData(&data); len(fieldErrors) != 0 {
+		t.Fatalf("valid legacy grades: errors = %v", fieldErrors)
+	}
+	if got := data.NilaiSemester[1].NilaiNormal; got != 85 {
+		t.Errorf("8.5 on the 0-10 scale normalized to %v, want 85", got)
+	}
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"sibestie/config"
+	"sibestie/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recordAudit appends an entry to the audit trail using db, which may be a
+// transaction so the entry is committed together with the change it describes
+func recordAudit(db *gorm.DB, actorID uint, action, entity string, entityID uint, detail string) error {
+	entry := models.AuditLog{
+		ActorID:  actorID,
+		Action:   action,
+		Entity:   entity,
+		EntityID: entityID,
+		Detail:   detail,
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		log.Printf("Error writing audit log %s: %v", action, err)
+		return err
+	}
+	return nil
+}
+
+// GET /api/audit
+func ListAuditLogs(c *gin.Context) {
+	query := config.DB.Order("created_at desc")
+	if entity := c.Query("entity"); entity != "" {
+		query = query.Where("entity = ?", entity)
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	var logs []models.AuditLog
+	if err := query.Limit(500).Find(&logs).Error; err != nil {
+		log.Printf("Error querying audit logs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query audit logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, logs)
+}
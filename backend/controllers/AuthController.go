@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -133,3 +134,62 @@ func generateToken(user models.User) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 }
+
+// AuthRequired validates the bearer token and stores the caller's id and role
+// in the context. When roles are given, the caller must have one of them.
+func AuthRequired(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing authorization token"})
+			return
+		}
+
+		claims := jwt.MapClaims{}
+		token, err := jwt.ParseWithClaims(strings.TrimPrefix(header, "Bearer "), claims, func(t *jwt.Token) (interface{}, error) {
+			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.New("unexpected signing method")
+			}
+			return []byte(os.Getenv("JWT_SECRET")), nil
+		})
+		if err != nil || !token.Valid {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+
+		id, _ := claims["id"].(float64)
+		role, _ := claims["role"].(string)
+		if id == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			return
+		}
+
+		if len(roles) > 0 {
+			allowed := false
+			for _, r := range roles {
+				if r == role {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You are not allowed to access this resource"})
+				return
+			}
+		}
+
+		c.Set("user_id", uint(id))
+		c.Set("user_role", role)
+		c.Next()
+	}
+}
+
+// currentUserID returns the id of the authenticated caller set by AuthRequired
+func currentUserID(c *gin.Context) uint {
+	return c.GetUint("user_id")
+}
+
+// currentUserRole returns the role of the authenticated caller set by AuthRequired
+func currentUserRole(c *gin.Context) string {
+	return c.GetString("user_role")
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"sibestie/config"
+	"sibestie/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ConflictDeclarationRequest represents a conflict of interest declared by a verifikator
+type ConflictDeclarationRequest struct {
+	Kind        string `json:"kind" binding:"required,oneof=applicant school family_name"`
+	ApplicantID uint   `json:"applicant_id"`
+	Value       string `json:"value"`
+	Reason      string `json:"reason" binding:"required"`
+}
+
+// findConflict returns the first conflict declared by the verifikator that
+// matches the verification record, loaded withApplicant, or nil when the
+// verifikator is not conflicted
+func findConflict(db *gorm.DB, verifikatorID uint, verifikasi models.Verifikasi) (*models.ConflictDeclaration, error) {
+	var declarations []models.ConflictDeclaration
+	if err := db.Where("verifikator_id = ?", verifikatorID).Find(&declarations).Error; err != nil {
+		return nil, err
+	}
+
+	for i, d := range declarations {
+		switch d.Kind {
+		case models.ConflictApplicant:
+			if d.ApplicantID == verifikasi.UserID {
+				return &declarations[i], nil
+			}
+		case models.ConflictSchool:
+			if normalizeName(d.Value) != "" && normalizeName(d.Value) == normalizeName(verifikasi.UserData.Academic.SchoolName) {
+				return &declarations[i], nil
+			}
+		case models.ConflictFamilyName:
+			applicant := verifikasi.UserData
+			for _, name := range []string{applicant.FullName, applicant.Family.FatherName, applicant.Family.MotherName} {
+				if containsWords(name, d.Value) {
+					return &declarations[i], nil
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// normalizeName lowercases s and collapses its whitespace
+func normalizeName(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// containsWords reports whether the words of needle appear consecutively in haystack
+func containsWords(haystack, needle string) bool {
+	h := normalizeName(haystack)
+	n := normalizeName(needle)
+	if h == "" || n == "" {
+		return false
+	}
+	return strings.Contains(" "+h+" ", " "+n+" ")
+}
+
+// checkConflict writes a 409 response and returns false when the verifikator
+// has declared a conflict with the verification record
+func checkConflict(c *gin.Context, verifikatorID uint, verifikasi models.Verifikasi) bool {
+	conflict, err := findConflict(config.DB, verifikatorID, verifikasi)
+	if err != nil {
+		log.Printf("Error checking conflicts of interest: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check conflicts of interest"})
+		return false
+	}
+	if conflict != nil {
+		recordAudit(config.DB, verifikatorID, "verifikasi.conflict_refused", "verifikasi", verifikasi.ID,
+			fmt.Sprintf("conflict declaration %d (%s)", conflict.ID, conflict.Kind))
+		c.JSON(http.StatusConflict, gin.H{
+			"error":       "Verifikator has a declared conflict of interest with this applicant",
+			"conflict_id": conflict.ID,
+			"kind":        conflict.Kind,
+		})
+		return false
+	}
+	return true
+}
+
+// POST /api/conflicts
+func DeclareConflict(c *gin.Context) {
+	var input ConflictDeclarationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conflict data: " + err.Error()})
+		return
+	}
+
+	if input.Kind == models.ConflictApplicant && input.ApplicantID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "applicant_id is required for applicant conflicts"})
+		return
+	}
+	if input.Kind != models.ConflictApplicant && strings.TrimSpace(input.Value) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "value is required for school and family name conflicts"})
+		return
+	}
+
+	declaration := models.ConflictDeclaration{
+		VerifikatorID: currentUserID(c),
+		Kind:          input.Kind,
+		ApplicantID:   input.ApplicantID,
+		Value:         strings.TrimSpace(input.Value),
+		Reason:        input.Reason,
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&declaration).Error; err != nil {
+			return err
+		}
+		detail := fmt.Sprintf("kind=%s applicant_id=%d value=%q reason=%q",
+			declaration.Kind, declaration.ApplicantID, declaration.Value, declaration.Reason)
+		return recordAudit(tx, declaration.VerifikatorID, "conflict.declare", "conflict", declaration.ID, detail)
+	})
+	if err != nil {
+		log.Printf("Error saving conflict declaration: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save conflict declaration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Conflict declared successfully", "data": declaration})
+}
+
+// GET /api/conflicts
+func ListConflicts(c *gin.Context) {
+	query := config.DB.Order("created_at desc")
+	if currentUserRole(c) != "admin" {
+		query = query.Where("verifikator_id = ?", currentUserID(c))
+	} else if verifikatorID := c.Query("verifikator_id"); verifikatorID != "" {
+		query = query.Where("verifikator_id = ?", verifikatorID)
+	}
+
+	var declarations []models.ConflictDeclaration
+	if err := query.Find(&declarations).Error; err != nil {
+		log.Printf("Error querying conflict declarations: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query conflict declarations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, declarations)
+}
+
+// DELETE /api/conflicts/:id
+func RevokeConflict(c *gin.Context) {
+	var declaration models.ConflictDeclaration
+	if err := config.DB.First(&declaration, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conflict declaration not found"})
+		} else {
+			log.Printf("Error finding conflict declaration: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find conflict declaration"})
+		}
+		return
+	}
+
+	if declaration.VerifikatorID != currentUserID(c) && currentUserRole(c) != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only revoke your own declarations"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&declaration).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, currentUserID(c), "conflict.revoke", "conflict", declaration.ID,
+			fmt.Sprintf("kind=%s verifikator_id=%d", declaration.Kind, declaration.VerifikatorID))
+	})
+	if err != nil {
+		log.Printf("Error revoking conflict declaration: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke conflict declaration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Conflict declaration revoked"})
+}
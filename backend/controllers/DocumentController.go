@@ -0,0 +1,965 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"sibestie/config"
+	"sibestie/models"
+	"sibestie/tools/imaging"
+	"sibestie/tools/watermark"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxUploadSize is the largest document accepted by the upload endpoint
+const maxUploadSize = 10 << 20
+
+// Image documents are downscaled to imageMaxSide and previewed at thumbnailSide
+const (
+	imageMaxSide  = 2000
+	thumbnailSide = 320
+)
+
+// allowedContentTypes lists the sniffed content types accepted for upload
+var allowedContentTypes = map[string]bool{
+	imaging.TypeJPEG:  true,
+	imaging.TypePNG:   true,
+	"application/pdf": true,
+}
+
+// documentSizeLimits caps the upload size per document type
+var documentSizeLimits = map[string]int64{
+	models.DocKTP:        5 << 20,
+	models.DocKK:         5 << 20,
+	models.DocIjazah:     5 << 20,
+	models.DocSKL:        5 << 20,
+	models.DocSertifikat: maxUploadSize,
+	models.DocSKTM:       5 << 20,
+	models.DocRapor:      maxUploadSize,
+}
+
+// errInvalidDocument marks document references rejected because of client input
+var errInvalidDocument = errors.New("invalid document")
+
+var (
+	errUnsupportedDocument = fmt.Errorf("%w: only JPEG, PNG and PDF files are accepted", errInvalidDocument)
+	errDocumentTooLarge    = fmt.Errorf("%w: file exceeds the size limit for this document type", errInvalidDocument)
+	errMalformedDocument   = fmt.Errorf("%w: file is damaged or not what its type claims", errInvalidDocument)
+	errEncryptedDocument   = fmt.Errorf("%w: password-protected PDFs are not accepted", errInvalidDocument)
+)
+
+// processUpload validates an upload against the allow-list and size limit of
+// its document type. Images are normalized (EXIF stripped, downscaled) and a
+// thumbnail is produced; PDFs are kept as uploaded.
+func processUpload(docType string, content []byte) (processed []byte, contentType string, thumbnail []byte, err error) {
+	limit, ok := documentSizeLimits[docType]
+	if !ok {
+		limit = maxUploadSize
+	}
+	if int64(len(content)) > limit {
+		return nil, "", nil, errDocumentTooLarge
+	}
+
+	// Sniff the content rather than trusting the file name or the client's header
+	contentType = http.DetectContentType(content)
+	if !allowedContentTypes[contentType] {
+		return nil, "", nil, errUnsupportedDocument
+	}
+
+	if contentType == "application/pdf" {
+		if !bytes.HasPrefix(content, []byte("%PDF-")) || !bytes.Contains(content[max(0, len(content)-1024):], []byte("%%EOF")) {
+			return nil, "", nil, errMalformedDocument
+		}
+		// Staff copies are watermarked, so only accept PDFs that can be
+		if err := watermark.Validate(content); err != nil {
+			if errors.Is(err, watermark.ErrEncrypted) {
+				return nil, "", nil, errEncryptedDocument
+			}
+			return nil, "", nil, errMalformedDocument
+		}
+		return content, contentType, nil, nil
+	}
+
+	processed, img, err := imaging.Normalize(content, contentType, imageMaxSide)
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		return nil, "", nil, errDocumentTooLarge
+	}
+	if err != nil {
+		return nil, "", nil, errMalformedDocument
+	}
+	thumbnail, err = imaging.Thumbnail(img, thumbnailSide)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return processed, contentType, thumbnail, nil
+}
+
+// DocumentRef references an uploaded document from a verification submission
+type DocumentRef struct {
+	Type       string `json:"type"`
+	DocumentID uint   `json:"document_id"`
+}
+
+// documentURL returns the API path a stored document is served from
+func documentURL(id uint) string {
+	return fmt.Sprintf("/api/documents/%d", id)
+}
+
+// contentChecksum returns the hex SHA-256 of stored document content
+func contentChecksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// contentStorageKey returns the content-addressed storage key for a checksum
+func contentStorageKey(checksum string) string {
+	return fmt.Sprintf("documents/sha256/%s/%s", checksum[:2], checksum)
+}
+
+// storeDocument validates and normalizes content and records it as a
+// SourceFile owned by userID. Content is stored once per checksum: a file the
+// user already has under the same type is returned as is (existing is true),
+// and content another file already holds is shared rather than stored again.
+func storeDocument(ctx context.Context, db *gorm.DB, userID uint, docType, name string, content []byte) (file models.SourceFile, existing bool, err error) {
+	processed, contentType, thumbnail, err := processUpload(docType, content)
+	if err != nil {
+		return models.SourceFile{}, false, err
+	}
+	checksum := contentChecksum(processed)
+
+	err = db.Where("user_id = ? AND source_type = ? AND checksum = ?", userID, docType, checksum).First(&file).Error
+	if err == nil {
+		return file, true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.SourceFile{}, false, err
+	}
+
+	file = models.SourceFile{
+		UserID:      userID,
+		SourceType:  docType,
+		SourceName:  name,
+		ContentType: contentType,
+		Size:        int64(len(processed)),
+		Checksum:    checksum,
+	}
+
+	var blob models.SourceFile
+	err = db.Where("checksum = ? AND storage_key <> ''", checksum).First(&blob).Error
+	switch {
+	case err == nil:
+		file.StorageKey, file.ThumbnailKey = blob.StorageKey, blob.ThumbnailKey
+		// The content has been (or is being) scanned already
+		file.ScanStatus, file.ScanSignature, file.ScannedAt = blob.ScanStatus, blob.ScanSignature, blob.ScannedAt
+		if err := db.Create(&file).Error; err != nil {
+			return models.SourceFile{}, false, err
+		}
+		return file, false, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return models.SourceFile{}, false, err
+	}
+
+	file.StorageKey = contentStorageKey(checksum)
+	file.ScanStatus = models.ScanPending
+	if err := config.Storage.Put(ctx, file.StorageKey, bytes.NewReader(processed), int64(len(processed)), contentType); err != nil {
+		return models.SourceFile{}, false, err
+	}
+	if thumbnail != nil {
+		file.ThumbnailKey = file.StorageKey + "-thumb"
+		if err := config.Storage.Put(ctx, file.ThumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), imaging.TypeJPEG); err != nil {
+			config.Storage.Delete(ctx, file.StorageKey)
+			return models.SourceFile{}, false, err
+		}
+	}
+
+	if err := db.Create(&file).Error; err != nil {
+		config.Storage.Delete(ctx, file.StorageKey)
+		if file.ThumbnailKey != "" {
+			config.Storage.Delete(ctx, file.ThumbnailKey)
+		}
+		return models.SourceFile{}, false, err
+	}
+	queueScan(file.ID)
+	return file, false, nil
+}
+
+// resolveDocumentRefs loads the referenced documents, checking that each
+// belongs to the user and that every document type appears at most once
+func resolveDocumentRefs(db *gorm.DB, userID uint, refs []DocumentRef) (map[string]models.SourceFile, error) {
+	files := map[string]models.SourceFile{}
+	for _, ref := range refs {
+		if !models.IsDocumentType(ref.Type) {
+			return nil, fmt.Errorf("%w: unknown document type %q", errInvalidDocument, ref.Type)
+		}
+		if _, dup := files[ref.Type]; dup {
+			return nil, fmt.Errorf("%w: document type %q referenced more than once", errInvalidDocument, ref.Type)
+		}
+
+		var file models.SourceFile
+		if err := db.First(&file, ref.DocumentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: document %d not found", errInvalidDocument, ref.DocumentID)
+			}
+			return nil, err
+		}
+		if file.UserID != userID {
+			return nil, fmt.Errorf("%w: document %d does not belong to this user", errInvalidDocument, ref.DocumentID)
+		}
+		if file.SourceType != ref.Type {
+			return nil, fmt.Errorf("%w: document %d was uploaded as %q, not %q", errInvalidDocument, ref.DocumentID, file.SourceType, ref.Type)
+		}
+		if file.ScanStatus == models.ScanInfected {
+			return nil, fmt.Errorf("%w: document %d failed the malware scan", errInvalidDocument, ref.DocumentID)
+		}
+		files[ref.Type] = file
+	}
+	return files, nil
+}
+
+// attachDocuments links uploaded documents to a verification
+func attachDocuments(tx *gorm.DB, verifikasiID uint, files map[string]models.SourceFile) error {
+	for docType, file := range files {
+		link := models.VerifikasiDocument{
+			VerifikasiID: verifikasiID,
+			SourceFileID: file.ID,
+			DocType:      docType,
+		}
+		if err := tx.Create(&link).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteSourceFile hard-deletes an uploaded file, and its stored content once
+// no other file shares it
+func deleteSourceFile(tx *gorm.DB, file models.SourceFile) error {
+	if err := tx.Unscoped().Delete(&file).Error; err != nil {
+		return err
+	}
+	if file.StorageKey != "" {
+		var shared int64
+		if err := tx.Unscoped().Model(&models.SourceFile{}).Where("storage_key = ?", file.StorageKey).Count(&shared).Error; err != nil {
+			return err
+		}
+		if shared > 0 {
+			return nil
+		}
+	}
+	if file.ThumbnailKey != "" {
+		if err := config.Storage.Delete(context.Background(), file.ThumbnailKey); err != nil {
+			return err
+		}
+	}
+	if file.StorageKey != "" {
+		return config.Storage.Delete(context.Background(), file.StorageKey)
+	}
+	return nil
+}
+
+// detachVerifikasiDocuments removes the documents attached to a verification,
+// deleting files that no other verification still references
+func detachVerifikasiDocuments(tx *gorm.DB, verifikasiID uint) error {
+	var links []models.VerifikasiDocument
+	if err := tx.Where("verifikasi_id = ?", verifikasiID).Find(&links).Error; err != nil {
+		return err
+	}
+	linkIDs := tx.Model(&models.VerifikasiDocument{}).Select("id").Where("verifikasi_id = ?", verifikasiID)
+	if err := deleteDocumentAnnotations(tx, linkIDs); err != nil {
+		return err
+	}
+	if err := tx.Where("verifikasi_id = ?", verifikasiID).Delete(&models.VerifikasiDocument{}).Error; err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		var refs int64
+		if err := tx.Model(&models.VerifikasiDocument{}).Where("source_file_id = ?", link.SourceFileID).Count(&refs).Error; err != nil {
+			return err
+		}
+		if refs > 0 {
+			continue
+		}
+		var file models.SourceFile
+		if err := tx.Unscoped().First(&file, link.SourceFileID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+		if err := deleteSourceFile(tx, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// POST /api/documents
+func UploadDocument(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize+(1<<20))
+
+	docType := c.PostForm("type")
+	if !models.IsDocumentType(docType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown document type", "allowed": models.DocumentTypes})
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+	if header.Size > maxUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+		return
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer src.Close()
+
+	content, err := io.ReadAll(io.LimitReader(src, maxUploadSize+1))
+	if err != nil || len(content) > maxUploadSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+
+	file, existing, err := storeDocument(c.Request.Context(), config.DB, currentUserID(c), docType, header.Filename, content)
+	if err != nil {
+		switch {
+		case errors.Is(err, errDocumentTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error(), "limit": documentSizeLimits[docType]})
+			return
+		case errors.Is(err, errUnsupportedDocument):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, errInvalidDocument):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error storing document: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store document"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Document uploaded successfully",
+		"id":            file.ID,
+		"type":          file.SourceType,
+		"name":          file.SourceName,
+		"size":          file.Size,
+		"content_type":  file.ContentType,
+		"url":           documentURL(file.ID),
+		"has_thumbnail": file.ThumbnailKey != "",
+		"existing":      existing,
+		"scan_status":   file.ScanStatus,
+	})
+}
+
+// LibraryDocument is an upload in an applicant's document library
+type LibraryDocument struct {
+	ID           uint      `json:"id"`
+	Type         string    `json:"type"`
+	Name         string    `json:"name"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type"`
+	Checksum     string    `json:"checksum"`
+	URL          string    `json:"url"`
+	HasThumbnail bool      `json:"has_thumbnail"`
+	ScanStatus   string    `json:"scan_status"`
+	UsedIn       []uint    `json:"used_in"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// GET /api/documents
+func ListDocuments(c *gin.Context) {
+	query := config.DB.Where("user_id = ?", currentUserID(c))
+	if docType := c.Query("type"); docType != "" {
+		query = query.Where("source_type = ?", docType)
+	}
+
+	var files []models.SourceFile
+	if err := query.Order("created_at DESC").Find(&files).Error; err != nil {
+		log.Printf("Error listing documents: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list documents"})
+		return
+	}
+
+	ids := make([]uint, len(files))
+	for i, file := range files {
+		ids[i] = file.ID
+	}
+	var links []models.VerifikasiDocument
+	if len(ids) > 0 {
+		if err := config.DB.Where("source_file_id IN ?", ids).Order("verifikasi_id").Find(&links).Error; err != nil {
+			log.Printf("Error listing document links: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list documents"})
+			return
+		}
+	}
+	usedIn := map[uint][]uint{}
+	for _, link := range links {
+		usedIn[link.SourceFileID] = append(usedIn[link.SourceFileID], link.VerifikasiID)
+	}
+
+	documents := make([]LibraryDocument, len(files))
+	for i, file := range files {
+		documents[i] = LibraryDocument{
+			ID:           file.ID,
+			Type:         file.SourceType,
+			Name:         file.SourceName,
+			Size:         file.Size,
+			ContentType:  file.ContentType,
+			Checksum:     file.Checksum,
+			URL:          documentURL(file.ID),
+			HasThumbnail: file.ThumbnailKey != "",
+			ScanStatus:   file.ScanStatus,
+			UsedIn:       append([]uint{}, usedIn[file.ID]...),
+			CreatedAt:    file.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": documents, "total": len(documents)})
+}
+
+// canAccessDocument reports whether a user may view an uploaded file: the
+// applicant who uploaded it, an admin, or a verifikator who may decide a
+// verification the file is attached to. Like decisions, that is one
+// assigned to the verifikator or one not assigned to anybody yet.
+func canAccessDocument(db *gorm.DB, userID uint, role string, file models.SourceFile) (bool, error) {
+	if file.UserID == userID {
+		return true, nil
+	}
+	if role != "verifikator" && role != "admin" {
+		return false, nil
+	}
+
+	query := db.Model(&models.VerifikasiDocument{}).
+		Joins("JOIN verifikasis ON verifikasis.id = verifikasi_documents.verifikasi_id").
+		Where("verifikasi_documents.source_file_id = ?", file.ID)
+	if role == "verifikator" {
+		query = query.Where("verifikasis.assigned_verifikator_id = ? OR verifikasis.assigned_verifikator_id = 0 OR verifikasis.assigned_verifikator_id IS NULL", userID)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// canServeDocument reports whether a file's content may be sent to a viewer.
+// Infected content is never served, and content without a clean scan only
+// to the applicant who uploaded it.
+func canServeDocument(file models.SourceFile, viewerID uint) bool {
+	switch file.ScanStatus {
+	case models.ScanClean:
+		return true
+	case models.ScanInfected:
+		return false
+	default:
+		return file.UserID == viewerID
+	}
+}
+
+// documentURLTTL returns how long signed document URLs stay valid
+func documentURLTTL() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("DOCUMENT_URL_TTL_SECONDS"))
+	if err != nil || seconds <= 0 {
+		seconds = 300
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// errNoSigningKey is returned when no SECRET_KEY is set to sign document
+// links with; an empty key would make every signature guessable
+var errNoSigningKey = errors.New("SECRET_KEY is not set, document links cannot be signed")
+
+// documentSignature signs a download link for one viewer, record and variant
+func documentSignature(fileID, viewerID, recordID uint, variant, expires string) (string, error) {
+	key := os.Getenv("SECRET_KEY")
+	if key == "" {
+		return "", errNoSigningKey
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%d|%d|%d|%s|%s", fileID, viewerID, recordID, variant, expires)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// signedDocumentURL returns a short-lived download link for a document that
+// only works for the viewer it was issued to. recordID is the verification
+// the document is viewed for, printed in the staff watermark, or 0. variant
+// is "" for the document itself or "thumbnail" for its preview.
+func signedDocumentURL(fileID, viewerID, recordID uint, variant string) (string, error) {
+	expires := strconv.FormatInt(time.Now().Add(documentURLTTL()).Unix(), 10)
+	signature, err := documentSignature(fileID, viewerID, recordID, variant, expires)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{
+		"viewer":    {strconv.FormatUint(uint64(viewerID), 10)},
+		"record":    {strconv.FormatUint(uint64(recordID), 10)},
+		"expires":   {expires},
+		"signature": {signature},
+	}
+	if variant != "" {
+		query.Set("variant", variant)
+	}
+	return fmt.Sprintf("/api/documents/%d/download?%s", fileID, query.Encode()), nil
+}
+
+// VerifikasiDocumentView describes a document attached to a verification
+// as shown to a particular viewer
+type VerifikasiDocumentView struct {
+	ID           uint   `json:"id"`
+	Type         string `json:"type"`
+	Name         string `json:"name"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	ScanStatus   string `json:"scan_status"`
+	URL          string `json:"url,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+
+	Required     bool                        `json:"required"`
+	ReviewStatus string                      `json:"review_status"`
+	ReviewNote   string                      `json:"review_note"`
+	ReviewedBy   uint                        `json:"reviewed_by"`
+	ReviewedAt   *time.Time                  `json:"reviewed_at"`
+	Annotations  []models.DocumentAnnotation `json:"annotations"`
+}
+
+// documentsForViewer lists the documents attached to a verification with
+// signed links for the ones the viewer is allowed to open
+func documentsForViewer(db *gorm.DB, verifikasiID, viewerID uint, role string) ([]VerifikasiDocumentView, error) {
+	var links []models.VerifikasiDocument
+	if err := db.Where("verifikasi_id = ?", verifikasiID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	requirements, err := verifikasiRequirements(db, verifikasiID)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]VerifikasiDocumentView, 0, len(links))
+	for _, link := range links {
+		var file models.SourceFile
+		if err := db.First(&file, link.SourceFileID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		view := VerifikasiDocumentView{
+			ID:          file.ID,
+			Type:        link.DocType,
+			Name:        file.SourceName,
+			ContentType: file.ContentType,
+			Size:        file.Size,
+			ScanStatus:  file.ScanStatus,
+
+			Required:     requirements[link.DocType].Required,
+			ReviewStatus: link.ReviewStatus,
+			ReviewNote:   link.ReviewNote,
+			ReviewedBy:   link.ReviewedBy,
+			ReviewedAt:   link.ReviewedAt,
+		}
+		if err := db.Where("verifikasi_document_id = ?", link.ID).Order("page, id").Find(&view.Annotations).Error; err != nil {
+			return nil, err
+		}
+		allowed, err := canAccessDocument(db, viewerID, role, file)
+		if err != nil {
+			return nil, err
+		}
+		if allowed && canServeDocument(file, viewerID) {
+			if view.URL, err = signedDocumentURL(file.ID, viewerID, verifikasiID, ""); err != nil {
+				return nil, err
+			}
+			if file.ThumbnailKey != "" {
+				if view.ThumbnailURL, err = signedDocumentURL(file.ID, viewerID, verifikasiID, "thumbnail"); err != nil {
+					return nil, err
+				}
+			}
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// findDocument loads the SourceFile named by the :id parameter, writing an
+// error response and returning false when it cannot
+func findDocument(c *gin.Context) (models.SourceFile, bool) {
+	var file models.SourceFile
+	if err := config.DB.First(&file, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		} else {
+			log.Printf("Error finding document: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find document"})
+		}
+		return file, false
+	}
+	return file, true
+}
+
+// GET /api/documents/:id
+func GetDocument(c *gin.Context) {
+	file, ok := findDocument(c)
+	if !ok {
+		return
+	}
+
+	allowed, err := canAccessDocument(config.DB, currentUserID(c), currentUserRole(c), file)
+	if err != nil {
+		log.Printf("Error checking document access: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check document access"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to access this document"})
+		return
+	}
+
+	response := gin.H{
+		"id":           file.ID,
+		"type":         file.SourceType,
+		"name":         file.SourceName,
+		"size":         file.Size,
+		"content_type": file.ContentType,
+		"scan_status":  file.ScanStatus,
+	}
+	if canServeDocument(file, currentUserID(c)) {
+		recordID, err := assignedRecordID(config.DB, file.ID, currentUserID(c))
+		if err != nil {
+			log.Printf("Error finding document record: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check document access"})
+			return
+		}
+		link, err := signedDocumentURL(file.ID, currentUserID(c), recordID, "")
+		thumbnail := ""
+		if err == nil && file.ThumbnailKey != "" {
+			thumbnail, err = signedDocumentURL(file.ID, currentUserID(c), recordID, "thumbnail")
+		}
+		if err != nil {
+			log.Printf("Error signing document link: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create document link"})
+			return
+		}
+		response["url"] = link
+		response["expires_in"] = int(documentURLTTL().Seconds())
+		if thumbnail != "" {
+			response["thumbnail_url"] = thumbnail
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GET /api/documents/:id/download
+func DownloadDocument(c *gin.Context) {
+	file, ok := findDocument(c)
+	if !ok {
+		return
+	}
+
+	variant := c.Query("variant")
+	expires := c.Query("expires")
+	viewer, _ := strconv.ParseUint(c.Query("viewer"), 10, 64)
+	record, _ := strconv.ParseUint(c.Query("record"), 10, 64)
+	signature, err := documentSignature(file.ID, uint(viewer), uint(record), variant, expires)
+	if err != nil {
+		log.Printf("Error checking document link: %v", err)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired link"})
+		return
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp || !hmac.Equal([]byte(c.Query("signature")), []byte(signature)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired link"})
+		return
+	}
+
+	// Re-check access so a link stops working once an assignment is withdrawn
+	var user models.User
+	if err := config.DB.First(&user, viewer).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired link"})
+		return
+	}
+	allowed, err := canAccessDocument(config.DB, user.ID, user.Role, file)
+	if err != nil || !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to access this document"})
+		return
+	}
+	if !canServeDocument(file, user.ID) {
+		if file.ScanStatus == models.ScanInfected {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Document failed the malware scan and is quarantined"})
+		} else {
+			c.JSON(http.StatusConflict, gin.H{"error": "Document is still being scanned"})
+		}
+		return
+	}
+
+	key, contentType, name := file.StorageKey, file.ContentType, file.SourceName
+	if variant == "thumbnail" {
+		if file.ThumbnailKey == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document has no thumbnail"})
+			return
+		}
+		key, contentType, name = file.ThumbnailKey, imaging.TypeJPEG, "thumbnail-"+file.SourceName
+	}
+
+	reader, err := config.Storage.Get(c.Request.Context(), key)
+	if err != nil {
+		log.Printf("Error reading document %d: %v", file.ID, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document content not found"})
+		return
+	}
+	content, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		log.Printf("Error reading document %d: %v", file.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read document"})
+		return
+	}
+
+	// Staff copies carry the viewer, time and record so a leaked scan can be traced
+	staff := user.Role == "verifikator" || user.Role == "admin"
+	if staff {
+		content, err = watermarkDocument(content, contentType, watermarkText(user, uint(record), file.ID))
+		if err != nil {
+			log.Printf("Error watermarking document %d: %v", file.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare document"})
+			return
+		}
+	}
+
+	recordAudit(config.DB, user.ID, "document.view", "document", file.ID,
+		fmt.Sprintf("type=%s variant=%s record=%d watermarked=%t ip=%s", file.SourceType, variant, record, staff, c.ClientIP()))
+
+	disposition := "inline"
+	if c.Query("download") == "1" {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+	c.Header("Cache-Control", "no-store, no-cache, must-revalidate, private")
+	c.Header("Pragma", "no-cache")
+	c.Header("Expires", "0")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, contentType, content)
+}
+
+// assignedRecordID returns a verification the viewer is assigned to that has
+// the document attached, or 0
+func assignedRecordID(db *gorm.DB, fileID, viewerID uint) (uint, error) {
+	var ids []uint
+	err := db.Model(&models.VerifikasiDocument{}).
+		Joins("JOIN verifikasis ON verifikasis.id = verifikasi_documents.verifikasi_id").
+		Where("verifikasi_documents.source_file_id = ? AND verifikasis.assigned_verifikator_id = ?", fileID, viewerID).
+		Order("verifikasi_documents.verifikasi_id").Limit(1).
+		Pluck("verifikasi_documents.verifikasi_id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// watermarkText identifies a staff member's copy of a document
+func watermarkText(viewer models.User, recordID, fileID uint) string {
+	record := fmt.Sprintf("Dokumen #%d", fileID)
+	if recordID != 0 {
+		record = fmt.Sprintf("Verifikasi #%d", recordID)
+	}
+	return fmt.Sprintf("%s | %s | %s", viewer.Name, time.Now().Format("2006-01-02 15:04:05 MST"), record)
+}
+
+// watermarkDocument stamps text onto a stored image or PDF
+func watermarkDocument(content []byte, contentType, text string) ([]byte, error) {
+	if contentType == "application/pdf" {
+		return watermark.PDF(content, text)
+	}
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	return imaging.Encode(watermark.Image(img, text), contentType)
+}
+
+// InlineMigrationReport summarizes a MigrateInlineDocuments run
+type InlineMigrationReport struct {
+	DryRun            bool `json:"dry_run"`
+	SourceFiles       int  `json:"source_files"`
+	VerifikasiFields  int  `json:"verifikasi_fields"`
+	Checksums         int  `json:"checksums"`
+	Skipped           int  `json:"skipped"`
+	BytesMovedOutOfDB int  `json:"bytes_moved_out_of_db"`
+}
+
+// decodeInlineDocument decodes a legacy inline Foto* value, either a data URL
+// ("data:image/png;base64,...") or bare base64
+func decodeInlineDocument(value string) ([]byte, error) {
+	if strings.HasPrefix(value, "data:") {
+		comma := strings.Index(value, ",")
+		if comma < 0 {
+			return nil, errors.New("malformed data URL")
+		}
+		value = value[comma+1:]
+	}
+	value = strings.TrimSpace(value)
+	if content, err := base64.StdEncoding.DecodeString(value); err == nil {
+		return content, nil
+	}
+	return base64.RawStdEncoding.DecodeString(value)
+}
+
+// MigrateInlineDocuments moves document content still kept inside the
+// database (SourceFile.SourceData and the legacy inline Foto* columns of
+// verifikasis)
+// into document storage and records checksums for stored files that lack one
+func MigrateInlineDocuments(dryRun bool) (InlineMigrationReport, error) {
+	ctx := context.Background()
+	report := InlineMigrationReport{DryRun: dryRun}
+
+	// 1. SourceFile rows whose bytes live in the source_data column
+	var files []models.SourceFile
+	if err := config.DB.Where("storage_key = '' OR storage_key IS NULL").Find(&files).Error; err != nil {
+		return report, err
+	}
+	for _, file := range files {
+		if len(file.SourceData) == 0 {
+			continue
+		}
+		report.SourceFiles++
+		report.BytesMovedOutOfDB += len(file.SourceData)
+		if dryRun {
+			continue
+		}
+
+		// The key is derived from the content, so rewriting an object that
+		// is already stored is harmless and a failed update leaves it shared
+		checksum := contentChecksum(file.SourceData)
+		key := contentStorageKey(checksum)
+		contentType := http.DetectContentType(file.SourceData)
+		if err := config.Storage.Put(ctx, key, bytes.NewReader(file.SourceData), int64(len(file.SourceData)), contentType); err != nil {
+			return report, err
+		}
+		err := config.DB.Model(&file).Updates(map[string]interface{}{
+			"storage_key":  key,
+			"checksum":     checksum,
+			"content_type": contentType,
+			"size":         len(file.SourceData),
+			"source_data":  nil,
+		}).Error
+		if err != nil {
+			return report, err
+		}
+	}
+
+	// 2. Stored files uploaded before checksums were recorded
+	var unhashed []models.SourceFile
+	if err := config.DB.Where("storage_key <> '' AND (checksum = '' OR checksum IS NULL)").Find(&unhashed).Error; err != nil {
+		return report, err
+	}
+	for _, file := range unhashed {
+		report.Checksums++
+		if dryRun {
+			continue
+		}
+		content, err := config.Storage.Get(ctx, file.StorageKey)
+		if err != nil {
+			log.Printf("Skipping checksum of document %d: %v", file.ID, err)
+			report.Skipped++
+			continue
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, content)
+		content.Close()
+		if err != nil {
+			return report, err
+		}
+		if err := config.DB.Model(&file).Update("checksum", hex.EncodeToString(hash.Sum(nil))).Error; err != nil {
+			return report, err
+		}
+	}
+
+	// 3. Inline base64 documents left in the legacy Foto* columns of
+	// verification records
+	var verifications []legacyVerifikasi
+	if legacyColumnsPresent(config.DB, "foto_ktp") {
+		if err := config.DB.Find(&verifications).Error; err != nil {
+			return report, err
+		}
+	}
+	for _, v := range verifications {
+		fields := map[string]string{
+			models.DocKTP:        v.FotoKTP,
+			models.DocKK:         v.FotoKK,
+			models.DocIjazah:     v.FotoIjazah,
+			models.DocSKL:        v.FotoSKL,
+			models.DocSertifikat: v.FotoSertifikat,
+		}
+
+		updates := map[string]interface{}{}
+		for docType, value := range fields {
+			if value == "" || strings.HasPrefix(value, "/api/documents/") {
+				continue
+			}
+			content, err := decodeInlineDocument(value)
+			if err == nil && dryRun {
+				_, _, _, err = processUpload(docType, content)
+			}
+			if err != nil || len(content) == 0 {
+				log.Printf("Skipping %s of verification %d: %v", docType, v.ID, err)
+				report.Skipped++
+				continue
+			}
+			if dryRun {
+				report.VerifikasiFields++
+				report.BytesMovedOutOfDB += len(value)
+				continue
+			}
+
+			err = config.DB.Transaction(func(tx *gorm.DB) error {
+				file, _, err := storeDocument(ctx, tx, v.UserID, docType, docType+"-migrated", content)
+				if err != nil {
+					return err
+				}
+				updates["foto_"+docType] = documentURL(file.ID)
+				return attachDocuments(tx, v.ID, map[string]models.SourceFile{docType: file})
+			})
+			if errors.Is(err, errInvalidDocument) {
+				log.Printf("Skipping %s of verification %d: %v", docType, v.ID, err)
+				report.Skipped++
+				continue
+			}
+			if err != nil {
+				return report, err
+			}
+			report.VerifikasiFields++
+			report.BytesMovedOutOfDB += len(value)
+		}
+
+		if len(updates) > 0 {
+			if err := config.DB.Model(&v).Updates(updates).Error; err != nil {
+				return report, err
+			}
+		}
+	}
+
+	return report, nil
+}
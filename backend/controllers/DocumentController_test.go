@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"sibestie/config"
+	"sibestie/models"
+	"sibestie/scanner"
+
+	"github.com/gin-gonic/gin"
+)
+
+// download requests a signed document link without any session
+func download(fileID uint, link string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, link, nil)
+	c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(int(fileID))}}
+	DownloadDocument(c)
+	return w
+}
+
+func TestDocumentSignature(t *testing.T) {
+	t.Setenv("SECRET_KEY", "0123456789abcdef0123456789abcdef")
+	base, err := documentSignature(7, 4, 3, "", "1700000000")
+	if err != nil {
+		t.Fatalf("documentSignature: %v", err)
+	}
+	for name, sign := range map[string]func() (string, error){
+		"file":    func() (string, error) { return documentSignature(8, 4, 3, "", "1700000000") },
+		"viewer":  func() (string, error) { return documentSignature(7, 5, 3, "", "1700000000") },
+		"record":  func() (string, error) { return documentSignature(7, 4, 0, "", "1700000000") },
+		"variant": func() (string, error) { return documentSignature(7, 4, 3, "thumbnail", "1700000000") },
+		"expires": func() (string, error) { return documentSignature(7, 4, 3, "", "1700000001") },
+	} {
+		if other, err := sign(); err != nil || other == base {
+			t.Errorf("changing the %s leaves the signature valid (%v)", name, err)
+		}
+	}
+
+	t.Setenv("SECRET_KEY", "")
+	if _, err := documentSignature(7, 4, 3, "", "1700000000"); !errors.Is(err, errNoSigningKey) {
+		t.Errorf("signing without a key: err = %v, want errNoSigningKey", err)
+	}
+	if link, err := signedDocumentURL(7, 4, 3, ""); !errors.Is(err, errNoSigningKey) || link != "" {
+		t.Errorf("link without a key = %q (%v), want none", link, err)
+	}
+}
+
+func TestCanAccessDocument(t *testing.T) {
+	db := setupTestDB(t)
+	file := models.SourceFile{UserID: 4, StorageKey: "documents/4/ktp.jpg", ScanStatus: models.ScanClean}
+	unattached := models.SourceFile{UserID: 4, StorageKey: "documents/4/kk.jpg", ScanStatus: models.ScanClean}
+	for _, f := range []*models.SourceFile{&file, &unattached} {
+		if err := db.Create(f).Error; err != nil {
+			t.Fatalf("create file: %v", err)
+		}
+	}
+	v := models.Verifikasi{UserID: 4, Status: models.StatusPending, AssignedVerifikatorID: 2}
+	if err := db.Omit("UserData").Create(&v).Error; err != nil {
+		t.Fatalf("create verification: %v", err)
+	}
+	if err := db.Create(&models.VerifikasiDocument{VerifikasiID: v.ID, SourceFileID: file.ID, DocType: "ktp"}).Error; err != nil {
+		t.Fatalf("attach document: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		userID uint
+		role   string
+		file   models.SourceFile
+		want   bool
+	}{
+		{"owner", 4, "applicant", file, true},
+		{"owner of an unattached file", 4, "applicant", unattached, true},
+		{"admin", 1, "admin", file, true},
+		{"assigned verifikator", 2, "verifikator", file, true},
+		{"unassigned verifikator", 3, "verifikator", file, false},
+		{"other applicant", 5, "applicant", file, false},
+		{"admin on an unattached file", 1, "admin", unattached, false},
+	}
+	for _, tt := range tests {
+		got, err := canAccessDocument(db, tt.userID, tt.role, tt.file)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: access %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Once the application is released any verifikator may open it
+	db.Model(&v).Update("assigned_verifikator_id", 0)
+	if ok, _ := canAccessDocument(db, 3, "verifikator", file); !ok {
+		t.Error("verifikator refused an unassigned application's document")
+	}
+}
+
+func TestDownloadDocumentChecksLink(t *testing.T) {
+	setupScanTest(t, scanner.NewFake())
+	t.Setenv("SECRET_KEY", "0123456789abcdef0123456789abcdef")
+	for _, u := range []models.User{
+		{Name: "Pendaftar", Email: "a@example.com", Role: "applicant"},
+		{Name: "Lain", Email: "b@example.com", Role: "applicant"},
+	} {
+		if err := config.DB.Create(&u).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	owner, other := uint(1), uint(2)
+	file := storeScanFixture(t, "a harmless scan", 1)[0]
+	config.DB.Model(&file).Updates(map[string]interface{}{"user_id": owner, "scan_status": models.ScanClean})
+
+	link, err := signedDocumentURL(file.ID, owner, 0, "")
+	if err != nil {
+		t.Fatalf("signedDocumentURL: %v", err)
+	}
+	if w := download(file.ID, link); w.Code != http.StatusOK || w.Body.String() != "a harmless scan" {
+		t.Fatalf("valid link: status %d: %s", w.Code, w.Body)
+	}
+
+	u, _ := url.Parse(link)
+	expired := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	expiredSignature, _ := documentSignature(file.ID, owner, 0, "", expired)
+	for name, change := range map[string]func(q url.Values){
+		"viewer":    func(q url.Values) { q.Set("viewer", strconv.Itoa(int(other))) },
+		"record":    func(q url.Values) { q.Set("record", "9") },
+		"variant":   func(q url.Values) { q.Set("variant", "thumbnail") },
+		"expires":   func(q url.Values) { q.Set("expires", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)) },
+		"signature": func(q url.Values) { q.Set("signature", "") },
+		"expired":   func(q url.Values) { q.Set("expires", expired); q.Set("signature", expiredSignature) },
+	} {
+		q := u.Query()
+		change(q)
+		if w := download(file.ID, u.Path+"?"+q.Encode()); w.Code != http.StatusForbidden {
+			t.Errorf("%s changed: status %d, want 403", name, w.Code)
+		}
+	}
+
+	// Once the key is unset, no link is accepted
+	t.Setenv("SECRET_KEY", "")
+	if w := download(file.ID, link); w.Code != http.StatusForbidden {
+		t.Errorf("valid link without a key: status %d, want 403", w.Code)
+	}
+}
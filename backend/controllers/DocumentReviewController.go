@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"sibestie/config"
+	"sibestie/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DocumentReviewRequest sets the review status of one attached document
+type DocumentReviewRequest struct {
+	Status string `json:"status" binding:"required"`
+	Note   string `json:"note"`
+}
+
+// DocumentAnnotationRequest marks a region of an attached document
+type DocumentAnnotationRequest struct {
+	Page   int     `json:"page"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width" binding:"required"`
+	Height float64 `json:"height" binding:"required"`
+	Note   string  `json:"note" binding:"required"`
+}
+
+var errRequiredDocumentInvalid = errors.New("a required document is marked invalid")
+
+// invalidRequiredDocuments returns the document types a verification's
+// scholarship requires that a verifikator marked invalid
+func invalidRequiredDocuments(db *gorm.DB, verifikasiID uint) ([]string, error) {
+	var docTypes []string
+	err := db.Model(&models.VerifikasiDocument{}).
+		Where("verifikasi_id = ? AND review_status = ?", verifikasiID, models.DocumentInvalid).
+		Pluck("doc_type", &docTypes).Error
+	if err != nil {
+		return nil, err
+	}
+	requirements, err := verifikasiRequirements(db, verifikasiID)
+	if err != nil {
+		return nil, err
+	}
+
+	invalid := []string{}
+	for _, docType := range docTypes {
+		if requirements[docType].Required {
+			invalid = append(invalid, docType)
+		}
+	}
+	return invalid, nil
+}
+
+// deleteDocumentAnnotations removes the annotations of the document links
+// selected by linkIDs, a subquery returning verifikasi_documents.id
+func deleteDocumentAnnotations(tx *gorm.DB, linkIDs *gorm.DB) error {
+	return tx.Where("verifikasi_document_id IN (?)", linkIDs).Delete(&models.DocumentAnnotation{}).Error
+}
+
+// findReviewableDocument loads the verification and attached document named
+// by the :id and :document_id parameters and checks that the current user
+// may review it. It writes an error response and returns false when not.
+func findReviewableDocument(c *gin.Context) (models.Verifikasi, models.VerifikasiDocument, bool) {
+	var verifikasi models.Verifikasi
+	var link models.VerifikasiDocument
+
+	if err := withApplicant(config.DB).First(&verifikasi, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Verification data not found"})
+		} else {
+			log.Printf("Error finding verification for document review: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find verification data"})
+		}
+		return verifikasi, link, false
+	}
+
+	err := config.DB.Where("verifikasi_id = ? AND source_file_id = ?", verifikasi.ID, c.Param("document_id")).First(&link).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document is not attached to this verification"})
+		} else {
+			log.Printf("Error finding verification document: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find verification document"})
+		}
+		return verifikasi, link, false
+	}
+
+	if verifikasi.Status != models.StatusPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only documents of pending verifications can be reviewed"})
+		return verifikasi, link, false
+	}
+	userID := currentUserID(c)
+	if verifikasi.AssignedVerifikatorID != 0 && verifikasi.AssignedVerifikatorID != userID && currentUserRole(c) != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Verification is assigned to another verifikator"})
+		return verifikasi, link, false
+	}
+	if !checkConflict(c, userID, verifikasi) {
+		return verifikasi, link, false
+	}
+	return verifikasi, link, true
+}
+
+// PUT /api/verifikasi/:id/documents/:document_id/review
+func ReviewVerifikasiDocument(c *gin.Context) {
+	var input DocumentReviewRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review data: " + err.Error()})
+		return
+	}
+	switch input.Status {
+	case models.DocumentUnchecked, models.DocumentValid:
+	case models.DocumentInvalid:
+		if input.Note == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A note is required when marking a document invalid"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown document status",
+			"allowed": []string{models.DocumentUnchecked, models.DocumentValid, models.DocumentInvalid}})
+		return
+	}
+
+	_, link, ok := findReviewableDocument(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	link.ReviewStatus = input.Status
+	link.ReviewNote = input.Note
+	link.ReviewedBy = currentUserID(c)
+	link.ReviewedAt = &now
+	if input.Status == models.DocumentUnchecked {
+		link.ReviewedBy = 0
+		link.ReviewedAt = nil
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&link).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, currentUserID(c), "document.review", "verifikasi", link.VerifikasiID,
+			fmt.Sprintf("document_id=%d type=%s status=%s note=%q", link.SourceFileID, link.DocType, link.ReviewStatus, link.ReviewNote))
+	})
+	if err != nil {
+		log.Printf("Error saving document review: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save document review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document review saved", "data": link})
+}
+
+// POST /api/verifikasi/:id/documents/:document_id/annotations
+func CreateDocumentAnnotation(c *gin.Context) {
+	var input DocumentAnnotationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid annotation data: " + err.Error()})
+		return
+	}
+	if input.Page == 0 {
+		input.Page = 1
+	}
+	if input.Page < 0 || input.X < 0 || input.Y < 0 || input.Width <= 0 || input.Height <= 0 ||
+		input.X+input.Width > 1 || input.Y+input.Height > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Annotation region must lie within the page, as fractions between 0 and 1"})
+		return
+	}
+
+	_, link, ok := findReviewableDocument(c)
+	if !ok {
+		return
+	}
+
+	annotation := models.DocumentAnnotation{
+		VerifikasiDocumentID: link.ID,
+		AuthorID:             currentUserID(c),
+		Page:                 input.Page,
+		X:                    input.X,
+		Y:                    input.Y,
+		Width:                input.Width,
+		Height:               input.Height,
+		Note:                 input.Note,
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&annotation).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, annotation.AuthorID, "document.annotate", "verifikasi", link.VerifikasiID,
+			fmt.Sprintf("document_id=%d annotation_id=%d page=%d note=%q", link.SourceFileID, annotation.ID, annotation.Page, annotation.Note))
+	})
+	if err != nil {
+		log.Printf("Error creating document annotation: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create annotation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Annotation created", "data": annotation})
+}
+
+// DELETE /api/verifikasi/:id/documents/:document_id/annotations/:annotation_id
+func DeleteDocumentAnnotation(c *gin.Context) {
+	_, link, ok := findReviewableDocument(c)
+	if !ok {
+		return
+	}
+
+	var annotation models.DocumentAnnotation
+	err := config.DB.Where("id = ? AND verifikasi_document_id = ?", c.Param("annotation_id"), link.ID).First(&annotation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Annotation not found"})
+		} else {
+			log.Printf("Error finding document annotation: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find annotation"})
+		}
+		return
+	}
+	if annotation.AuthorID != currentUserID(c) && currentUserRole(c) != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can delete an annotation"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&annotation).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, currentUserID(c), "document.annotation_delete", "verifikasi", link.VerifikasiID,
+			fmt.Sprintf("document_id=%d annotation_id=%d author_id=%d note=%q", link.SourceFileID, annotation.ID, annotation.AuthorID, annotation.Note))
+	})
+	if err != nil {
+		log.Printf("Error deleting document annotation: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete annotation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Annotation deleted"})
+}
@@ -0,0 +1,360 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"sibestie/config"
+	"sibestie/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// nameSimilarityThreshold is the similarity from which two names with the
+// same birth date are taken to be the same applicant
+const nameSimilarityThreshold = 0.85
+
+// DuplicateLink points a verifikator at an application suspected to come
+// from the same applicant
+type DuplicateLink struct {
+	VerifikasiID uint     `json:"verifikasi_id"`
+	UserID       uint     `json:"user_id"`
+	NamaLengkap  string   `json:"nama_lengkap"`
+	Status       string   `json:"status"`
+	Reasons      []string `json:"reasons"`
+	Details      []string `json:"details"`
+	URL          string   `json:"url"`
+}
+
+// DuplicateCluster is a group of applications linked by duplicate flags
+type DuplicateCluster struct {
+	Applications []DuplicateLink        `json:"applications"`
+	Flags        []models.DuplicateFlag `json:"flags"`
+}
+
+// duplicateCandidate is an application whose applicant data may match
+type duplicateCandidate struct {
+	VerifikasiID uint
+	NIK          string
+	NISN         string
+	FullName     string
+	BirthDate    *time.Time
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// nameSimilarity compares two names from 0 (different) to 1 (same),
+// ignoring case, spacing and word order
+func nameSimilarity(a, b string) float64 {
+	wordsA := strings.Fields(normalizeName(a))
+	wordsB := strings.Fields(normalizeName(b))
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+	sort.Strings(wordsA)
+	sort.Strings(wordsB)
+	runesA := []rune(strings.Join(wordsA, " "))
+	runesB := []rune(strings.Join(wordsB, " "))
+	longest := max(len(runesA), len(runesB))
+	return 1 - float64(levenshtein(runesA, runesB))/float64(longest)
+}
+
+// findDuplicates compares an application, loaded withApplicant, against the
+// applications of other accounts. Anonymized applications have no data left
+// to compare.
+func findDuplicates(db *gorm.DB, verifikasi models.Verifikasi) ([]models.DuplicateFlag, error) {
+	applicant := verifikasi.UserData
+	var flags []models.DuplicateFlag
+	flag := func(otherID uint, reason, detail string) {
+		flags = append(flags, models.DuplicateFlag{
+			VerifikasiID:  verifikasi.ID,
+			DuplicateOfID: otherID,
+			Reason:        reason,
+			Detail:        detail,
+		})
+	}
+
+	others := db.Table("verifikasis").
+		Where("verifikasis.id <> ? AND verifikasis.user_id <> ?", verifikasi.ID, verifikasi.UserID).
+		Where("verifikasis.anonymized_at IS NULL")
+
+	// Identity numbers and names with the same birth date
+	conditions := db.Where("1 = 0")
+	if applicant.NIK != "" {
+		conditions = conditions.Or("user_data.nik = ?", applicant.NIK)
+	}
+	if applicant.NISN != "" {
+		conditions = conditions.Or("user_data.nisn = ?", applicant.NISN)
+	}
+	if applicant.BirthDate != nil {
+		day := applicant.BirthDate.Truncate(24 * time.Hour)
+		conditions = conditions.Or("user_data.birth_date >= ? AND user_data.birth_date < ?", day, day.Add(24*time.Hour))
+	}
+	var candidates []duplicateCandidate
+	err := others.Session(&gorm.Session{}).
+		Select("verifikasis.id AS verifikasi_id, user_data.nik, user_data.nisn, user_data.full_name, user_data.birth_date").
+		Joins("JOIN user_data ON user_data.id = verifikasis.user_data_id AND user_data.deleted_at IS NULL").
+		Where(conditions).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range candidates {
+		if applicant.NIK != "" && candidate.NIK == applicant.NIK {
+			flag(candidate.VerifikasiID, models.DuplicateNIK, candidate.NIK)
+		}
+		if applicant.NISN != "" && candidate.NISN == applicant.NISN {
+			flag(candidate.VerifikasiID, models.DuplicateNISN, candidate.NISN)
+		}
+		if applicant.BirthDate != nil && candidate.BirthDate != nil &&
+			candidate.BirthDate.Format("2006-01-02") == applicant.BirthDate.Format("2006-01-02") &&
+			nameSimilarity(candidate.FullName, applicant.FullName) >= nameSimilarityThreshold {
+			flag(candidate.VerifikasiID, models.DuplicateNameBirth, fmt.Sprintf("%s / %s", applicant.FullName, candidate.FullName))
+		}
+	}
+
+	// Uploads of the same file content
+	var matches []struct {
+		VerifikasiID uint
+		DocType      string
+	}
+	err = others.Session(&gorm.Session{}).
+		Select("DISTINCT verifikasis.id AS verifikasi_id, verifikasi_documents.doc_type").
+		Joins("JOIN verifikasi_documents ON verifikasi_documents.verifikasi_id = verifikasis.id").
+		Joins("JOIN source_files ON source_files.id = verifikasi_documents.source_file_id").
+		Where("source_files.checksum <> '' AND source_files.checksum IN (?)",
+			db.Table("verifikasi_documents").
+				Select("source_files.checksum").
+				Joins("JOIN source_files ON source_files.id = verifikasi_documents.source_file_id").
+				Where("verifikasi_documents.verifikasi_id = ?", verifikasi.ID)).
+		Find(&matches).Error
+	if err != nil {
+		return nil, err
+	}
+	docTypes := map[uint][]string{}
+	var matched []uint
+	for _, match := range matches {
+		if _, ok := docTypes[match.VerifikasiID]; !ok {
+			matched = append(matched, match.VerifikasiID)
+		}
+		docTypes[match.VerifikasiID] = append(docTypes[match.VerifikasiID], match.DocType)
+	}
+	for _, otherID := range matched {
+		sort.Strings(docTypes[otherID])
+		flag(otherID, models.DuplicateDocument, strings.Join(docTypes[otherID], ", "))
+	}
+
+	return flags, nil
+}
+
+// flagDuplicates stores the duplicate flags of a new application and marks
+// both it and the applications it resembles for verifikators
+func flagDuplicates(tx *gorm.DB, verifikasi *models.Verifikasi) error {
+	flags, err := findDuplicates(tx, *verifikasi)
+	if err != nil || len(flags) == 0 {
+		return err
+	}
+	if err := tx.Create(&flags).Error; err != nil {
+		return err
+	}
+
+	ids := []uint{verifikasi.ID}
+	summary := make([]string, 0, len(flags))
+	for _, f := range flags {
+		ids = append(ids, f.DuplicateOfID)
+		summary = append(summary, fmt.Sprintf("%d (%s)", f.DuplicateOfID, f.Reason))
+	}
+	if err := tx.Model(&models.Verifikasi{}).Where("id IN ?", ids).Update("duplicate_suspected", true).Error; err != nil {
+		return err
+	}
+	verifikasi.DuplicateSuspected = true
+
+	log.Printf("Verification %d flagged as possible duplicate of %s", verifikasi.ID, strings.Join(summary, ", "))
+	return recordAudit(tx, 0, "verifikasi.duplicate_flagged", "verifikasi", verifikasi.ID, strings.Join(summary, ", "))
+}
+
+// clearDuplicateFlags removes the flags of an application, for instance when
+// it is anonymized, and unmarks the applications left without flags
+func clearDuplicateFlags(tx *gorm.DB, verifikasiID uint) error {
+	var flags []models.DuplicateFlag
+	if err := tx.Where("verifikasi_id = ? OR duplicate_of_id = ?", verifikasiID, verifikasiID).Find(&flags).Error; err != nil {
+		return err
+	}
+	if len(flags) == 0 {
+		return nil
+	}
+	if err := tx.Where("verifikasi_id = ? OR duplicate_of_id = ?", verifikasiID, verifikasiID).Delete(&models.DuplicateFlag{}).Error; err != nil {
+		return err
+	}
+
+	for _, f := range flags {
+		other := f.DuplicateOfID
+		if other == verifikasiID {
+			other = f.VerifikasiID
+		}
+		var remaining int64
+		if err := tx.Model(&models.DuplicateFlag{}).Where("verifikasi_id = ? OR duplicate_of_id = ?", other, other).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining == 0 {
+			if err := tx.Model(&models.Verifikasi{}).Where("id = ?", other).Update("duplicate_suspected", false).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// duplicateLinks builds links to the applications the given flags point at,
+// seen from the application with the given ID
+func duplicateLinks(db *gorm.DB, verifikasiID uint, flags []models.DuplicateFlag) ([]DuplicateLink, error) {
+	byID := map[uint]*DuplicateLink{}
+	var order []uint
+	for _, f := range flags {
+		other := f.DuplicateOfID
+		if other == verifikasiID {
+			other = f.VerifikasiID
+		}
+		link, ok := byID[other]
+		if !ok {
+			link = &DuplicateLink{VerifikasiID: other, URL: fmt.Sprintf("/api/verifikasi/%d", other)}
+			byID[other] = link
+			order = append(order, other)
+		}
+		link.Reasons = append(link.Reasons, f.Reason)
+		link.Details = append(link.Details, f.Detail)
+	}
+	if len(order) == 0 {
+		return []DuplicateLink{}, nil
+	}
+
+	var records []models.Verifikasi
+	if err := db.Preload("UserData").Where("id IN ?", order).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		link := byID[record.ID]
+		link.UserID = record.UserID
+		link.NamaLengkap = record.UserData.FullName
+		link.Status = record.Status
+	}
+
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+	links := make([]DuplicateLink, 0, len(order))
+	for _, id := range order {
+		links = append(links, *byID[id])
+	}
+	return links, nil
+}
+
+// verifikasiDuplicates returns the applications suspected to come from the
+// same applicant as the given one
+func verifikasiDuplicates(db *gorm.DB, verifikasiID uint) ([]DuplicateLink, error) {
+	var flags []models.DuplicateFlag
+	if err := db.Where("verifikasi_id = ? OR duplicate_of_id = ?", verifikasiID, verifikasiID).Order("id").Find(&flags).Error; err != nil {
+		return nil, err
+	}
+	return duplicateLinks(db, verifikasiID, flags)
+}
+
+// GET /api/verifikasi/duplicates
+func ListDuplicateClusters(c *gin.Context) {
+	var flags []models.DuplicateFlag
+	if err := config.DB.Order("id").Find(&flags).Error; err != nil {
+		log.Printf("Error querying duplicate flags: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query duplicate flags"})
+		return
+	}
+
+	// Applications linked directly or through other applications form one cluster
+	parent := map[uint]uint{}
+	var find func(id uint) uint
+	find = func(id uint) uint {
+		if p, ok := parent[id]; ok && p != id {
+			parent[id] = find(p)
+			return parent[id]
+		}
+		parent[id] = id
+		return id
+	}
+	for _, f := range flags {
+		a, b := find(f.VerifikasiID), find(f.DuplicateOfID)
+		if a != b {
+			parent[max(a, b)] = min(a, b)
+		}
+	}
+
+	clusterFlags := map[uint][]models.DuplicateFlag{}
+	for _, f := range flags {
+		root := find(f.VerifikasiID)
+		clusterFlags[root] = append(clusterFlags[root], f)
+	}
+
+	roots := make([]uint, 0, len(clusterFlags))
+	for root := range clusterFlags {
+		roots = append(roots, root)
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i] < roots[j] })
+
+	clusters := make([]DuplicateCluster, 0, len(roots))
+	for _, root := range roots {
+		members := []uint{}
+		for id := range parent {
+			if find(id) == root {
+				members = append(members, id)
+			}
+		}
+		sort.Slice(members, func(i, j int) bool { return members[i] < members[j] })
+
+		var records []models.Verifikasi
+		if err := config.DB.Preload("UserData").Where("id IN ?", members).Order("id").Find(&records).Error; err != nil {
+			log.Printf("Error loading duplicate cluster: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load duplicate clusters"})
+			return
+		}
+		applications := make([]DuplicateLink, 0, len(records))
+		for _, record := range records {
+			link := DuplicateLink{
+				VerifikasiID: record.ID,
+				UserID:       record.UserID,
+				NamaLengkap:  record.UserData.FullName,
+				Status:       record.Status,
+				URL:          fmt.Sprintf("/api/verifikasi/%d", record.ID),
+			}
+			for _, f := range clusterFlags[root] {
+				if f.VerifikasiID == record.ID || f.DuplicateOfID == record.ID {
+					link.Reasons = append(link.Reasons, f.Reason)
+					link.Details = append(link.Details, f.Detail)
+				}
+			}
+			applications = append(applications, link)
+		}
+		clusters = append(clusters, DuplicateCluster{Applications: applications, Flags: clusterFlags[root]})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"total": len(clusters), "clusters": clusters})
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"sibestie/models"
+
+	"gorm.io/gorm"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		match bool
+	}{
+		{"Siti Nurhaliza", "Siti Nurhaliza", true},
+		{"Siti Nurhaliza", "  siti   NURHALIZA ", true},
+		{"Siti Nurhaliza", "Nurhaliza Siti", true},
+		{"Siti Nurhaliza", "Siti Nurhalisa", true},
+		{"Muhammad Rizky Pratama", "Muhamad Rizki Pratama", true},
+		{"Siti Nurhaliza", "Siti Aminah", false},
+		{"Budi Santoso", "Siti Nurhaliza", false},
+		{"Siti Nurhaliza", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got := nameSimilarity(tt.a, tt.b)
+		if (got >= nameSimilarityThreshold) != tt.match {
+			t.Errorf("nameSimilarity(%q, %q) = %.2f, match %v", tt.a, tt.b, got, tt.match)
+		}
+		if back := nameSimilarity(tt.b, tt.a); back != got {
+			t.Errorf("nameSimilarity(%q, %q) = %.2f one way and %.2f the other", tt.a, tt.b, got, back)
+		}
+	}
+	if got := nameSimilarity("Siti Nurhaliza", "Nurhaliza Siti"); got != 1 {
+		t.Errorf("word order changed the similarity to %.2f", got)
+	}
+}
+
+// createApplicant creates an application with applicant data and, when
+// checksum is set, one uploaded document with that content checksum
+func createApplicant(t *testing.T, db *gorm.DB, userID uint, data models.UserData, checksum string) models.Verifikasi {
+	t.Helper()
+	data.UserID = userID
+	v := models.Verifikasi{UserID: userID, Status: models.StatusPending, UserData: data}
+	if err := db.Create(&v).Error; err != nil {
+		t.Fatalf("create verification: %v", err)
+	}
+	if checksum != "" {
+		file := models.SourceFile{UserID: userID, SourceType: "kk", Checksum: checksum}
+		if err := db.Create(&file).Error; err != nil {
+			t.Fatalf("create file: %v", err)
+		}
+		if err := db.Create(&models.VerifikasiDocument{VerifikasiID: v.ID, SourceFileID: file.ID, DocType: "kk"}).Error; err != nil {
+			t.Fatalf("attach document: %v", err)
+		}
+	}
+	return v
+}
+
+func TestFindDuplicates(t *testing.T) {
+	db := setupTestDB(t)
+	birth := time.Date(2005, 8, 12, 0, 0, 0, 0, time.UTC)
+	otherBirth := time.Date(2006, 1, 3, 0, 0, 0, 0, time.UTC)
+	const (
+		nik      = "3273015208050001"
+		nisn     = "0051234567"
+		checksum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	)
+
+	applicant := createApplicant(t, db, 4, models.UserData{NIK: nik, NISN: nisn, FullName: "Siti Nurhaliza", BirthDate: &birth}, checksum)
+	tests := []struct {
+		name   string
+		userID uint
+		data   models.UserData
+		sum    string
+		want   []string
+	}{
+		{"same NIK", 5, models.UserData{NIK: nik, FullName: "Andi", BirthDate: &otherBirth}, "", []string{models.DuplicateNIK}},
+		{"same NISN", 6, models.UserData{NISN: nisn, FullName: "Budi", BirthDate: &otherBirth}, "", []string{models.DuplicateNISN}},
+		{"similar name, same birth date", 7, models.UserData{FullName: "Nurhalisa Siti", BirthDate: &birth}, "", []string{models.DuplicateNameBirth}},
+		{"other name, same birth date", 8, models.UserData{FullName: "Budi Santoso", BirthDate: &birth}, "", nil},
+		{"same name, other birth date", 9, models.UserData{FullName: "Siti Nurhaliza", BirthDate: &otherBirth}, "", nil},
+		{"same document", 10, models.UserData{FullName: "Citra", BirthDate: &otherBirth}, checksum, []string{models.DuplicateDocument}},
+		{"everything", 11, models.UserData{NIK: nik, NISN: nisn, FullName: "SITI NURHALIZA", BirthDate: &birth}, checksum,
+			[]string{models.DuplicateDocument, models.DuplicateNameBirth, models.DuplicateNIK, models.DuplicateNISN}},
+		// The applicant's own other applications are not duplicates
+		{"same account", 4, models.UserData{NIK: nik, NISN: nisn, FullName: "Siti Nurhaliza", BirthDate: &birth}, checksum, nil},
+	}
+	ids := map[uint]string{}
+	for _, tt := range tests {
+		ids[createApplicant(t, db, tt.userID, tt.data, tt.sum).ID] = tt.name
+	}
+	// Anonymized applications have nothing left to compare
+	erased := createApplicant(t, db, 12, models.UserData{NIK: nik}, "")
+	db.Model(&erased).Update("anonymized_at", time.Now())
+
+	if err := withApplicant(db).First(&applicant, applicant.ID).Error; err != nil {
+		t.Fatalf("reload applicant: %v", err)
+	}
+	flags, err := findDuplicates(db, applicant)
+	if err != nil {
+		t.Fatalf("findDuplicates: %v", err)
+	}
+	got := map[string][]string{}
+	for _, f := range flags {
+		name, ok := ids[f.DuplicateOfID]
+		if !ok {
+			t.Errorf("flagged application %d (%s)", f.DuplicateOfID, f.Reason)
+			continue
+		}
+		if f.VerifikasiID != applicant.ID {
+			t.Errorf("%s: flag on %d, want %d", name, f.VerifikasiID, applicant.ID)
+		}
+		got[name] = append(got[name], f.Reason)
+	}
+	for _, tt := range tests {
+		sort.Strings(got[tt.name])
+		if fmt.Sprint(got[tt.name]) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: reasons %v, want %v", tt.name, got[tt.name], tt.want)
+		}
+	}
+}
+
+func TestListDuplicateClustersMergesLinkedApplications(t *testing.T) {
+	db := setupTestDB(t)
+	var ids []uint
+	for userID := uint(4); userID < 11; userID++ {
+		ids = append(ids, createApplication(t, db, userID, models.StatusPending).ID)
+	}
+	// 0-1 and 2-3 start apart and are merged by 3-1; 4-5 stay on their own
+	// and 6 has no flags
+	for _, pair := range [][2]int{{1, 0}, {2, 3}, {3, 1}, {5, 4}} {
+		flag := models.DuplicateFlag{VerifikasiID: ids[pair[0]], DuplicateOfID: ids[pair[1]], Reason: models.DuplicateNIK}
+		if err := db.Create(&flag).Error; err != nil {
+			t.Fatalf("create flag: %v", err)
+		}
+	}
+
+	w := serve(ListDuplicateClusters, 2, "verifikator", http.MethodGet, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var response struct {
+		Clusters []DuplicateCluster `json:"clusters"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	want := []struct {
+		members []uint
+		flags   int
+	}{
+		{[]uint{ids[0], ids[1], ids[2], ids[3]}, 3},
+		{[]uint{ids[4], ids[5]}, 1},
+	}
+	if len(response.Clusters) != len(want) {
+		t.Fatalf("%d clusters, want %d: %+v", len(response.Clusters), len(want), response.Clusters)
+	}
+	for i, w := range want {
+		cluster := response.Clusters[i]
+		var members []uint
+		for _, application := range cluster.Applications {
+			members = append(members, application.VerifikasiID)
+			if len(application.Reasons) == 0 {
+				t.Errorf("cluster %d: application %d listed without reasons", i, application.VerifikasiID)
+			}
+		}
+		if fmt.Sprint(members) != fmt.Sprint(w.members) || len(cluster.Flags) != w.flags {
+			t.Errorf("cluster %d = %v with %d flags, want %v with %d", i, members, len(cluster.Flags), w.members, w.flags)
+		}
+	}
+}
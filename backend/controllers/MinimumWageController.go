@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"sibestie/config"
+	"sibestie/models"
+	"sibestie/tools/identity"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MinimumWageInput is the minimum wage of one region in one year
+type MinimumWageInput struct {
+	RegionCode string `json:"region_code" binding:"required"`
+	RegionName string `json:"region_name"`
+	Year       int    `json:"year" binding:"required"`
+	Amount     int64  `json:"amount" binding:"required"`
+}
+
+// MinimumWagesRequest adds or updates minimum wages
+type MinimumWagesRequest struct {
+	Wages []MinimumWageInput `json:"wages" binding:"required"`
+}
+
+// defaultMinimumWages seeds the table on first start with the 2024
+// provincial minimum wages (UMP) of the provinces most applicants come from
+var defaultMinimumWages = []models.MinimumWage{
+	{RegionCode: "11", RegionName: "Aceh", Year: 2024, Amount: 3460672},
+	{RegionCode: "12", RegionName: "Sumatera Utara", Year: 2024, Amount: 2809915},
+	{RegionCode: "31", RegionName: "DKI Jakarta", Year: 2024, Amount: 5067381},
+	{RegionCode: "32", RegionName: "Jawa Barat", Year: 2024, Amount: 2057495},
+	{RegionCode: "33", RegionName: "Jawa Tengah", Year: 2024, Amount: 2036947},
+	{RegionCode: "34", RegionName: "DI Yogyakarta", Year: 2024, Amount: 2125897},
+	{RegionCode: "35", RegionName: "Jawa Timur", Year: 2024, Amount: 2165244},
+	{RegionCode: "36", RegionName: "Banten", Year: 2024, Amount: 2727812},
+	{RegionCode: "51", RegionName: "Bali", Year: 2024, Amount: 2813672},
+}
+
+// SeedMinimumWages fills the minimum wage table when it is empty
+func SeedMinimumWages() {
+	var count int64
+	if err := config.DB.Model(&models.MinimumWage{}).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	for _, wage := range defaultMinimumWages {
+		if err := config.DB.Create(&wage).Error; err != nil {
+			log.Printf("Error seeding minimum wage %s/%d: %v", wage.RegionCode, wage.Year, err)
+		}
+	}
+}
+
+// SnapshotMinimumWages stores the minimum wage on applications scored
+// before it was kept with the score, taken from the wage table as it is
+// now, so later changes to the table no longer change their scores
+func SnapshotMinimumWages() {
+	var applications []models.Verifikasi
+	if err := withApplicant(config.DB).Where("applied_umr IS NULL").Find(&applications).Error; err != nil {
+		log.Printf("Error finding verifications without a minimum wage: %v", err)
+		return
+	}
+
+	stored := 0
+	for _, verifikasi := range applications {
+		applicant, err := scoringApplicant(config.DB, verifikasiToData(verifikasi), nil)
+		if err != nil {
+			log.Printf("Error finding the minimum wage of verification %d: %v", verifikasi.ID, err)
+			continue
+		}
+		if err := config.DB.Model(&verifikasi).Update("applied_umr", int64(applicant.UMR)).Error; err != nil {
+			log.Printf("Error storing the minimum wage of verification %d: %v", verifikasi.ID, err)
+			continue
+		}
+		stored++
+	}
+	if stored > 0 {
+		log.Printf("[MIGRATE] Stored the minimum wage applied to %d verifications", stored)
+	}
+}
+
+// regionalMinimumWage returns the minimum wage in force in the applicant's
+// region in the given year, preferring the regency or city wage (UMK) over
+// the provincial one (UMP). The region is read from the NIK. It returns
+// false when no wage is on record.
+func regionalMinimumWage(db *gorm.DB, nik string, year int) (models.MinimumWage, bool, error) {
+	var wage models.MinimumWage
+	parsed, err := identity.ParseNIK(nik)
+	if err != nil {
+		return wage, false, nil
+	}
+
+	var wages []models.MinimumWage
+	err = db.Where("region_code IN ? AND year <= ?", []string{parsed.Province + parsed.Regency, parsed.Province}, year).
+		Order("LENGTH(region_code) desc, year desc").
+		Limit(1).
+		Find(&wages).Error
+	if err != nil || len(wages) == 0 {
+		return wage, false, err
+	}
+	return wages[0], true, nil
+}
+
+// GET /api/minimum-wages
+func ListMinimumWages(c *gin.Context) {
+	query := config.DB.Order("region_code, year desc")
+	if year := c.Query("year"); year != "" {
+		query = query.Where("year = ?", year)
+	}
+	if region := c.Query("region_code"); region != "" {
+		query = query.Where("region_code = ?", region)
+	}
+
+	var wages []models.MinimumWage
+	if err := query.Find(&wages).Error; err != nil {
+		log.Printf("Error querying minimum wages: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query minimum wages"})
+		return
+	}
+	c.JSON(http.StatusOK, wages)
+}
+
+// PUT /api/minimum-wages
+func SetMinimumWages(c *gin.Context) {
+	var input MinimumWagesRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid minimum wages: " + err.Error()})
+		return
+	}
+
+	actorID := currentUserID(c)
+	wages := make([]models.MinimumWage, 0, len(input.Wages))
+	for _, w := range input.Wages {
+		code := strings.TrimSpace(w.RegionCode)
+		if len(code) != 2 && len(code) != 4 || strings.Trim(code, "0123456789") != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "region_code must be a 2-digit province or 4-digit regency code: " + w.RegionCode})
+			return
+		}
+		if w.Year < 2000 || w.Year > time.Now().Year()+1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid year %d for region %s", w.Year, code)})
+			return
+		}
+		if w.Amount <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be positive for region " + code})
+			return
+		}
+		wages = append(wages, models.MinimumWage{
+			RegionCode: code,
+			RegionName: strings.TrimSpace(w.RegionName),
+			Year:       w.Year,
+			Amount:     w.Amount,
+			UpdatedBy:  actorID,
+		})
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range wages {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "region_code"}, {Name: "year"}},
+				DoUpdates: clause.AssignmentColumns([]string{"region_name", "amount", "updated_by", "updated_at"}),
+			}).Create(&wages[i]).Error
+			if err != nil {
+				return err
+			}
+			if err := recordAudit(tx, actorID, "minimum_wage.set", "minimum_wage", wages[i].ID,
+				fmt.Sprintf("region=%s year=%d amount=%d", wages[i].RegionCode, wages[i].Year, wages[i].Amount)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error saving minimum wages: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save minimum wages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Minimum wages saved", "count": len(wages)})
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"testing"
+
+	"sibestie/models"
+)
+
+func TestRegionalMinimumWage(t *testing.T) {
+	db := setupTestDB(t)
+	for _, wage := range []models.MinimumWage{
+		{RegionCode: "32", Year: 2023, Amount: 1986670},
+		{RegionCode: "32", Year: 2024, Amount: 2057495},
+		{RegionCode: "3273", Year: 2023, Amount: 4209309},
+		{RegionCode: "3273", Year: 2025, Amount: 4482914},
+	} {
+		if err := db.Create(&wage).Error; err != nil {
+			t.Fatalf("create minimum wage: %v", err)
+		}
+	}
+
+	const (
+		bandung = "3273015208050001" // Kota Bandung, Jawa Barat
+		bogor   = "3201015208050001" // Kab. Bogor, no city wage on record
+		jakarta = "3173051203980002" // DKI Jakarta, no wage on record
+	)
+	tests := []struct {
+		name   string
+		nik    string
+		year   int
+		amount int64
+		found  bool
+	}{
+		{"city wage over a newer provincial one", bandung, 2024, 4209309, true},
+		{"latest city wage", bandung, 2025, 4482914, true},
+		{"city wage from before the year", bandung, 2030, 4482914, true},
+		{"provincial wage without a city wage", bogor, 2024, 2057495, true},
+		{"provincial wage of the year", bogor, 2023, 1986670, true},
+		{"no wage yet", bandung, 2022, 0, false},
+		{"region without wages", jakarta, 2024, 0, false},
+		{"invalid NIK", "12345", 2024, 0, false},
+	}
+	for _, tt := range tests {
+		wage, found, err := regionalMinimumWage(db, tt.nik, tt.year)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if found != tt.found || wage.Amount != tt.amount {
+			t.Errorf("%s: wage %d (found %v), want %d (found %v)", tt.name, wage.Amount, found, tt.amount, tt.found)
+		}
+	}
+}
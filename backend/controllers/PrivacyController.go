@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"sibestie/config"
+	"sibestie/models"
+	crypto "sibestie/tools"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeleteAccountRequest confirms an account deletion with the user's password
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// auditFreeTextActions are the audit actions on a verification whose detail
+// quotes what a verifikator wrote about the applicant
+var auditFreeTextActions = []string{
+	"verifikasi.approve",
+	"verifikasi.reject",
+	"verifikasi.rank_override",
+	"document.review",
+	"document.annotate",
+	"document.annotation_delete",
+}
+
+// auditRedacted replaces the detail of those audit entries on erasure
+const auditRedacted = "[redacted]"
+
+// anonymizeVerifikasi deletes the applicant data and documents of a
+// verification record while keeping the decision fields for statistics
+func anonymizeVerifikasi(tx *gorm.DB, verifikasi *models.Verifikasi) error {
+	now := time.Now()
+
+	if err := deleteUserData(tx, verifikasi.UserDataID); err != nil {
+		return err
+	}
+	if err := blankLegacyColumns(tx, verifikasi.ID, legacyApplicantColumns); err != nil {
+		return err
+	}
+	verifikasi.UserDataID = 0
+	verifikasi.UserData = models.UserData{}
+	verifikasi.VerifikatorMessage = ""
+	// Override reasons are free text about the applicant like the message
+	if err := tx.Model(&models.RankOverride{}).Where("verifikasi_id = ?", verifikasi.ID).Update("reason", "").Error; err != nil {
+		return err
+	}
+	// and so are the notes and messages the audit log quotes
+	if err := tx.Model(&models.AuditLog{}).
+		Where("entity = ? AND entity_id = ? AND action IN ?", "verifikasi", verifikasi.ID, auditFreeTextActions).
+		Update("detail", auditRedacted).Error; err != nil {
+		return err
+	}
+
+	if err := clearDuplicateFlags(tx, verifikasi.ID); err != nil {
+		return err
+	}
+	verifikasi.DuplicateSuspected = false
+
+	verifikasi.UserID = 0
+	verifikasi.AnonymizedAt = &now
+
+	return purgeVerifikasiDocuments(tx, verifikasi)
+}
+
+// purgeVerifikasiDocuments removes the uploaded documents of a verification record
+func purgeVerifikasiDocuments(tx *gorm.DB, verifikasi *models.Verifikasi) error {
+	now := time.Now()
+	verifikasi.DocumentsPurgedAt = &now
+
+	if err := blankLegacyColumns(tx, verifikasi.ID, legacyDocumentColumns); err != nil {
+		return err
+	}
+
+	if err := tx.Omit(clause.Associations).Save(verifikasi).Error; err != nil {
+		return err
+	}
+	return detachVerifikasiDocuments(tx, verifikasi.ID)
+}
+
+// retainedUserDataIDs selects the profiles that verification records still
+// hold, for use in a NOT IN condition. A verification keeps the data it was
+// decided on until it is anonymized itself.
+func retainedUserDataIDs(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Model(&models.Verifikasi{}).
+		Select("user_data_id").
+		Where("anonymized_at IS NULL AND user_data_id IS NOT NULL AND user_data_id <> 0")
+}
+
+// purgeUserProfile hard-deletes the normalized profile rows and uploaded
+// files that belong to a user, except those a verification record that is
+// not anonymized still refers to
+func purgeUserProfile(tx *gorm.DB, userID uint) error {
+	var userData []models.UserData
+	if err := tx.Unscoped().Where("user_id = ? AND id NOT IN (?)", userID, retainedUserDataIDs(tx)).Find(&userData).Error; err != nil {
+		return err
+	}
+
+	for _, d := range userData {
+		if err := tx.Unscoped().Where("user_data_id = ?", d.ID).Delete(&models.SourceFile{}).Error; err != nil {
+			return err
+		}
+		if d.FamilyID != 0 {
+			var family models.Family
+			if err := tx.Unscoped().First(&family, d.FamilyID).Error; err == nil && family.SourceKKID != 0 {
+				if err := tx.Unscoped().Delete(&models.SourceFile{}, family.SourceKKID).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if err := deleteUserData(tx, d.ID); err != nil {
+			return err
+		}
+	}
+
+	// Documents uploaded through the document endpoint
+	attached := tx.Session(&gorm.Session{NewDB: true}).Model(&models.VerifikasiDocument{}).
+		Select("verifikasi_documents.source_file_id").
+		Joins("JOIN verifikasis ON verifikasis.id = verifikasi_documents.verifikasi_id").
+		Where("verifikasis.anonymized_at IS NULL")
+	var uploads []models.SourceFile
+	if err := tx.Unscoped().Where("user_id = ? AND id NOT IN (?)", userID, attached).Find(&uploads).Error; err != nil {
+		return err
+	}
+	for _, file := range uploads {
+		linkIDs := tx.Model(&models.VerifikasiDocument{}).Select("id").Where("source_file_id = ?", file.ID)
+		if err := deleteDocumentAnnotations(tx, linkIDs); err != nil {
+			return err
+		}
+		if err := tx.Where("source_file_id = ?", file.ID).Delete(&models.VerifikasiDocument{}).Error; err != nil {
+			return err
+		}
+		if err := deleteSourceFile(tx, file); err != nil {
+			return err
+		}
+	}
+
+	// Academic and social media rows not reached through a deleted profile,
+	// unless a retained profile refers to them
+	retained := func(column string) *gorm.DB {
+		return tx.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.UserData{}).
+			Select(column).
+			Where("id IN (?) AND "+column+" IS NOT NULL", retainedUserDataIDs(tx))
+	}
+	if err := tx.Unscoped().Where("user_id = ? AND id NOT IN (?)", userID, retained("academic_id")).Delete(&models.Academic{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("user_id = ? AND id NOT IN (?)", userID, retained("social_media_id")).Delete(&models.SocialMedia{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// POST /api/verifikasi/:id/withdraw
+func WithdrawVerifikasi(c *gin.Context) {
+	var verifikasi models.Verifikasi
+	result := config.DB.First(&verifikasi, c.Param("id"))
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Verification data not found"})
+		} else {
+			log.Printf("Error finding verification for withdrawal: %v", result.Error)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find verification data"})
+		}
+		return
+	}
+
+	if verifikasi.UserID != currentUserID(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only withdraw your own verification"})
+		return
+	}
+	if !models.CanTransition(verifikasi.Status, models.StatusWithdrawn) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only pending verifications can be withdrawn"})
+		return
+	}
+
+	now := time.Now()
+	verifikasi.Status = models.StatusWithdrawn
+	verifikasi.WithdrawnAt = &now
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&verifikasi).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, verifikasi.UserID, "verifikasi.withdraw", "verifikasi", verifikasi.ID, "")
+	})
+	if err != nil {
+		log.Printf("Error withdrawing verification: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw verification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification withdrawn successfully", "status": verifikasi.Status})
+}
+
+// DELETE /api/account
+// Only applicants erase their own account; staff accounts hold decisions
+// and assignments and are managed by an admin.
+func DeleteAccount(c *gin.Context) {
+	var input DeleteAccountRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password confirmation is required"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, currentUserID(c)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	decryptedPassword, err := crypto.Decrypt(user.Password)
+	if err != nil || decryptedPassword != input.Password {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return
+	}
+
+	var verifications []models.Verifikasi
+	if err := config.DB.Where("user_id = ?", user.ID).Find(&verifications).Error; err != nil {
+		log.Printf("Error finding verifications for erasure: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range verifications {
+			v := &verifications[i]
+			// A pending application cannot be decided once its data is gone
+			if models.CanTransition(v.Status, models.StatusWithdrawn) {
+				now := time.Now()
+				v.Status = models.StatusWithdrawn
+				v.WithdrawnAt = &now
+			}
+			if err := anonymizeVerifikasi(tx, v); err != nil {
+				return err
+			}
+		}
+		if err := purgeUserProfile(tx, user.ID); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Pendaftar{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&user).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, user.ID, "account.erase", "user", user.ID,
+			fmt.Sprintf("anonymized_verifications=%d", len(verifications)))
+	})
+	if err != nil {
+		log.Printf("Error erasing account %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account and personal data deleted"})
+}
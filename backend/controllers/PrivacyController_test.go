@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"sibestie/config"
+	"sibestie/models"
+	"sibestie/scanner"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestErasureLeavesNoFreeText(t *testing.T) {
+	setupScanTest(t, scanner.NewFake())
+	db := config.DB
+	SeedRejectionReasons()
+	const verifikatorID = 2
+
+	file := storeScanFixture(t, "a scanned KTP", 1)[0]
+	if err := db.Model(&file).Update("scan_status", models.ScanClean).Error; err != nil {
+		t.Fatalf("mark file clean: %v", err)
+	}
+	v := createApplication(t, db, 4, models.StatusPending)
+	if err := db.Create(&models.VerifikasiDocument{VerifikasiID: v.ID, SourceFileID: file.ID, DocType: "ktp"}).Error; err != nil {
+		t.Fatalf("attach document: %v", err)
+	}
+
+	const (
+		reviewNote     = "alamat di KTP berbeda dengan KK"
+		annotationNote = "tanda tangan ayah tidak cocok"
+		deletedNote    = "foto terpotong di sudut kanan"
+		message        = "pendaftar tinggal bersama paman"
+		overrideReason = "ayah baru saja di-PHK"
+	)
+	params := []gin.Param{{Key: "id", Value: strconv.Itoa(int(v.ID))}, {Key: "document_id", Value: strconv.Itoa(int(file.ID))}}
+	if w := serve(ReviewVerifikasiDocument, verifikatorID, "verifikator", http.MethodPut,
+		fmt.Sprintf(`{"status": %q, "note": %q}`, models.DocumentInvalid, reviewNote), params...); w.Code != http.StatusOK {
+		t.Fatalf("review: status %d: %s", w.Code, w.Body)
+	}
+	var annotationIDs []uint
+	for _, note := range []string{annotationNote, deletedNote} {
+		w := serve(CreateDocumentAnnotation, verifikatorID, "verifikator", http.MethodPost,
+			fmt.Sprintf(`{"width": 0.5, "height": 0.5, "note": %q}`, note), params...)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("annotate: status %d: %s", w.Code, w.Body)
+		}
+		var response struct{ Data models.DocumentAnnotation }
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		annotationIDs = append(annotationIDs, response.Data.ID)
+	}
+	deleteParams := append(params, gin.Param{Key: "annotation_id", Value: strconv.Itoa(int(annotationIDs[1]))})
+	if w := serve(DeleteDocumentAnnotation, verifikatorID, "verifikator", http.MethodDelete, "", deleteParams...); w.Code != http.StatusOK {
+		t.Fatalf("delete annotation: status %d: %s", w.Code, w.Body)
+	}
+	body := fmt.Sprintf(`{"message": %q, "reason_codes": ["KTP_UNREADABLE"], "rank_override": 9, "override_reason": %q}`,
+		message, overrideReason)
+	if w := serve(RejectVerifikasi, verifikatorID, "verifikator", http.MethodPost, body, params[0]); w.Code != http.StatusOK {
+		t.Fatalf("reject: status %d: %s", w.Code, w.Body)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var verifikasi models.Verifikasi
+		if err := withApplicant(tx).First(&verifikasi, v.ID).Error; err != nil {
+			return err
+		}
+		return anonymizeVerifikasi(tx, &verifikasi)
+	})
+	if err != nil {
+		t.Fatalf("anonymize: %v", err)
+	}
+
+	for _, text := range []string{reviewNote, annotationNote, deletedNote, message, overrideReason} {
+		pattern := "%" + text + "%"
+		for _, where := range []struct {
+			model  interface{}
+			column string
+		}{
+			{&models.AuditLog{}, "detail"},
+			{&models.RankOverride{}, "reason"},
+			{&models.DocumentAnnotation{}, "note"},
+			{&models.VerifikasiDocument{}, "review_note"},
+			{&models.Verifikasi{}, "verifikator_message"},
+		} {
+			var count int64
+			if err := db.Unscoped().Model(where.model).Where(where.column+" LIKE ?", pattern).Count(&count).Error; err != nil {
+				t.Fatalf("query %s: %v", where.column, err)
+			}
+			if count != 0 {
+				t.Errorf("%q is still in %d %T %s after erasure", text, count, where.model, where.column)
+			}
+		}
+	}
+
+	// The audit trail itself stays
+	want := []string{"document.review", "document.annotate", "document.annotate", "document.annotation_delete",
+		"verifikasi.rank_override", "verifikasi.reject"}
+	if got := auditActions(t, db, v.ID); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("audit actions %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"sibestie/config"
+	"sibestie/models"
+	"sibestie/tools/scoring"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RankOverrideRequest sets the rank of an application by hand; a rank of 0
+// lifts the override and returns the application to its merit rank
+type RankOverrideRequest struct {
+	Rank   int    `json:"rank"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+var (
+	errOverrideRank   = errors.New("rank override must be between 1 and 10")
+	errOverrideReason = errors.New("a reason is required to override the rank")
+)
+
+// checkRankOverride validates an override; rank 0 lifts an earlier one
+func checkRankOverride(rank int, reason string) error {
+	if rank < 0 || rank > scoring.MaxRank {
+		return errOverrideRank
+	}
+	if strings.TrimSpace(reason) == "" {
+		return errOverrideReason
+	}
+	return nil
+}
+
+// overrideRank records a verifikator's rank for an application next to the
+// computed merit rank, which is left as it is
+func overrideRank(tx *gorm.DB, verifikasi *models.Verifikasi, rank int, reason string, verifikatorID uint) error {
+	override := models.RankOverride{
+		VerifikasiID:  verifikasi.ID,
+		ComputedRank:  verifikasi.MeritRank,
+		Rank:          rank,
+		Reason:        strings.TrimSpace(reason),
+		VerifikatorID: verifikatorID,
+	}
+	if err := tx.Create(&override).Error; err != nil {
+		return err
+	}
+	verifikasi.OverrideRank = rank
+	if err := tx.Model(verifikasi).Update("override_rank", rank).Error; err != nil {
+		return err
+	}
+	return recordAudit(tx, verifikatorID, "verifikasi.rank_override", "verifikasi", verifikasi.ID,
+		fmt.Sprintf("rank=%d computed=%d reason=%q", rank, override.ComputedRank, override.Reason))
+}
+
+// rankOverrides lists the rank overrides of a verification, latest first
+func rankOverrides(db *gorm.DB, verifikasiID uint) ([]models.RankOverride, error) {
+	overrides := []models.RankOverride{}
+	err := db.Where("verifikasi_id = ?", verifikasiID).Order("created_at desc, id desc").Find(&overrides).Error
+	return overrides, err
+}
+
+// PUT /api/verifikasi/:id/rank-override
+func SetRankOverride(c *gin.Context) {
+	var input RankOverrideRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rank override: " + err.Error()})
+		return
+	}
+	if err := checkRankOverride(input.Rank, input.Reason); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var verifikasi models.Verifikasi
+	if err := withApplicant(config.DB).First(&verifikasi, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Verification data not found"})
+		} else {
+			log.Printf("Error finding verification for rank override: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find verification data"})
+		}
+		return
+	}
+	if verifikasi.AnonymizedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Verification data has been anonymized"})
+		return
+	}
+	if verifikasi.OverrideRank == input.Rank {
+		c.JSON(http.StatusConflict, gin.H{"error": "Rank is already set to this value"})
+		return
+	}
+
+	verifikatorID := currentUserID(c)
+	if verifikasi.AssignedVerifikatorID != 0 && verifikasi.AssignedVerifikatorID != verifikatorID && currentUserRole(c) != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Verification is assigned to another verifikator"})
+		return
+	}
+	if !checkConflict(c, verifikatorID, verifikasi) {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return overrideRank(tx, &verifikasi, input.Rank, input.Reason, verifikatorID)
+	})
+	if err != nil {
+		log.Printf("Error saving rank override: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save rank override"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Rank override saved",
+		"data_completeness": verifikasi.DataCompleteness,
+		"merit_rank":        verifikasi.MeritRank,
+		"override_rank":     verifikasi.OverrideRank,
+		"rank":              verifikasi.Rank(),
+	})
+}
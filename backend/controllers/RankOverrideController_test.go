@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"sibestie/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRankOverrideRefusesDeclaredConflicts(t *testing.T) {
+	db := setupTestDB(t)
+	applicant := createApplicant(t, db, 4, models.UserData{
+		FullName: "Siti Nurhaliza Wijaya",
+		Academic: models.Academic{SchoolName: "SMA Negeri 3 Bandung"},
+	}, "")
+	params := gin.Param{Key: "id", Value: strconv.Itoa(int(applicant.ID))}
+	body := `{"rank": 9, "reason": "prestasi olimpiade tidak tercatat di nilai"}`
+
+	// Declarations about the school and the family name need the applicant's data
+	for verifikatorID, declaration := range map[uint]models.ConflictDeclaration{
+		2: {Kind: models.ConflictSchool, Value: "sma negeri 3  bandung"},
+		3: {Kind: models.ConflictFamilyName, Value: "Wijaya"},
+	} {
+		declaration.VerifikatorID = verifikatorID
+		if err := db.Create(&declaration).Error; err != nil {
+			t.Fatalf("declare conflict: %v", err)
+		}
+		w := serve(SetRankOverride, verifikatorID, "verifikator", http.MethodPut, body, params)
+		if w.Code != http.StatusConflict {
+			t.Errorf("%s conflict: status %d, want 409: %s", declaration.Kind, w.Code, w.Body)
+		}
+	}
+
+	var reloaded models.Verifikasi
+	db.First(&reloaded, applicant.ID)
+	var overrides int64
+	db.Model(&models.RankOverride{}).Count(&overrides)
+	if reloaded.OverrideRank != 0 || overrides != 0 {
+		t.Errorf("override rank %d with %d overrides after refusals", reloaded.OverrideRank, overrides)
+	}
+	if got, want := auditActions(t, db, applicant.ID), []string{"verifikasi.conflict_refused", "verifikasi.conflict_refused"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("audit actions %v, want %v", got, want)
+	}
+
+	// A verifikator without a conflict may override the rank
+	if w := serve(SetRankOverride, 5, "verifikator", http.MethodPut, body, params); w.Code != http.StatusOK {
+		t.Fatalf("override without conflict: status %d: %s", w.Code, w.Body)
+	}
+	db.First(&reloaded, applicant.ID)
+	if reloaded.OverrideRank != 9 {
+		t.Errorf("override rank %d, want 9", reloaded.OverrideRank)
+	}
+}
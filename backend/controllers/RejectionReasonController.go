@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"sibestie/config"
+	"sibestie/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RejectionReasonInput represents the admin payload for a rejection reason
+type RejectionReasonInput struct {
+	Code        string `json:"code"`
+	Label       string `json:"label" binding:"required"`
+	Description string `json:"description"`
+	Active      *bool  `json:"active"`
+}
+
+// RejectionReasonCount is the number of rejections citing a reason
+type RejectionReasonCount struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// defaultRejectionReasons seeds the catalog on first start
+var defaultRejectionReasons = []models.RejectionReason{
+	{Code: "KTP_UNREADABLE", Label: "Foto KTP tidak terbaca"},
+	{Code: "KK_UNREADABLE", Label: "Foto KK tidak terbaca"},
+	{Code: "INCOME_DOC_MISSING", Label: "Dokumen penghasilan orang tua tidak ada"},
+	{Code: "NIK_MISMATCH", Label: "NIK tidak sesuai dengan KTP/KK"},
+	{Code: "ACADEMIC_DOC_MISSING", Label: "Ijazah atau SKL tidak dilampirkan"},
+	{Code: "DATA_INCONSISTENT", Label: "Data tidak konsisten antar dokumen"},
+}
+
+// SeedRejectionReasons fills the rejection reason catalog when it is empty
+func SeedRejectionReasons() {
+	var count int64
+	if err := config.DB.Model(&models.RejectionReason{}).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	for _, reason := range defaultRejectionReasons {
+		reason.Active = true
+		if err := config.DB.Create(&reason).Error; err != nil {
+			log.Printf("Error seeding rejection reason %s: %v", reason.Code, err)
+		}
+	}
+}
+
+// normalizeReasonCode uppercases a reason code and replaces spaces with underscores
+func normalizeReasonCode(code string) string {
+	return strings.ReplaceAll(strings.ToUpper(strings.TrimSpace(code)), " ", "_")
+}
+
+// resolveRejectionReasons looks up active catalog entries for the given codes.
+// Unknown or inactive codes are returned separately so the caller can report them.
+func resolveRejectionReasons(db *gorm.DB, codes []string) ([]models.RejectionReason, []string, error) {
+	if len(codes) == 0 {
+		return nil, nil, nil
+	}
+
+	normalized := make([]string, 0, len(codes))
+	seen := map[string]bool{}
+	for _, code := range codes {
+		code = normalizeReasonCode(code)
+		if code != "" && !seen[code] {
+			seen[code] = true
+			normalized = append(normalized, code)
+		}
+	}
+
+	var reasons []models.RejectionReason
+	if err := db.Where("code IN ? AND active = ?", normalized, true).Find(&reasons).Error; err != nil {
+		return nil, nil, err
+	}
+
+	found := map[string]bool{}
+	for _, r := range reasons {
+		found[r.Code] = true
+	}
+	var unknown []string
+	for _, code := range normalized {
+		if !found[code] {
+			unknown = append(unknown, code)
+		}
+	}
+	return reasons, unknown, nil
+}
+
+// saveRejectionReasons replaces the reasons recorded for a verification
+func saveRejectionReasons(tx *gorm.DB, verifikasiID uint, reasons []models.RejectionReason) error {
+	if err := tx.Where("verifikasi_id = ?", verifikasiID).Delete(&models.VerifikasiRejectionReason{}).Error; err != nil {
+		return err
+	}
+	for _, r := range reasons {
+		link := models.VerifikasiRejectionReason{
+			VerifikasiID:      verifikasiID,
+			RejectionReasonID: r.ID,
+			Code:              r.Code,
+		}
+		if err := tx.Create(&link).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rejectionReasonCounts counts how often each catalog reason has been cited
+func rejectionReasonCounts(db *gorm.DB) ([]RejectionReasonCount, error) {
+	var counts []RejectionReasonCount
+	err := db.Model(&models.VerifikasiRejectionReason{}).
+		Select("verifikasi_rejection_reasons.code AS code, rejection_reasons.label AS label, COUNT(*) AS count").
+		Joins("LEFT JOIN rejection_reasons ON rejection_reasons.id = verifikasi_rejection_reasons.rejection_reason_id").
+		Group("verifikasi_rejection_reasons.code, rejection_reasons.label").
+		Order("count desc").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// GET /api/rejection-reasons
+func ListRejectionReasons(c *gin.Context) {
+	query := config.DB.Order("code")
+	if c.Query("include_inactive") != "true" {
+		query = query.Where("active = ?", true)
+	}
+
+	var reasons []models.RejectionReason
+	if err := query.Find(&reasons).Error; err != nil {
+		log.Printf("Error querying rejection reasons: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query rejection reasons"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reasons)
+}
+
+// POST /api/rejection-reasons
+func CreateRejectionReason(c *gin.Context) {
+	var input RejectionReasonInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rejection reason data: " + err.Error()})
+		return
+	}
+
+	code := normalizeReasonCode(input.Code)
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason code is required"})
+		return
+	}
+
+	var existing models.RejectionReason
+	if err := config.DB.Where("code = ?", code).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Reason code already exists"})
+		return
+	}
+
+	reason := models.RejectionReason{
+		Code:        code,
+		Label:       input.Label,
+		Description: input.Description,
+		Active:      input.Active == nil || *input.Active,
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&reason).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, currentUserID(c), "rejection_reason.create", "rejection_reason", reason.ID, reason.Code)
+	})
+	if err != nil {
+		log.Printf("Error creating rejection reason: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rejection reason"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rejection reason created", "data": reason})
+}
+
+// PUT /api/rejection-reasons/:id
+func UpdateRejectionReason(c *gin.Context) {
+	var reason models.RejectionReason
+	if err := config.DB.First(&reason, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rejection reason not found"})
+		} else {
+			log.Printf("Error finding rejection reason: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find rejection reason"})
+		}
+		return
+	}
+
+	var input RejectionReasonInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rejection reason data: " + err.Error()})
+		return
+	}
+
+	// The code is the stable key used in reports, so it cannot be changed
+	reason.Label = input.Label
+	reason.Description = input.Description
+	if input.Active != nil {
+		reason.Active = *input.Active
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&reason).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, currentUserID(c), "rejection_reason.update", "rejection_reason", reason.ID, reason.Code)
+	})
+	if err != nil {
+		log.Printf("Error updating rejection reason: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rejection reason"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rejection reason updated", "data": reason})
+}
+
+// GET /api/verifikasi/stats/rejection-reasons
+func GetRejectionReasonStats(c *gin.Context) {
+	counts, err := rejectionReasonCounts(config.DB)
+	if err != nil {
+		log.Printf("Error counting rejection reasons: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count rejection reasons"})
+		return
+	}
+
+	c.JSON(http.StatusOK, counts)
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"sibestie/config"
+	"sibestie/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Retention categories
+const (
+	RetentionRejectedDocuments = "rejected_documents"
+	RetentionRejectedRecords   = "rejected_records"
+	RetentionWithdrawnRecords  = "withdrawn_records"
+	RetentionApprovedRecords   = "approved_records"
+	RetentionInactiveProfiles  = "inactive_profiles"
+)
+
+// RetentionCategoryReport lists the records a retention category purges
+type RetentionCategoryReport struct {
+	Category      string     `json:"category"`
+	Entity        string     `json:"entity"`
+	RetentionDays int        `json:"retention_days"`
+	Cutoff        *time.Time `json:"cutoff"`
+	Count         int        `json:"count"`
+	IDs           []uint     `json:"ids"`
+}
+
+// RetentionReport is the result of a retention run
+type RetentionReport struct {
+	DryRun     bool                      `json:"dry_run"`
+	RanAt      time.Time                 `json:"ran_at"`
+	Categories []RetentionCategoryReport `json:"categories"`
+}
+
+// retentionRule describes how one category finds and purges its records
+type retentionRule struct {
+	category string
+	entity   string
+	period   time.Duration
+	find     func(db *gorm.DB, cutoff time.Time) ([]uint, error)
+	purge    func(tx *gorm.DB, id uint) error
+}
+
+// retentionRules builds the rules for a policy
+func retentionRules(policy config.RetentionPolicy) []retentionRule {
+	verifikasiByStatus := func(status, column string, extra string) func(db *gorm.DB, cutoff time.Time) ([]uint, error) {
+		return func(db *gorm.DB, cutoff time.Time) ([]uint, error) {
+			var ids []uint
+			err := db.Model(&models.Verifikasi{}).
+				Where("status = ? AND "+column+" < ? AND anonymized_at IS NULL"+extra, status, cutoff).
+				Pluck("id", &ids).Error
+			return ids, err
+		}
+	}
+	withVerifikasi := func(fn func(tx *gorm.DB, v *models.Verifikasi) error) func(tx *gorm.DB, id uint) error {
+		return func(tx *gorm.DB, id uint) error {
+			var v models.Verifikasi
+			if err := tx.First(&v, id).Error; err != nil {
+				return err
+			}
+			return fn(tx, &v)
+		}
+	}
+
+	return []retentionRule{
+		{
+			category: RetentionRejectedDocuments,
+			entity:   "verifikasi",
+			period:   policy.RejectedDocuments,
+			find:     verifikasiByStatus(models.StatusRejected, "verified_at", " AND documents_purged_at IS NULL"),
+			purge:    withVerifikasi(purgeVerifikasiDocuments),
+		},
+		{
+			category: RetentionRejectedRecords,
+			entity:   "verifikasi",
+			period:   policy.RejectedRecords,
+			find:     verifikasiByStatus(models.StatusRejected, "verified_at", ""),
+			purge:    withVerifikasi(anonymizeVerifikasi),
+		},
+		{
+			category: RetentionWithdrawnRecords,
+			entity:   "verifikasi",
+			period:   policy.WithdrawnRecords,
+			find:     verifikasiByStatus(models.StatusWithdrawn, "withdrawn_at", ""),
+			purge:    withVerifikasi(anonymizeVerifikasi),
+		},
+		{
+			category: RetentionApprovedRecords,
+			entity:   "verifikasi",
+			period:   policy.ApprovedRecords,
+			find:     verifikasiByStatus(models.StatusApproved, "verified_at", ""),
+			purge:    withVerifikasi(anonymizeVerifikasi),
+		},
+		{
+			category: RetentionInactiveProfiles,
+			entity:   "user",
+			period:   policy.InactiveProfiles,
+			find: func(db *gorm.DB, cutoff time.Time) ([]uint, error) {
+				// Profiles of applicants with a pending application are still
+				// in use, and the profile a verification was decided on is
+				// kept for as long as that verification's own retention
+				var ids []uint
+				err := db.Model(&models.UserData{}).
+					Where("updated_at < ?", cutoff).
+					Where("id NOT IN (?)", retainedUserDataIDs(db)).
+					Where("user_id NOT IN (?)", db.Model(&models.Verifikasi{}).Select("user_id").Where("status = ?", models.StatusPending)).
+					Distinct().Pluck("user_id", &ids).Error
+				return ids, err
+			},
+			purge: purgeUserProfile,
+		},
+	}
+}
+
+// runRetention finds the records each category would purge and, unless
+// dryRun is set, purges them and writes an audit entry for every record
+func runRetention(policy config.RetentionPolicy, dryRun bool, actorID uint) (RetentionReport, error) {
+	now := time.Now()
+	report := RetentionReport{DryRun: dryRun, RanAt: now}
+
+	for _, rule := range retentionRules(policy) {
+		category := RetentionCategoryReport{
+			Category:      rule.category,
+			Entity:        rule.entity,
+			RetentionDays: int(rule.period / (24 * time.Hour)),
+			IDs:           []uint{},
+		}
+		if rule.period == 0 {
+			report.Categories = append(report.Categories, category)
+			continue
+		}
+
+		cutoff := now.Add(-rule.period)
+		category.Cutoff = &cutoff
+
+		ids, err := rule.find(config.DB, cutoff)
+		if err != nil {
+			return report, fmt.Errorf("%s: %w", rule.category, err)
+		}
+
+		for _, id := range ids {
+			if !dryRun {
+				err := config.DB.Transaction(func(tx *gorm.DB) error {
+					if err := rule.purge(tx, id); err != nil {
+						return err
+					}
+					return recordAudit(tx, actorID, "retention.purge", rule.entity, id,
+						fmt.Sprintf("category=%s retention_days=%d", rule.category, category.RetentionDays))
+				})
+				if err != nil {
+					log.Printf("Error purging %s %d (%s): %v", rule.entity, id, rule.category, err)
+					continue
+				}
+			}
+			category.IDs = append(category.IDs, id)
+		}
+		category.Count = len(category.IDs)
+		report.Categories = append(report.Categories, category)
+	}
+
+	return report, nil
+}
+
+// StartRetentionScheduler runs the retention job in the background at the
+// interval configured in the environment. At startup it only logs what the
+// job would purge; the first purge runs one interval later, so an admin can
+// check the report after a deploy or a policy change first.
+func StartRetentionScheduler() {
+	policy := config.LoadRetentionPolicy()
+	if policy.Interval == 0 {
+		log.Println("[RETENTION] Scheduled purging disabled")
+		return
+	}
+
+	go func() {
+		if report, err := runRetention(policy, true, 0); err != nil {
+			log.Printf("[RETENTION] Startup report failed: %v", err)
+		} else {
+			logRetention(report, "Would purge")
+			log.Printf("[RETENTION] First purge in %s", policy.Interval)
+		}
+
+		ticker := time.NewTicker(policy.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			report, err := runRetention(policy, false, 0)
+			if err != nil {
+				log.Printf("[RETENTION] Run failed: %v", err)
+				continue
+			}
+			logRetention(report, "Purged")
+		}
+	}()
+}
+
+// logRetention logs the non-empty categories of a retention report
+func logRetention(report RetentionReport, verb string) {
+	for _, category := range report.Categories {
+		if category.Count > 0 {
+			log.Printf("[RETENTION] %s %d %s record(s) for %s", verb, category.Count, category.Entity, category.Category)
+		}
+	}
+}
+
+// GET /api/admin/retention/report
+func GetRetentionReport(c *gin.Context) {
+	report, err := runRetention(config.LoadRetentionPolicy(), true, currentUserID(c))
+	if err != nil {
+		log.Printf("Error building retention report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build retention report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// POST /api/admin/retention/run
+func RunRetention(c *gin.Context) {
+	report, err := runRetention(config.LoadRetentionPolicy(), false, currentUserID(c))
+	if err != nil {
+		log.Printf("Error running retention: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run retention", "report": report})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
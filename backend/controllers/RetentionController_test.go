@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"sibestie/config"
+	"sibestie/models"
+
+	"gorm.io/gorm"
+)
+
+// createProfile creates a profile with an academic record that was last
+// updated long ago
+func createProfile(t *testing.T, db *gorm.DB, userID uint) models.UserData {
+	t.Helper()
+	academic := models.Academic{UserID: userID, SchoolName: "SMA 1"}
+	if err := db.Create(&academic).Error; err != nil {
+		t.Fatalf("create academic: %v", err)
+	}
+	data := models.UserData{UserID: userID, FullName: "Applicant", AcademicID: academic.ID}
+	if err := db.Create(&data).Error; err != nil {
+		t.Fatalf("create profile: %v", err)
+	}
+	old := time.Now().AddDate(-5, 0, 0)
+	if err := db.Model(&data).UpdateColumn("updated_at", old).Error; err != nil {
+		t.Fatalf("age profile: %v", err)
+	}
+	return data
+}
+
+func createDecided(t *testing.T, db *gorm.DB, userID uint, data models.UserData, status string, anonymized bool) models.Verifikasi {
+	t.Helper()
+	now := time.Now()
+	v := models.Verifikasi{UserID: userID, UserDataID: data.ID, Status: status, VerifiedAt: &now}
+	if anonymized {
+		v.UserID, v.UserDataID, v.AnonymizedAt = 0, 0, &now
+	}
+	if err := db.Omit("UserData").Create(&v).Error; err != nil {
+		t.Fatalf("create verification: %v", err)
+	}
+	return v
+}
+
+func exists(t *testing.T, db *gorm.DB, model interface{}, id uint) bool {
+	t.Helper()
+	var count int64
+	if err := db.Unscoped().Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	return count > 0
+}
+
+func TestInactiveProfilesKeepDecidedSnapshots(t *testing.T) {
+	db := setupTestDB(t)
+
+	// Applicant 4 has an approved application and a newer profile nobody
+	// refers to
+	decided := createProfile(t, db, 4)
+	approved := createDecided(t, db, 4, decided, models.StatusApproved, false)
+	stale := createProfile(t, db, 4)
+	attached := models.SourceFile{UserID: 4, SourceType: "ktp"}
+	loose := models.SourceFile{UserID: 4, SourceType: "kk"}
+	db.Create(&attached)
+	db.Create(&loose)
+	db.Create(&models.VerifikasiDocument{VerifikasiID: approved.ID, SourceFileID: attached.ID, DocType: "ktp"})
+
+	// Applicant 5 only has the profile of a rejected application
+	rejected := createProfile(t, db, 5)
+	createDecided(t, db, 5, rejected, models.StatusRejected, false)
+
+	// Applicant 6's application was anonymized, so its profile is unused
+	orphan := createProfile(t, db, 6)
+	createDecided(t, db, 6, orphan, models.StatusApproved, true)
+
+	policy := config.RetentionPolicy{InactiveProfiles: 365 * 24 * time.Hour}
+	report, err := runRetention(policy, false, 0)
+	if err != nil {
+		t.Fatalf("runRetention: %v", err)
+	}
+	var purged []uint
+	for _, category := range report.Categories {
+		if category.Category == RetentionInactiveProfiles {
+			purged = category.IDs
+		}
+	}
+	if len(purged) != 2 || purged[0] != 4 || purged[1] != 6 {
+		t.Errorf("purged users %v, want [4 6]", purged)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		model interface{}
+		id    uint
+		want  bool
+	}{
+		{"approved snapshot", &models.UserData{}, decided.ID, true},
+		{"approved snapshot's academic record", &models.Academic{}, decided.AcademicID, true},
+		{"document attached to the approved application", &models.SourceFile{}, attached.ID, true},
+		{"rejected snapshot", &models.UserData{}, rejected.ID, true},
+		{"unreferenced profile", &models.UserData{}, stale.ID, false},
+		{"unreferenced academic record", &models.Academic{}, stale.AcademicID, false},
+		{"unattached upload", &models.SourceFile{}, loose.ID, false},
+		{"profile of an anonymized application", &models.UserData{}, orphan.ID, false},
+	} {
+		if got := exists(t, db, tt.model, tt.id); got != tt.want {
+			t.Errorf("%s: exists = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// A second run finds nothing left to purge
+	report, err = runRetention(policy, true, 0)
+	if err != nil {
+		t.Fatalf("runRetention: %v", err)
+	}
+	for _, category := range report.Categories {
+		if category.Category == RetentionInactiveProfiles && category.Count != 0 {
+			t.Errorf("second run would purge users %v again", category.IDs)
+		}
+	}
+}
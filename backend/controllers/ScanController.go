@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"sibestie/config"
+	"sibestie/models"
+	"sibestie/scanner"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxScanAttempts is how often a scan is retried before the file is marked
+// ScanFailed and left for an admin to rescan
+const maxScanAttempts = 5
+
+// quarantinePrefix is prepended to the storage key of infected content
+const quarantinePrefix = "quarantine/"
+
+// scanQueue hands freshly uploaded files to the scan worker. Files that do
+// not fit are picked up by the worker's periodic sweep.
+var scanQueue = make(chan uint, 256)
+
+var (
+	errScanIncomplete    = errors.New("documents have not finished malware scanning")
+	errDocumentsInfected = errors.New("verification has documents that failed the malware scan")
+)
+
+// queueScan asks the scan worker to scan a file soon
+func queueScan(fileID uint) {
+	select {
+	case scanQueue <- fileID:
+	default:
+	}
+}
+
+// scanInterval returns how often the worker sweeps for pending scans
+func scanInterval() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("SCAN_INTERVAL_SECONDS"))
+	if err != nil || seconds <= 0 {
+		seconds = 30
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// StartScanWorker scans uploaded documents in the background
+func StartScanWorker() {
+	go func() {
+		ticker := time.NewTicker(scanInterval())
+		defer ticker.Stop()
+
+		sweepPendingScans()
+		for {
+			select {
+			case id := <-scanQueue:
+				if err := scanDocument(id); err != nil {
+					log.Printf("[SCAN] Document %d: %v", id, err)
+				}
+			case <-ticker.C:
+				sweepPendingScans()
+			}
+		}
+	}()
+}
+
+// sweepPendingScans scans every stored file still waiting for a verdict
+func sweepPendingScans() {
+	var ids []uint
+	err := config.DB.Model(&models.SourceFile{}).
+		Where("scan_status = ? AND storage_key <> ''", models.ScanPending).
+		Pluck("id", &ids).Error
+	if err != nil {
+		log.Printf("[SCAN] Finding pending scans failed: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if err := scanDocument(id); err != nil {
+			log.Printf("[SCAN] Document %d: %v", id, err)
+		}
+	}
+}
+
+// scanDocument scans the stored content of a file and records the verdict on
+// every file sharing that content
+func scanDocument(fileID uint) error {
+	var file models.SourceFile
+	if err := config.DB.First(&file, fileID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if file.ScanStatus != models.ScanPending || file.StorageKey == "" {
+		return nil
+	}
+	shared := func() *gorm.DB {
+		return config.DB.Model(&models.SourceFile{}).Where("storage_key = ?", file.StorageKey)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	result, err := func() (scanner.Result, error) {
+		content, err := config.Storage.Get(ctx, file.StorageKey)
+		if err != nil {
+			return scanner.Result{}, err
+		}
+		defer content.Close()
+		return config.Scanner.Scan(ctx, content)
+	}()
+	if err != nil {
+		status := models.ScanPending
+		if file.ScanAttempts+1 >= maxScanAttempts {
+			status = models.ScanFailed
+		}
+		if uerr := shared().Updates(map[string]interface{}{
+			"scan_status":   status,
+			"scan_attempts": gorm.Expr("scan_attempts + 1"),
+		}).Error; uerr != nil {
+			return uerr
+		}
+		return fmt.Errorf("scan failed (attempt %d, now %s): %w", file.ScanAttempts+1, status, err)
+	}
+
+	now := time.Now()
+	if !result.Infected {
+		return shared().Updates(map[string]interface{}{
+			"scan_status":   models.ScanClean,
+			"scan_attempts": gorm.Expr("scan_attempts + 1"),
+			"scanned_at":    &now,
+		}).Error
+	}
+
+	log.Printf("[SCAN] Document %d is infected with %s, quarantining", file.ID, result.Signature)
+	return quarantineDocument(ctx, file, result.Signature, now)
+}
+
+// quarantineDocument moves infected content out of the regular document
+// keys, drops its thumbnail and marks every file sharing it as infected
+func quarantineDocument(ctx context.Context, file models.SourceFile, signature string, scannedAt time.Time) error {
+	quarantineKey := quarantinePrefix + file.StorageKey
+
+	content, err := config.Storage.Get(ctx, file.StorageKey)
+	if err != nil {
+		return err
+	}
+	err = config.Storage.Put(ctx, quarantineKey, content, -1, "application/octet-stream")
+	content.Close()
+	if err != nil {
+		return err
+	}
+
+	var files []models.SourceFile
+	if err := config.DB.Where("storage_key = ?", file.StorageKey).Find(&files).Error; err != nil {
+		return err
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.SourceFile{}).Where("storage_key = ?", file.StorageKey).Updates(map[string]interface{}{
+			"storage_key":    quarantineKey,
+			"thumbnail_key":  "",
+			"scan_status":    models.ScanInfected,
+			"scan_signature": signature,
+			"scan_attempts":  gorm.Expr("scan_attempts + 1"),
+			"scanned_at":     &scannedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if err := recordAudit(tx, 0, "document.quarantine", "document", f.ID,
+				fmt.Sprintf("signature=%q user_id=%d", signature, f.UserID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		config.Storage.Delete(ctx, quarantineKey)
+		return err
+	}
+
+	if file.ThumbnailKey != "" {
+		config.Storage.Delete(ctx, file.ThumbnailKey)
+	}
+	return config.Storage.Delete(ctx, file.StorageKey)
+}
+
+// documentScanStatus summarizes the scans of the documents attached to a
+// verification: infected if any is infected, pending while any has no
+// verdict yet, clean otherwise
+func documentScanStatus(db *gorm.DB, verifikasiID uint) (string, error) {
+	var statuses []string
+	err := db.Model(&models.SourceFile{}).
+		Joins("JOIN verifikasi_documents ON verifikasi_documents.source_file_id = source_files.id").
+		Where("verifikasi_documents.verifikasi_id = ?", verifikasiID).
+		Distinct().Pluck("source_files.scan_status", &statuses).Error
+	if err != nil {
+		return "", err
+	}
+
+	summary := models.ScanClean
+	for _, status := range statuses {
+		switch status {
+		case models.ScanInfected:
+			return models.ScanInfected, nil
+		case models.ScanPending, models.ScanFailed:
+			summary = models.ScanPending
+		}
+	}
+	return summary, nil
+}
+
+// unscannedVerifikasiIDs selects verifications that still have documents
+// waiting for a scan verdict, for use in a NOT IN condition
+func unscannedVerifikasiIDs(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.VerifikasiDocument{}).
+		Select("verifikasi_documents.verifikasi_id").
+		Joins("JOIN source_files ON source_files.id = verifikasi_documents.source_file_id").
+		Where("source_files.scan_status IN ?", []string{models.ScanPending, models.ScanFailed})
+}
+
+// POST /api/admin/documents/:id/rescan
+func RescanDocument(c *gin.Context) {
+	file, ok := findDocument(c)
+	if !ok {
+		return
+	}
+	if file.ScanStatus == models.ScanInfected {
+		c.JSON(http.StatusConflict, gin.H{"error": "Infected documents stay in quarantine"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.SourceFile{}).Where("storage_key = ?", file.StorageKey).Updates(map[string]interface{}{
+			"scan_status":    models.ScanPending,
+			"scan_signature": "",
+			"scan_attempts":  0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return recordAudit(tx, currentUserID(c), "document.rescan", "document", file.ID, "")
+	})
+	if err != nil {
+		log.Printf("Error queueing rescan: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue rescan"})
+		return
+	}
+	queueScan(file.ID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document queued for scanning", "scan_status": models.ScanPending})
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sibestie/config"
+	"sibestie/models"
+	"sibestie/scanner"
+	"sibestie/storage"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// setupScanTest points the config globals at a test database, temporary
+// local storage and the given scanner
+func setupScanTest(t *testing.T, s scanner.Scanner) {
+	t.Helper()
+	setupTestDB(t)
+	store, err := storage.NewLocal(filepath.Join(t.TempDir(), "uploads"))
+	if err != nil {
+		t.Fatalf("storage: %v", err)
+	}
+
+	prevStorage, prevScanner := config.Storage, config.Scanner
+	config.Storage, config.Scanner = store, s
+	t.Cleanup(func() {
+		config.Storage, config.Scanner = prevStorage, prevScanner
+	})
+}
+
+// storeScanFixture stores content and creates count files sharing it
+func storeScanFixture(t *testing.T, content string, count int) []models.SourceFile {
+	t.Helper()
+	key := "documents/4/ktp.jpg"
+	if err := config.Storage.Put(context.Background(), key, strings.NewReader(content), int64(len(content)), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := config.Storage.Put(context.Background(), "thumbnails/4/ktp.jpg", strings.NewReader("thumb"), 5, "image/jpeg"); err != nil {
+		t.Fatalf("Put thumbnail: %v", err)
+	}
+
+	files := make([]models.SourceFile, count)
+	for i := range files {
+		files[i] = models.SourceFile{
+			UserID:       uint(4 + i),
+			StorageKey:   key,
+			ThumbnailKey: "thumbnails/4/ktp.jpg",
+			ScanStatus:   models.ScanPending,
+		}
+		if err := config.DB.Create(&files[i]).Error; err != nil {
+			t.Fatalf("create file: %v", err)
+		}
+	}
+	return files
+}
+
+func reloadFile(t *testing.T, id uint) models.SourceFile {
+	t.Helper()
+	var file models.SourceFile
+	if err := config.DB.First(&file, id).Error; err != nil {
+		t.Fatalf("reload file %d: %v", id, err)
+	}
+	return file
+}
+
+func TestScanDocumentClean(t *testing.T) {
+	setupScanTest(t, scanner.NewFake())
+	files := storeScanFixture(t, "a harmless scan", 2)
+
+	if err := scanDocument(files[0].ID); err != nil {
+		t.Fatalf("scanDocument: %v", err)
+	}
+	for _, f := range files {
+		got := reloadFile(t, f.ID)
+		if got.ScanStatus != models.ScanClean || got.ScannedAt == nil || got.StorageKey != f.StorageKey {
+			t.Errorf("file %d: status=%s scanned_at=%v key=%s, want clean at the original key", f.ID, got.ScanStatus, got.ScannedAt, got.StorageKey)
+		}
+	}
+}
+
+func TestScanDocumentInfectedIsQuarantined(t *testing.T) {
+	setupScanTest(t, scanner.NewFake())
+	files := storeScanFixture(t, "infected "+eicar, 2)
+	ctx := context.Background()
+
+	if err := scanDocument(files[0].ID); err != nil {
+		t.Fatalf("scanDocument: %v", err)
+	}
+
+	quarantineKey := quarantinePrefix + files[0].StorageKey
+	for _, f := range files {
+		got := reloadFile(t, f.ID)
+		if got.ScanStatus != models.ScanInfected || got.ScanSignature != "Eicar-Test-Signature" {
+			t.Errorf("file %d: status=%s signature=%q, want infected with the EICAR signature", f.ID, got.ScanStatus, got.ScanSignature)
+		}
+		if got.StorageKey != quarantineKey || got.ThumbnailKey != "" {
+			t.Errorf("file %d: key=%s thumbnail=%q, want %s and no thumbnail", f.ID, got.StorageKey, got.ThumbnailKey, quarantineKey)
+		}
+	}
+
+	if _, err := config.Storage.Get(ctx, files[0].StorageKey); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("original object: err = %v, want ErrNotFound", err)
+	}
+	if _, err := config.Storage.Get(ctx, files[0].ThumbnailKey); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("thumbnail: err = %v, want ErrNotFound", err)
+	}
+	r, err := config.Storage.Get(ctx, quarantineKey)
+	if err != nil {
+		t.Fatalf("quarantined object: %v", err)
+	}
+	content, _ := io.ReadAll(r)
+	r.Close()
+	if string(content) != "infected "+eicar {
+		t.Errorf("quarantined content = %q", content)
+	}
+
+	var audits int64
+	config.DB.Model(&models.AuditLog{}).Where("action = ?", "document.quarantine").Count(&audits)
+	if audits != int64(len(files)) {
+		t.Errorf("quarantine audit entries = %d, want %d", audits, len(files))
+	}
+}
+
+func TestScanDocumentScannerUnavailable(t *testing.T) {
+	fake := scanner.NewFake()
+	fake.Err = errors.New("scanner: connect to clamd: no such file or directory")
+	setupScanTest(t, fake)
+	files := storeScanFixture(t, "infected "+eicar, 1)
+	id := files[0].ID
+
+	for attempt := 1; attempt <= maxScanAttempts; attempt++ {
+		if err := scanDocument(id); err == nil {
+			t.Fatalf("attempt %d: scanDocument succeeded without a scanner", attempt)
+		}
+		got := reloadFile(t, id)
+		want := models.ScanPending
+		if attempt == maxScanAttempts {
+			want = models.ScanFailed
+		}
+		if got.ScanStatus != want || got.ScanAttempts != attempt {
+			t.Fatalf("attempt %d: status=%s attempts=%d, want %s and %d", attempt, got.ScanStatus, got.ScanAttempts, want, attempt)
+		}
+		if got.StorageKey != files[0].StorageKey {
+			t.Fatalf("attempt %d: content moved to %s without a verdict", attempt, got.StorageKey)
+		}
+	}
+
+	// Failed files are left for an admin to rescan
+	if err := scanDocument(id); err != nil {
+		t.Fatalf("scanDocument on a failed file: %v", err)
+	}
+	if got := reloadFile(t, id); got.ScanAttempts != maxScanAttempts {
+		t.Errorf("failed file was scanned again: attempts=%d", got.ScanAttempts)
+	}
+}
@@ -21,7 +21,7 @@ type CreateBeasiswaInput struct {
 func GetScholarships(c *gin.Context) {
 	var beasiswa []models.Beasiswa
 
-	if err := config.DB.Find(&beasiswa).Error; err != nil {
+	if err := config.DB.Preload("DocumentRequirements").Find(&beasiswa).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data beasiswa"})
 		return
 	}
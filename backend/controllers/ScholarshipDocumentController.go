@@ -0,0 +1,342 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"sibestie/config"
+	"sibestie/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DocumentRequirementInput lists one document type a scholarship asks for
+type DocumentRequirementInput struct {
+	Type        string `json:"type" binding:"required"`
+	Required    bool   `json:"required"`
+	Description string `json:"description"`
+}
+
+// DocumentRequirementsRequest replaces the document requirements of a
+// scholarship
+type DocumentRequirementsRequest struct {
+	Documents []DocumentRequirementInput `json:"documents"`
+}
+
+// DocumentChecklistItem is one line of an application's document checklist
+type DocumentChecklistItem struct {
+	Type         string `json:"type"`
+	Label        string `json:"label"`
+	Description  string `json:"description"`
+	Required     bool   `json:"required"`
+	Provided     bool   `json:"provided"`
+	DocumentID   uint   `json:"document_id,omitempty"`
+	ScanStatus   string `json:"scan_status,omitempty"`
+	ReviewStatus string `json:"review_status,omitempty"`
+}
+
+// DocumentChecklistSummary counts how far an application's documents are
+type DocumentChecklistSummary struct {
+	RequiredTotal    int  `json:"required_total"`
+	RequiredProvided int  `json:"required_provided"`
+	RequiredValid    int  `json:"required_valid"`
+	OptionalProvided int  `json:"optional_provided"`
+	Complete         bool `json:"complete"`
+	Percent          int  `json:"percent"`
+}
+
+var errUnknownScholarship = errors.New("scholarship not found")
+
+// defaultDocumentRequirements applies to applications without a scholarship
+// or to scholarships that have not listed their documents
+func defaultDocumentRequirements() []models.BeasiswaDocumentRequirement {
+	requirements := make([]models.BeasiswaDocumentRequirement, 0, len(models.DocumentTypes))
+	for _, docType := range models.DocumentTypes {
+		requirements = append(requirements, models.BeasiswaDocumentRequirement{
+			DocType:  docType,
+			Required: models.IsRequiredDocument(docType),
+		})
+	}
+	return requirements
+}
+
+// documentRequirements returns the documents a scholarship asks for, in
+// the order of models.DocumentTypes
+func documentRequirements(db *gorm.DB, beasiswaID uint) ([]models.BeasiswaDocumentRequirement, error) {
+	if beasiswaID == 0 {
+		return defaultDocumentRequirements(), nil
+	}
+
+	var listed []models.BeasiswaDocumentRequirement
+	if err := db.Where("beasiswa_id = ?", beasiswaID).Find(&listed).Error; err != nil {
+		return nil, err
+	}
+	if len(listed) == 0 {
+		return defaultDocumentRequirements(), nil
+	}
+
+	byType := make(map[string]models.BeasiswaDocumentRequirement, len(listed))
+	for _, requirement := range listed {
+		byType[requirement.DocType] = requirement
+	}
+	requirements := make([]models.BeasiswaDocumentRequirement, 0, len(listed))
+	for _, docType := range models.DocumentTypes {
+		if requirement, ok := byType[docType]; ok {
+			requirements = append(requirements, requirement)
+		}
+	}
+	return requirements, nil
+}
+
+// verifikasiRequirements returns the document requirements of the
+// scholarship a verification was submitted for, keyed by document type
+func verifikasiRequirements(db *gorm.DB, verifikasiID uint) (map[string]models.BeasiswaDocumentRequirement, error) {
+	var verifikasi models.Verifikasi
+	if err := db.Select("id", "beasiswa_id").First(&verifikasi, verifikasiID).Error; err != nil {
+		return nil, err
+	}
+	requirements, err := documentRequirements(db, verifikasi.BeasiswaID)
+	if err != nil {
+		return nil, err
+	}
+	byType := make(map[string]models.BeasiswaDocumentRequirement, len(requirements))
+	for _, requirement := range requirements {
+		byType[requirement.DocType] = requirement
+	}
+	return byType, nil
+}
+
+// checkSubmittedDocuments validates the documents of a submission against
+// the scholarship's requirements. It returns the required types that are
+// missing and the submitted types the scholarship does not ask for.
+func checkSubmittedDocuments(db *gorm.DB, beasiswaID uint, files map[string]models.SourceFile) (missing, unexpected []string, err error) {
+	if beasiswaID != 0 {
+		var count int64
+		if err := db.Model(&models.Beasiswa{}).Where("id = ?", beasiswaID).Count(&count).Error; err != nil {
+			return nil, nil, err
+		}
+		if count == 0 {
+			return nil, nil, errUnknownScholarship
+		}
+	}
+
+	requirements, err := documentRequirements(db, beasiswaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	listed := make(map[string]bool, len(requirements))
+	missing, unexpected = []string{}, []string{}
+	for _, requirement := range requirements {
+		listed[requirement.DocType] = true
+		if _, ok := files[requirement.DocType]; requirement.Required && !ok {
+			missing = append(missing, requirement.DocType)
+		}
+	}
+	for _, docType := range models.DocumentTypes {
+		if _, ok := files[docType]; ok && !listed[docType] {
+			unexpected = append(unexpected, docType)
+		}
+	}
+	return missing, unexpected, nil
+}
+
+// documentChecklist lists the documents a verification's scholarship asks
+// for alongside what the applicant attached
+func documentChecklist(db *gorm.DB, verifikasi models.Verifikasi) ([]DocumentChecklistItem, DocumentChecklistSummary, error) {
+	var summary DocumentChecklistSummary
+
+	requirements, err := documentRequirements(db, verifikasi.BeasiswaID)
+	if err != nil {
+		return nil, summary, err
+	}
+
+	var links []models.VerifikasiDocument
+	if err := db.Where("verifikasi_id = ?", verifikasi.ID).Find(&links).Error; err != nil {
+		return nil, summary, err
+	}
+	attached := make(map[string]models.VerifikasiDocument, len(links))
+	for _, link := range links {
+		attached[link.DocType] = link
+	}
+
+	items := make([]DocumentChecklistItem, 0, len(requirements))
+	for _, requirement := range requirements {
+		item := DocumentChecklistItem{
+			Type:        requirement.DocType,
+			Label:       models.DocumentLabels[requirement.DocType],
+			Description: requirement.Description,
+			Required:    requirement.Required,
+		}
+		if link, ok := attached[requirement.DocType]; ok {
+			var file models.SourceFile
+			err := db.Select("id", "scan_status").First(&file, link.SourceFileID).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, summary, err
+			}
+			if err == nil {
+				item.Provided = true
+				item.DocumentID = file.ID
+				item.ScanStatus = file.ScanStatus
+				item.ReviewStatus = link.ReviewStatus
+			}
+		}
+
+		if item.Required {
+			summary.RequiredTotal++
+			if item.Provided {
+				summary.RequiredProvided++
+				if item.ReviewStatus == models.DocumentValid {
+					summary.RequiredValid++
+				}
+			}
+		} else if item.Provided {
+			summary.OptionalProvided++
+		}
+		items = append(items, item)
+	}
+
+	summary.Complete = summary.RequiredProvided == summary.RequiredTotal
+	summary.Percent = 100
+	if summary.RequiredTotal > 0 {
+		summary.Percent = summary.RequiredProvided * 100 / summary.RequiredTotal
+	}
+	return items, summary, nil
+}
+
+// findScholarship loads the scholarship named by the :id parameter. It
+// writes an error response and returns false when it does not exist.
+func findScholarship(c *gin.Context) (models.Beasiswa, bool) {
+	var beasiswa models.Beasiswa
+	if err := config.DB.First(&beasiswa, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Scholarship not found"})
+		} else {
+			log.Printf("Error finding scholarship: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find scholarship"})
+		}
+		return beasiswa, false
+	}
+	return beasiswa, true
+}
+
+// GET /api/scholarships/:id/documents
+func GetScholarshipDocuments(c *gin.Context) {
+	beasiswa, ok := findScholarship(c)
+	if !ok {
+		return
+	}
+
+	requirements, err := documentRequirements(config.DB, beasiswa.ID)
+	if err != nil {
+		log.Printf("Error loading document requirements: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load document requirements"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"beasiswa_id": beasiswa.ID, "documents": requirements, "labels": models.DocumentLabels})
+}
+
+// PUT /api/scholarships/:id/documents
+func SetScholarshipDocuments(c *gin.Context) {
+	var input DocumentRequirementsRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document requirements: " + err.Error()})
+		return
+	}
+
+	beasiswa, ok := findScholarship(c)
+	if !ok {
+		return
+	}
+
+	requirements := make([]models.BeasiswaDocumentRequirement, 0, len(input.Documents))
+	seen := make(map[string]bool, len(input.Documents))
+	for _, document := range input.Documents {
+		if !models.IsDocumentType(document.Type) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown document type: " + document.Type, "allowed": models.DocumentTypes})
+			return
+		}
+		if seen[document.Type] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Document type listed twice: " + document.Type})
+			return
+		}
+		seen[document.Type] = true
+		requirements = append(requirements, models.BeasiswaDocumentRequirement{
+			BeasiswaID:  beasiswa.ID,
+			DocType:     document.Type,
+			Required:    document.Required,
+			Description: strings.TrimSpace(document.Description),
+		})
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("beasiswa_id = ?", beasiswa.ID).Delete(&models.BeasiswaDocumentRequirement{}).Error; err != nil {
+			return err
+		}
+		if len(requirements) > 0 {
+			if err := tx.Create(&requirements).Error; err != nil {
+				return err
+			}
+		}
+		required := []string{}
+		for _, requirement := range requirements {
+			if requirement.Required {
+				required = append(required, requirement.DocType)
+			}
+		}
+		return recordAudit(tx, currentUserID(c), "scholarship.documents", "beasiswa", beasiswa.ID,
+			fmt.Sprintf("documents=%d required=%s", len(requirements), strings.Join(required, ",")))
+	})
+	if err != nil {
+		log.Printf("Error saving document requirements: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save document requirements"})
+		return
+	}
+
+	// An empty list falls back to the default requirements
+	saved, err := documentRequirements(config.DB, beasiswa.ID)
+	if err != nil {
+		log.Printf("Error loading document requirements: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load document requirements"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document requirements saved", "documents": saved})
+}
+
+// GET /api/verifikasi/:id/checklist
+func GetDocumentChecklist(c *gin.Context) {
+	var verifikasi models.Verifikasi
+	if err := config.DB.First(&verifikasi, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Verification data not found"})
+		} else {
+			log.Printf("Error finding verification for checklist: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find verification data"})
+		}
+		return
+	}
+	role := currentUserRole(c)
+	if verifikasi.UserID != currentUserID(c) && role != "verifikator" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to view this verification"})
+		return
+	}
+
+	items, summary, err := documentChecklist(config.DB, verifikasi)
+	if err != nil {
+		log.Printf("Error building document checklist: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build document checklist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"verifikasi_id": verifikasi.ID,
+		"beasiswa_id":   verifikasi.BeasiswaID,
+		"items":         items,
+		"summary":       summary,
+	})
+}
@@ -0,0 +1,926 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sibestie/config"
+	"sibestie/models"
+	"sibestie/tools/scoring"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ScoringRulesRequest saves a new version of the scoring rules of a
+// profile; profile 0 is the default profile
+type ScoringRulesRequest struct {
+	ProfileID uint          `json:"profile_id"`
+	Rules     scoring.Rules `json:"rules"`
+	Note      string        `json:"note"`
+	Activate  bool          `json:"activate"`
+}
+
+// ScoringRuleSetResponse is a rule set version with its rules decoded
+type ScoringRuleSetResponse struct {
+	models.ScoringRuleSet
+	Rules scoring.Rules `json:"rules"`
+}
+
+// ScoringProfileRequest creates a scoring profile. Without rules the
+// profile starts with the active rules of the default profile.
+type ScoringProfileRequest struct {
+	Code        string         `json:"code" binding:"required"`
+	Name        string         `json:"name" binding:"required"`
+	Description string         `json:"description"`
+	Rules       *scoring.Rules `json:"rules"`
+}
+
+// ScoringProfileResponse is a profile with its active rule set
+type ScoringProfileResponse struct {
+	models.ScoringProfile
+	ActiveVersion int            `json:"active_version"`
+	Rules         *scoring.Rules `json:"rules"`
+}
+
+// ScholarshipScoringProfileRequest sets the scoring profile of a
+// scholarship; 0 goes back to the default profile
+type ScholarshipScoringProfileRequest struct {
+	ProfileID uint `json:"profile_id"`
+}
+
+// ScholarshipRanking is the position of an application among the
+// applications to one scholarship
+type ScholarshipRanking struct {
+	Position     int    `json:"position"`
+	VerifikasiID uint   `json:"verifikasi_id"`
+	UserID       uint   `json:"user_id"`
+	NamaLengkap  string `json:"nama_lengkap"`
+	Status       string `json:"status"`
+	// Stored merit score, and the rank the verifikator set or else the
+	// merit rank
+	Score            float64 `json:"score"`
+	Rank             int     `json:"rank"`
+	DataCompleteness int     `json:"data_completeness"`
+	OverrideRank     int     `json:"override_rank"`
+	ScoringVersion   int     `json:"scoring_version"`
+}
+
+// ScoreBreakdown explains how the rank of an application came about
+type ScoreBreakdown struct {
+	scoring.Result
+	ScoringVersion int `json:"scoring_version"`
+	// Reproducible is false for merit ranks migrated from before the rules
+	// were versioned: the formula that gave them was not kept, so the
+	// result above does not explain the stored rank
+	Reproducible bool `json:"reproducible"`
+	// Merit rank stored on the application and the rank the verifikator
+	// set by hand, 0 when not overridden
+	MeritRank    int                  `json:"merit_rank"`
+	OverrideRank int                  `json:"override_rank"`
+	Household    scoring.Household    `json:"household"`
+	Completeness scoring.Completeness `json:"completeness"`
+}
+
+var errNoScoringRules = errors.New("no active scoring rule set")
+
+// seededRuleSets are the rule versions the default profile gets after the
+// baseline, oldest first. Each is saved and made active once, when the
+// profile has no version holding the same rules yet; an admin can activate
+// another version afterwards.
+var seededRuleSets = []struct {
+	note  string
+	rules func() scoring.Rules
+}{
+	{"Household income per capita relative to the regional minimum wage", scoring.PerCapitaRules},
+	{"Merit and need only, data completeness measured apart", scoring.MeritRules},
+}
+
+// SeedScoringRules creates the default profile and makes sure it has a
+// version holding the default rules, the baseline of the versioned rules.
+// Rule sets saved before profiles existed belong to the default profile.
+// Applications without a scoring version are stamped with that baseline
+// version; ranks migrated from before the rules were versioned stay marked
+// as not reproducible by it.
+func SeedScoringRules() {
+	var profile models.ScoringProfile
+	err := config.DB.Where(models.ScoringProfile{Code: models.DefaultScoringProfile}).
+		Attrs(models.ScoringProfile{Name: "Default", Description: "Used by scholarships without a scoring profile"}).
+		FirstOrCreate(&profile).Error
+	if err != nil {
+		log.Printf("Error seeding scoring profile: %v", err)
+		return
+	}
+	if err := config.DB.Model(&models.ScoringRuleSet{}).Where("profile_id = 0 OR profile_id IS NULL").Update("profile_id", profile.ID).Error; err != nil {
+		log.Printf("Error assigning scoring rules to the default profile: %v", err)
+	}
+
+	baseline, err := baselineRuleSet(config.DB)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var active int64
+		if err := config.DB.Model(&models.ScoringRuleSet{}).Where("profile_id = ? AND active = ?", profile.ID, true).Count(&active).Error; err != nil {
+			log.Printf("Error seeding scoring rules: %v", err)
+			return
+		}
+		baseline = models.ScoringRuleSet{ProfileID: profile.ID, Note: "Default rules"}
+		err = config.DB.Transaction(func(tx *gorm.DB) error {
+			return saveRuleSet(tx, &baseline, scoring.DefaultRules(), active == 0)
+		})
+	}
+	if err != nil {
+		log.Printf("Error seeding scoring rules: %v", err)
+		return
+	}
+
+	result := config.DB.Model(&models.Verifikasi{}).
+		Where("scoring_version = 0 OR scoring_version IS NULL").
+		Update("scoring_version", baseline.Version)
+	if result.Error != nil {
+		log.Printf("Error stamping verifications with the baseline scoring version: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("[MIGRATE] Stamped %d verifications with baseline scoring version %d", result.RowsAffected, baseline.Version)
+	}
+
+	for _, seed := range seededRuleSets {
+		rules := seed.rules()
+		if _, err := profileRuleSet(config.DB, profile.ID, rules); !errors.Is(err, gorm.ErrRecordNotFound) {
+			if err != nil {
+				log.Printf("Error seeding scoring rules %q: %v", seed.note, err)
+			}
+			continue
+		}
+		ruleSet := models.ScoringRuleSet{ProfileID: profile.ID, Note: seed.note}
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			return saveRuleSet(tx, &ruleSet, rules, true)
+		})
+		if err != nil {
+			log.Printf("Error seeding scoring rules %q: %v", seed.note, err)
+			continue
+		}
+		log.Printf("[MIGRATE] Activated scoring rules version %d: %s", ruleSet.Version, seed.note)
+	}
+}
+
+// legacyRank reads the single rank verifikasis carried before data
+// completeness and the merit score were stored apart. The column is left
+// in place so the migration can be checked against it.
+type legacyRank struct {
+	ID                   uint
+	DataCompletenessRank int
+}
+
+func (legacyRank) TableName() string {
+	return "verifikasis"
+}
+
+// MigrateScores moves the single rank applications carried before data
+// completeness and the merit score were stored apart into the merit rank
+// as it is, marked as migrated: the rule version the application is stamped
+// with does not reproduce it. Data
+// completeness is measured from the application; the old rank came with
+// no score, so the merit score stays 0. Whether a verifikator typed the old
+// rank in was never recorded, so no rank overrides are made for it.
+func MigrateScores() {
+	if !legacyColumnsPresent(config.DB, "data_completeness_rank") {
+		return
+	}
+	var legacy []legacyRank
+	err := config.DB.Where("(merit_rank = 0 OR merit_rank IS NULL) AND data_completeness_rank > 0").Find(&legacy).Error
+	if err != nil {
+		log.Printf("Error finding ranks to migrate: %v", err)
+		return
+	}
+
+	migrated := 0
+	for _, old := range legacy {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var verifikasi models.Verifikasi
+			if err := withApplicant(tx).First(&verifikasi, old.ID).Error; err != nil {
+				return err
+			}
+			updates := map[string]interface{}{
+				"merit_rank":    old.DataCompletenessRank,
+				"merit_score":   0,
+				"override_rank": 0,
+				"rank_migrated": true,
+			}
+			// Anonymized applications have nothing left to measure
+			if verifikasi.AnonymizedAt == nil {
+				completeness, err := applicationCompleteness(tx, verifikasiToData(verifikasi), attachedDocTypes(verifikasi))
+				if err != nil {
+					return err
+				}
+				updates["data_completeness"] = completeness.Percent
+			}
+			return tx.Model(&verifikasi).Updates(updates).Error
+		})
+		if err != nil {
+			log.Printf("Error migrating rank of verification %d: %v", old.ID, err)
+			continue
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		log.Printf("[MIGRATE] Moved the ranks of %d verifications into their merit rank", migrated)
+	}
+}
+
+// decodeRules parses the rules stored in a rule set
+func decodeRules(ruleSet models.ScoringRuleSet) (scoring.Rules, error) {
+	var rules scoring.Rules
+	if err := json.Unmarshal([]byte(ruleSet.Rules), &rules); err != nil {
+		return rules, fmt.Errorf("scoring rule set %d: %w", ruleSet.Version, err)
+	}
+	return rules, nil
+}
+
+// defaultScoringProfile returns the profile of scholarships without one
+func defaultScoringProfile(db *gorm.DB) (models.ScoringProfile, error) {
+	var profile models.ScoringProfile
+	err := db.Where("code = ?", models.DefaultScoringProfile).First(&profile).Error
+	return profile, err
+}
+
+// scholarshipProfile returns the scoring profile applications to a
+// scholarship are scored with
+func scholarshipProfile(db *gorm.DB, beasiswaID uint) (models.ScoringProfile, error) {
+	if beasiswaID != 0 {
+		var beasiswa models.Beasiswa
+		if err := db.Select("id", "scoring_profile_id").First(&beasiswa, beasiswaID).Error; err != nil {
+			return models.ScoringProfile{}, err
+		}
+		if beasiswa.ScoringProfileID != 0 {
+			var profile models.ScoringProfile
+			err := db.First(&profile, beasiswa.ScoringProfileID).Error
+			return profile, err
+		}
+	}
+	return defaultScoringProfile(db)
+}
+
+// activeScoringRules returns the rule set a profile scores new
+// applications with
+func activeScoringRules(db *gorm.DB, profileID uint) (models.ScoringRuleSet, scoring.Rules, error) {
+	var ruleSet models.ScoringRuleSet
+	if err := db.Where("profile_id = ? AND active = ?", profileID, true).Order("version desc").First(&ruleSet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ruleSet, scoring.Rules{}, errNoScoringRules
+		}
+		return ruleSet, scoring.Rules{}, err
+	}
+	rules, err := decodeRules(ruleSet)
+	return ruleSet, rules, err
+}
+
+// scoringRules returns the rules of a version
+func scoringRules(db *gorm.DB, version int) (models.ScoringRuleSet, scoring.Rules, error) {
+	var ruleSet models.ScoringRuleSet
+	if err := db.Where("version = ?", version).First(&ruleSet).Error; err != nil {
+		return ruleSet, scoring.Rules{}, err
+	}
+	rules, err := decodeRules(ruleSet)
+	return ruleSet, rules, err
+}
+
+// profileRuleSet returns the oldest version of a profile that holds the
+// given rules
+func profileRuleSet(db *gorm.DB, profileID uint, rules scoring.Rules) (models.ScoringRuleSet, error) {
+	var ruleSets []models.ScoringRuleSet
+	if err := db.Where("profile_id = ?", profileID).Order("version").Find(&ruleSets).Error; err != nil {
+		return models.ScoringRuleSet{}, err
+	}
+	for _, ruleSet := range ruleSets {
+		if saved, err := decodeRules(ruleSet); err == nil && reflect.DeepEqual(saved, rules) {
+			return ruleSet, nil
+		}
+	}
+	return models.ScoringRuleSet{}, gorm.ErrRecordNotFound
+}
+
+// baselineRuleSet returns the oldest version of the default profile that
+// holds the default rules. Applications scored before the rules were
+// versioned are stamped with it.
+func baselineRuleSet(db *gorm.DB) (models.ScoringRuleSet, error) {
+	profile, err := defaultScoringProfile(db)
+	if err != nil {
+		return models.ScoringRuleSet{}, err
+	}
+	return profileRuleSet(db, profile.ID, scoring.DefaultRules())
+}
+
+// verifikasiScoringRules returns the rules an application was scored with.
+// An application without a scoring version has not been stamped yet and
+// was scored with the baseline rules; it never takes on the active rules.
+func verifikasiScoringRules(db *gorm.DB, verifikasi models.Verifikasi) (models.ScoringRuleSet, scoring.Rules, error) {
+	if verifikasi.ScoringVersion != 0 {
+		return scoringRules(db, verifikasi.ScoringVersion)
+	}
+	ruleSet, err := baselineRuleSet(db)
+	if err != nil {
+		return ruleSet, scoring.Rules{}, err
+	}
+	rules, err := decodeRules(ruleSet)
+	return ruleSet, rules, err
+}
+
+// scoringApplicant extracts what the scoring looks at from an application.
+// appliedUMR is the minimum wage stored when the application was scored;
+// without one, the minimum wage of the applicant's region when it was
+// submitted is looked up.
+func scoringApplicant(db *gorm.DB, data VerifikasiData, appliedUMR *int64) (scoring.Applicant, error) {
+	applicant := scoring.Applicant{
+		PersonalFields: []scoring.Field{
+			{Name: "nik", Filled: data.NIK != ""},
+			{Name: "nisn", Filled: data.NISN != ""},
+			{Name: "nama_lengkap", Filled: data.NamaLengkap != ""},
+			{Name: "tanggal_lahir", Filled: data.TanggalLahir != ""},
+			{Name: "tempat_lahir", Filled: data.TempatLahir != ""},
+			{Name: "alamat", Filled: data.Alamat != ""},
+			{Name: "foto_ktp", Filled: data.FotoKTP != ""},
+			{Name: "nomor_telepon", Filled: data.NomorTelepon != ""},
+			{Name: "email", Filled: data.Email != ""},
+		},
+		SchoolName:     data.AsalSekolah,
+		GraduationYear: data.TahunLulus,
+		HasIjazah:      data.FotoIjazah != "",
+		MotherJob:      data.PekerjaanIbu,
+		MotherIncome:   int64(data.PendapatanIbu),
+		FatherJob:      data.PekerjaanAyah,
+		FatherIncome:   int64(data.PendapatanAyah),
+		FamilyAddress:  data.AlamatKeluarga,
+		Dependents:     data.Saudara.Dependents(),
+	}
+	if average, ok := averageGrade(data.NilaiSemester); ok {
+		applicant.AverageGrade = &average
+	}
+	for _, parent := range []string{data.NamaIbu, data.NamaAyah} {
+		if strings.TrimSpace(parent) != "" {
+			applicant.Parents++
+		}
+	}
+
+	if appliedUMR != nil {
+		applicant.UMR = float64(*appliedUMR)
+		return applicant, nil
+	}
+	year := time.Now().Year()
+	if submitted, err := time.Parse("2006-01-02 15:04:05", data.CreatedAt); err == nil {
+		year = submitted.Year()
+	}
+	wage, ok, err := regionalMinimumWage(db, data.NIK, year)
+	if err != nil {
+		return applicant, err
+	}
+	if ok {
+		applicant.UMR = float64(wage.Amount)
+	}
+	return applicant, nil
+}
+
+// householdOf works out the household of an applicant under the given rules
+func householdOf(rules scoring.Rules, applicant scoring.Applicant) scoring.Household {
+	fallback := 0.0
+	if rules.Household != nil {
+		fallback = rules.Household.FallbackUMR
+	}
+	return scoring.HouseholdOf(applicant, fallback)
+}
+
+// explainScore scores an application again under the rules it was scored
+// with and itemizes the result. data is the application as returned by
+// verifikasiToData.
+func explainScore(db *gorm.DB, verifikasi models.Verifikasi, data VerifikasiData) (ScoreBreakdown, error) {
+	ruleSet, rules, err := verifikasiScoringRules(db, verifikasi)
+	if err != nil {
+		return ScoreBreakdown{}, err
+	}
+	applicant, err := scoringApplicant(db, data, verifikasi.AppliedUMR)
+	if err != nil {
+		return ScoreBreakdown{}, err
+	}
+	completeness, err := applicationCompleteness(db, data, attachedDocTypes(verifikasi))
+	if err != nil {
+		return ScoreBreakdown{}, err
+	}
+	return ScoreBreakdown{
+		Result:         scoring.Score(rules, applicant),
+		ScoringVersion: ruleSet.Version,
+		Reproducible:   !verifikasi.RankMigrated,
+		MeritRank:      verifikasi.MeritRank,
+		OverrideRank:   verifikasi.OverrideRank,
+		Household:      householdOf(rules, applicant),
+		Completeness:   completeness,
+	}, nil
+}
+
+// applicationCompleteness measures how much of the required data, and of
+// the documents its scholarship requires, an application gives. documents
+// lists the types of the attached documents.
+func applicationCompleteness(db *gorm.DB, data VerifikasiData, documents []string) (scoring.Completeness, error) {
+	fields := []scoring.Field{
+		{Name: "nik", Filled: data.NIK != ""},
+		{Name: "nisn", Filled: data.NISN != ""},
+		{Name: "nama_lengkap", Filled: data.NamaLengkap != ""},
+		{Name: "tanggal_lahir", Filled: data.TanggalLahir != ""},
+		{Name: "jenis_kelamin", Filled: data.JenisKelamin != ""},
+		{Name: "tempat_lahir", Filled: data.TempatLahir != ""},
+		{Name: "alamat", Filled: data.Alamat != ""},
+		{Name: "nomor_telepon", Filled: data.NomorTelepon != ""},
+		{Name: "email", Filled: data.Email != ""},
+		{Name: "nama_ibu", Filled: data.NamaIbu != ""},
+		{Name: "pekerjaan_ibu", Filled: data.PekerjaanIbu != ""},
+		{Name: "nama_ayah", Filled: data.NamaAyah != ""},
+		{Name: "pekerjaan_ayah", Filled: data.PekerjaanAyah != ""},
+		{Name: "alamat_keluarga", Filled: data.AlamatKeluarga != ""},
+		{Name: "asal_sekolah", Filled: data.AsalSekolah != ""},
+		{Name: "tahun_lulus", Filled: data.TahunLulus != ""},
+		{Name: "nilai_semester", Filled: len(data.NilaiSemester) > 0},
+	}
+
+	requirements, err := documentRequirements(db, data.BeasiswaID)
+	if err != nil {
+		return scoring.Completeness{}, err
+	}
+	attached := make(map[string]bool, len(documents))
+	for _, docType := range documents {
+		attached[docType] = true
+	}
+	for _, requirement := range requirements {
+		if requirement.Required {
+			fields = append(fields, scoring.Field{Name: "dokumen_" + requirement.DocType, Filled: attached[requirement.DocType]})
+		}
+	}
+	return scoring.CompletenessOf(fields), nil
+}
+
+// attachedDocTypes lists the types of the documents attached to a
+// verification; Documents must be loaded
+func attachedDocTypes(verifikasi models.Verifikasi) []string {
+	docTypes := make([]string, 0, len(verifikasi.Documents))
+	for _, link := range verifikasi.Documents {
+		docTypes = append(docTypes, link.DocType)
+	}
+	return docTypes
+}
+
+// assessApplication stores the data completeness and the merit score of
+// an application, scored with the active rules of its scholarship's
+// profile, along with the rule version and the minimum wage applied. A
+// rank override is left as it is.
+func assessApplication(db *gorm.DB, verifikasi *models.Verifikasi, data VerifikasiData, documents []string) error {
+	profile, err := scholarshipProfile(db, data.BeasiswaID)
+	if err != nil {
+		return err
+	}
+	ruleSet, rules, err := activeScoringRules(db, profile.ID)
+	if err != nil {
+		return err
+	}
+	applicant, err := scoringApplicant(db, data, nil)
+	if err != nil {
+		return err
+	}
+	completeness, err := applicationCompleteness(db, data, documents)
+	if err != nil {
+		return err
+	}
+	score := scoring.Score(rules, applicant)
+	umr := int64(applicant.UMR)
+	verifikasi.DataCompleteness = completeness.Percent
+	verifikasi.MeritScore = score.Total * 100
+	verifikasi.MeritRank = score.Rank
+	verifikasi.ScoringVersion = ruleSet.Version
+	verifikasi.RankMigrated = false
+	verifikasi.AppliedUMR = &umr
+	return nil
+}
+
+// ruleSetResponse decodes a rule set for the API
+func ruleSetResponse(ruleSet models.ScoringRuleSet) (ScoringRuleSetResponse, error) {
+	rules, err := decodeRules(ruleSet)
+	return ScoringRuleSetResponse{ScoringRuleSet: ruleSet, Rules: rules}, err
+}
+
+// activateRuleSet makes one version the active rule set of its profile
+func activateRuleSet(tx *gorm.DB, ruleSet *models.ScoringRuleSet) error {
+	err := tx.Model(&models.ScoringRuleSet{}).
+		Where("profile_id = ? AND active = ? AND id <> ?", ruleSet.ProfileID, true, ruleSet.ID).
+		Update("active", false).Error
+	if err != nil {
+		return err
+	}
+	ruleSet.Active = true
+	return tx.Model(ruleSet).Update("active", true).Error
+}
+
+// saveRuleSet stores rules as the next version of a profile
+func saveRuleSet(tx *gorm.DB, ruleSet *models.ScoringRuleSet, rules scoring.Rules, activate bool) error {
+	encoded, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	ruleSet.Rules = string(encoded)
+
+	var latest int
+	if err := tx.Model(&models.ScoringRuleSet{}).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return err
+	}
+	ruleSet.Version = latest + 1
+	if err := tx.Create(ruleSet).Error; err != nil {
+		return err
+	}
+	if activate {
+		return activateRuleSet(tx, ruleSet)
+	}
+	return nil
+}
+
+// findRuleSet loads the rule set named by the :version parameter. It writes
+// an error response and returns false when it does not exist.
+func findRuleSet(c *gin.Context) (models.ScoringRuleSet, bool) {
+	var ruleSet models.ScoringRuleSet
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule set version"})
+		return ruleSet, false
+	}
+	if err := config.DB.Where("version = ?", version).First(&ruleSet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Scoring rule set not found"})
+		} else {
+			log.Printf("Error finding scoring rule set: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find scoring rule set"})
+		}
+		return ruleSet, false
+	}
+	return ruleSet, true
+}
+
+// findProfile loads a scoring profile by ID, 0 being the default profile.
+// It writes an error response and returns false when it does not exist.
+func findProfile(c *gin.Context, profileID uint) (models.ScoringProfile, bool) {
+	var profile models.ScoringProfile
+	var err error
+	if profileID == 0 {
+		profile, err = defaultScoringProfile(config.DB)
+	} else {
+		err = config.DB.First(&profile, profileID).Error
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Scoring profile not found"})
+		} else {
+			log.Printf("Error finding scoring profile: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find scoring profile"})
+		}
+		return profile, false
+	}
+	return profile, true
+}
+
+// GET /api/scoring/rules
+func ListScoringRules(c *gin.Context) {
+	query := config.DB.Order("version desc")
+	if profileID := c.Query("profile_id"); profileID != "" {
+		query = query.Where("profile_id = ?", profileID)
+	}
+
+	var ruleSets []models.ScoringRuleSet
+	if err := query.Find(&ruleSets).Error; err != nil {
+		log.Printf("Error querying scoring rule sets: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query scoring rule sets"})
+		return
+	}
+
+	response := make([]ScoringRuleSetResponse, 0, len(ruleSets))
+	for _, ruleSet := range ruleSets {
+		item, err := ruleSetResponse(ruleSet)
+		if err != nil {
+			log.Printf("Error decoding scoring rules: %v", err)
+		}
+		response = append(response, item)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GET /api/scoring/rules/:version
+func GetScoringRules(c *gin.Context) {
+	ruleSet, ok := findRuleSet(c)
+	if !ok {
+		return
+	}
+	response, err := ruleSetResponse(ruleSet)
+	if err != nil {
+		log.Printf("Error decoding scoring rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode scoring rules"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// POST /api/scoring/rules
+func CreateScoringRules(c *gin.Context) {
+	var input ScoringRulesRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scoring rules: " + err.Error()})
+		return
+	}
+	if err := input.Rules.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scoring rules: " + err.Error()})
+		return
+	}
+	profile, ok := findProfile(c, input.ProfileID)
+	if !ok {
+		return
+	}
+
+	ruleSet := models.ScoringRuleSet{
+		ProfileID: profile.ID,
+		Note:      strings.TrimSpace(input.Note),
+		CreatedBy: currentUserID(c),
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveRuleSet(tx, &ruleSet, input.Rules, input.Activate); err != nil {
+			return err
+		}
+		return recordAudit(tx, ruleSet.CreatedBy, "scoring.rules_create", "scoring_rule_set", ruleSet.ID,
+			fmt.Sprintf("profile=%s version=%d active=%t note=%q", profile.Code, ruleSet.Version, ruleSet.Active, ruleSet.Note))
+	})
+	if err != nil {
+		log.Printf("Error saving scoring rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save scoring rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scoring rules saved", "data": ScoringRuleSetResponse{ScoringRuleSet: ruleSet, Rules: input.Rules}})
+}
+
+// POST /api/scoring/rules/:version/activate
+func ActivateScoringRules(c *gin.Context) {
+	ruleSet, ok := findRuleSet(c)
+	if !ok {
+		return
+	}
+	if _, err := decodeRules(ruleSet); err != nil {
+		log.Printf("Error decoding scoring rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode scoring rules"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := activateRuleSet(tx, &ruleSet); err != nil {
+			return err
+		}
+		return recordAudit(tx, currentUserID(c), "scoring.rules_activate", "scoring_rule_set", ruleSet.ID,
+			fmt.Sprintf("profile_id=%d version=%d", ruleSet.ProfileID, ruleSet.Version))
+	})
+	if err != nil {
+		log.Printf("Error activating scoring rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate scoring rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scoring rules activated", "version": ruleSet.Version})
+}
+
+// GET /api/verifikasi/:id/score
+func GetVerifikasiScore(c *gin.Context) {
+	var verifikasi models.Verifikasi
+	if err := withApplicant(config.DB).First(&verifikasi, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Verification data not found"})
+		} else {
+			log.Printf("Error finding verification for scoring: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find verification data"})
+		}
+		return
+	}
+	role := currentUserRole(c)
+	if verifikasi.UserID != currentUserID(c) && role != "verifikator" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to view this verification"})
+		return
+	}
+
+	breakdown, err := explainScore(config.DB, verifikasi, verifikasiToData(verifikasi))
+	if err != nil {
+		log.Printf("Error scoring verification: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to score verification"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"verifikasi_id": verifikasi.ID, "score": breakdown})
+}
+
+// GET /api/scoring/profiles
+func ListScoringProfiles(c *gin.Context) {
+	var profiles []models.ScoringProfile
+	if err := config.DB.Order("id").Find(&profiles).Error; err != nil {
+		log.Printf("Error querying scoring profiles: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query scoring profiles"})
+		return
+	}
+
+	response := make([]ScoringProfileResponse, 0, len(profiles))
+	for _, profile := range profiles {
+		item := ScoringProfileResponse{ScoringProfile: profile}
+		ruleSet, rules, err := activeScoringRules(config.DB, profile.ID)
+		if err == nil {
+			item.ActiveVersion = ruleSet.Version
+			item.Rules = &rules
+		} else if !errors.Is(err, errNoScoringRules) {
+			log.Printf("Error loading scoring rules of profile %s: %v", profile.Code, err)
+		}
+		response = append(response, item)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// POST /api/scoring/profiles
+func CreateScoringProfile(c *gin.Context) {
+	var input ScoringProfileRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scoring profile: " + err.Error()})
+		return
+	}
+	code := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(input.Code), " ", "_"))
+
+	var rules scoring.Rules
+	if input.Rules != nil {
+		rules = *input.Rules
+	} else {
+		profile, err := defaultScoringProfile(config.DB)
+		if err == nil {
+			_, rules, err = activeScoringRules(config.DB, profile.ID)
+		}
+		if err != nil {
+			log.Printf("Error loading default scoring rules: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load default scoring rules"})
+			return
+		}
+	}
+	if err := rules.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scoring rules: " + err.Error()})
+		return
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.ScoringProfile{}).Where("code = ?", code).Count(&count).Error; err != nil {
+		log.Printf("Error checking scoring profile code: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check scoring profile code"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Scoring profile code already exists"})
+		return
+	}
+
+	profile := models.ScoringProfile{
+		Code:        code,
+		Name:        strings.TrimSpace(input.Name),
+		Description: strings.TrimSpace(input.Description),
+	}
+	ruleSet := models.ScoringRuleSet{Note: "Initial rules", CreatedBy: currentUserID(c)}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&profile).Error; err != nil {
+			return err
+		}
+		ruleSet.ProfileID = profile.ID
+		if err := saveRuleSet(tx, &ruleSet, rules, true); err != nil {
+			return err
+		}
+		return recordAudit(tx, ruleSet.CreatedBy, "scoring.profile_create", "scoring_profile", profile.ID,
+			fmt.Sprintf("code=%s version=%d", profile.Code, ruleSet.Version))
+	})
+	if err != nil {
+		log.Printf("Error saving scoring profile: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save scoring profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scoring profile created", "data": ScoringProfileResponse{
+		ScoringProfile: profile,
+		ActiveVersion:  ruleSet.Version,
+		Rules:          &rules,
+	}})
+}
+
+// PUT /api/scholarships/:id/scoring-profile
+func SetScholarshipScoringProfile(c *gin.Context) {
+	var input ScholarshipScoringProfileRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scoring profile: " + err.Error()})
+		return
+	}
+
+	beasiswa, ok := findScholarship(c)
+	if !ok {
+		return
+	}
+	profile, ok := findProfile(c, input.ProfileID)
+	if !ok {
+		return
+	}
+
+	profileID := profile.ID
+	if profile.Code == models.DefaultScoringProfile {
+		profileID = 0
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&beasiswa).Update("scoring_profile_id", profileID).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, currentUserID(c), "scholarship.scoring_profile", "beasiswa", beasiswa.ID,
+			fmt.Sprintf("profile=%s", profile.Code))
+	})
+	if err != nil {
+		log.Printf("Error saving scholarship scoring profile: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save scholarship scoring profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scoring profile saved", "beasiswa_id": beasiswa.ID, "profile": profile})
+}
+
+// GET /api/scholarships/:id/rankings
+func GetScholarshipRankings(c *gin.Context) {
+	beasiswa, ok := findScholarship(c)
+	if !ok {
+		return
+	}
+
+	profile, err := scholarshipProfile(config.DB, beasiswa.ID)
+	if err != nil {
+		log.Printf("Error finding scoring profile: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find scoring profile"})
+		return
+	}
+
+	query := withApplicant(config.DB).Where("beasiswa_id = ? AND anonymized_at IS NULL", beasiswa.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status <> ?", models.StatusWithdrawn)
+	}
+	var applications []models.Verifikasi
+	if err := query.Order("created_at").Find(&applications).Error; err != nil {
+		log.Printf("Error querying scholarship applications: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query scholarship applications"})
+		return
+	}
+
+	// Applications are ranked by what was stored when they were scored, each
+	// under its own rule version, so a later rule change does not reorder them
+	rankings := make([]ScholarshipRanking, 0, len(applications))
+	for _, verifikasi := range applications {
+		rankings = append(rankings, ScholarshipRanking{
+			VerifikasiID:     verifikasi.ID,
+			UserID:           verifikasi.UserID,
+			NamaLengkap:      verifikasi.UserData.FullName,
+			Status:           verifikasi.Status,
+			Score:            verifikasi.MeritScore,
+			Rank:             verifikasi.Rank(),
+			DataCompleteness: verifikasi.DataCompleteness,
+			OverrideRank:     verifikasi.OverrideRank,
+			ScoringVersion:   verifikasi.ScoringVersion,
+		})
+	}
+
+	// Rank first, so overrides count, then the merit score. Equal places
+	// share a position; earlier applications are listed first.
+	samePlace := func(a, b ScholarshipRanking) bool {
+		return a.Rank == b.Rank && scoresEqual(a.Score, b.Score)
+	}
+	sort.SliceStable(rankings, func(i, j int) bool {
+		a, b := rankings[i], rankings[j]
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		return !scoresEqual(a.Score, b.Score) && a.Score > b.Score
+	})
+	for i := range rankings {
+		rankings[i].Position = i + 1
+		if i > 0 && samePlace(rankings[i], rankings[i-1]) {
+			rankings[i].Position = rankings[i-1].Position
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"beasiswa_id": beasiswa.ID,
+		"profile":     profile,
+		"total":       len(rankings),
+		"rankings":    rankings,
+	})
+}
+
+// scoresEqual compares two scores ignoring rounding noise
+func scoresEqual(a, b float64) bool {
+	diff := a - b
+	return diff < 1e-9 && diff > -1e-9
+}
@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"sibestie/models"
+	"sibestie/tools/scoring"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// saveTestRules saves rules as a new version of the default profile
+func saveTestRules(t *testing.T, db *gorm.DB, rules scoring.Rules, activate bool) models.ScoringRuleSet {
+	t.Helper()
+	profile, err := defaultScoringProfile(db)
+	if err != nil {
+		t.Fatalf("default profile: %v", err)
+	}
+	ruleSet := models.ScoringRuleSet{ProfileID: profile.ID}
+	if err := saveRuleSet(db, &ruleSet, rules, activate); err != nil {
+		t.Fatalf("save rules: %v", err)
+	}
+	return ruleSet
+}
+
+func TestUnversionedScoresUseBaselineRules(t *testing.T) {
+	db := setupTestDB(t)
+	SeedScoringRules()
+
+	// Rules that differ from the baseline become active
+	changed := scoring.DefaultRules()
+	changed.Weights = scoring.Weights{Personal: 0.2, Academic: 0.4, Family: 0.4}
+	current := saveTestRules(t, db, changed, true)
+
+	// An application scored before the rules were versioned
+	legacy := models.Verifikasi{UserID: 4, Status: models.StatusPending}
+	if err := db.Omit("UserData").Create(&legacy).Error; err != nil {
+		t.Fatalf("create verification: %v", err)
+	}
+	ruleSet, rules, err := verifikasiScoringRules(db, legacy)
+	if err != nil {
+		t.Fatalf("verifikasiScoringRules: %v", err)
+	}
+	if ruleSet.Version == current.Version || !reflect.DeepEqual(rules, scoring.DefaultRules()) {
+		t.Errorf("unversioned application scored with version %d, want the baseline", ruleSet.Version)
+	}
+
+	// Seeding again keeps the baseline and stamps the application with it
+	SeedScoringRules()
+	var count int64
+	db.Model(&models.ScoringRuleSet{}).Count(&count)
+	if want := int64(len(seededRuleSets) + 2); count != want {
+		t.Errorf("%d rule sets after seeding again, want %d", count, want)
+	}
+	if err := db.First(&legacy, legacy.ID).Error; err != nil {
+		t.Fatalf("reload verification: %v", err)
+	}
+	if legacy.ScoringVersion != ruleSet.Version {
+		t.Errorf("scoring version = %d, want baseline %d", legacy.ScoringVersion, ruleSet.Version)
+	}
+	if _, active, _ := activeScoringRules(db, current.ProfileID); !reflect.DeepEqual(active, changed) {
+		t.Error("seeding again changed the active rules")
+	}
+}
+
+func TestScholarshipRankingsUseStoredRanks(t *testing.T) {
+	db := setupTestDB(t)
+	SeedScoringRules()
+	beasiswa := models.Beasiswa{Judul: "Beasiswa Prestasi"}
+	if err := db.Create(&beasiswa).Error; err != nil {
+		t.Fatalf("create scholarship: %v", err)
+	}
+	for _, v := range []models.Verifikasi{
+		{UserID: 4, MeritScore: 62, MeritRank: 6},
+		{UserID: 5, MeritScore: 81, MeritRank: 8},
+		// Ranked up by the verifikator above a better merit score
+		{UserID: 6, MeritScore: 55, MeritRank: 5, OverrideRank: 9},
+		{UserID: 7, MeritScore: 62, MeritRank: 6},
+	} {
+		v.BeasiswaID, v.Status = beasiswa.ID, models.StatusPending
+		if err := db.Omit("UserData").Create(&v).Error; err != nil {
+			t.Fatalf("create verification: %v", err)
+		}
+	}
+	// Rules activated later do not rescore the applications
+	changed := scoring.DefaultRules()
+	changed.Weights = scoring.Weights{Personal: 1}
+	saveTestRules(t, db, changed, true)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(int(beasiswa.ID))}}
+	GetScholarshipRankings(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var response struct {
+		Rankings []ScholarshipRanking `json:"rankings"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	want := []struct {
+		user     uint
+		position int
+		rank     int
+	}{{6, 1, 9}, {5, 2, 8}, {4, 3, 6}, {7, 3, 6}}
+	if len(response.Rankings) != len(want) {
+		t.Fatalf("got %d rankings, want %d", len(response.Rankings), len(want))
+	}
+	for i, w := range want {
+		got := response.Rankings[i]
+		if got.UserID != w.user || got.Position != w.position || got.Rank != w.rank {
+			t.Errorf("ranking %d = user %d at %d with rank %d, want user %d at %d with rank %d",
+				i, got.UserID, got.Position, got.Rank, w.user, w.position, w.rank)
+		}
+	}
+}
+
+func TestScoresKeepTheirMinimumWage(t *testing.T) {
+	db := setupTestDB(t)
+	SeedScoringRules()
+	wage := models.MinimumWage{RegionCode: "32", Year: 2024, Amount: 2000000}
+	if err := db.Create(&wage).Error; err != nil {
+		t.Fatalf("create minimum wage: %v", err)
+	}
+
+	// Three people share 2jt, a third of the UMR per person
+	data := VerifikasiData{
+		NIK:            "3273015208050001",
+		NamaIbu:        "Ibu",
+		NamaAyah:       "Ayah",
+		PendapatanIbu:  1000000,
+		PendapatanAyah: 1000000,
+		CreatedAt:      "2024-07-01 10:00:00",
+	}
+	var verifikasi models.Verifikasi
+	if err := assessApplication(db, &verifikasi, data, nil); err != nil {
+		t.Fatalf("assessApplication: %v", err)
+	}
+	applied := wage.Amount
+	if verifikasi.RankMigrated {
+		t.Error("scored application marked as migrated")
+	}
+	if verifikasi.AppliedUMR == nil || *verifikasi.AppliedUMR != applied {
+		t.Fatalf("applied UMR = %v, want %d", verifikasi.AppliedUMR, applied)
+	}
+
+	// Raising the wage afterwards would move the household to a better bracket
+	if err := db.Model(&wage).Update("amount", 4000000).Error; err != nil {
+		t.Fatalf("update minimum wage: %v", err)
+	}
+	breakdown, err := explainScore(db, verifikasi, data)
+	if err != nil {
+		t.Fatalf("explainScore: %v", err)
+	}
+	if breakdown.Household.UMR != float64(applied) || breakdown.Rank != verifikasi.MeritRank || !breakdown.Reproducible {
+		t.Errorf("explained with UMR %v and rank %d (reproducible %v), want UMR %d and reproducible stored rank %d",
+			breakdown.Household.UMR, breakdown.Rank, breakdown.Reproducible, applied, verifikasi.MeritRank)
+	}
+}
+
+func TestMigrateScoresKeepsLegacyRanks(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.Exec("ALTER TABLE verifikasis ADD COLUMN data_completeness_rank integer").Error; err != nil {
+		t.Fatalf("add legacy column: %v", err)
+	}
+	// An approved application whose rank may or may not have been typed in
+	approved := models.Verifikasi{UserID: 4, Status: models.StatusApproved, VerifikatorID: 2}
+	if err := db.Omit("UserData").Create(&approved).Error; err != nil {
+		t.Fatalf("create verification: %v", err)
+	}
+	db.Model(&approved).Update("data_completeness_rank", 9)
+
+	SeedScoringRules()
+	MigrateScores()
+
+	var migrated models.Verifikasi
+	if err := db.First(&migrated, approved.ID).Error; err != nil {
+		t.Fatalf("reload verification: %v", err)
+	}
+	baseline, err := baselineRuleSet(db)
+	if err != nil {
+		t.Fatalf("baselineRuleSet: %v", err)
+	}
+	if migrated.MeritRank != 9 || migrated.OverrideRank != 0 || migrated.ScoringVersion != baseline.Version {
+		t.Errorf("merit rank %d, override %d, version %d; want 9, 0 and baseline %d",
+			migrated.MeritRank, migrated.OverrideRank, migrated.ScoringVersion, baseline.Version)
+	}
+	// The baseline rules do not reproduce the old rank, and the breakdown says so
+	breakdown, err := explainScore(db, migrated, verifikasiToData(migrated))
+	if err != nil {
+		t.Fatalf("explainScore: %v", err)
+	}
+	if !migrated.RankMigrated || breakdown.Reproducible || breakdown.MeritRank != 9 {
+		t.Errorf("migrated %v, reproducible %v, merit rank %d; want a migrated, not reproducible rank 9",
+			migrated.RankMigrated, breakdown.Reproducible, breakdown.MeritRank)
+	}
+	var overrides int64
+	db.Model(&models.RankOverride{}).Count(&overrides)
+	if overrides != 0 {
+		t.Errorf("%d rank overrides made up from legacy ranks", overrides)
+	}
+
+	// New applications are scored on merit and need only
+	if _, rules, _ := activeScoringRules(db, baseline.ProfileID); !reflect.DeepEqual(rules, scoring.MeritRules()) {
+		t.Errorf("active rules = %+v, want the merit-only rules", rules)
+	}
+}
+
+func TestApprovalKeepsStoredScore(t *testing.T) {
+	db := setupTestDB(t)
+	SeedScoringRules()
+	baseline, err := baselineRuleSet(db)
+	if err != nil {
+		t.Fatalf("baselineRuleSet: %v", err)
+	}
+	umr := int64(2000000)
+	v := models.Verifikasi{
+		UserID: 4, Status: models.StatusPending,
+		DataCompleteness: 70, MeritScore: 62, MeritRank: 6,
+		ScoringVersion: baseline.Version, AppliedUMR: &umr,
+	}
+	if err := db.Omit("UserData").Create(&v).Error; err != nil {
+		t.Fatalf("create verification: %v", err)
+	}
+	// Rules activated after submission would score the empty application at rank 1
+	changed := scoring.DefaultRules()
+	changed.Weights = scoring.Weights{Personal: 1}
+	saveTestRules(t, db, changed, true)
+
+	w := serve(ApproveVerifikasi, 2, "verifikator", http.MethodPost, `{"message": "Data lengkap"}`,
+		gin.Param{Key: "id", Value: strconv.Itoa(int(v.ID))})
+	if w.Code != http.StatusOK {
+		t.Fatalf("approve: status %d: %s", w.Code, w.Body)
+	}
+
+	var approved models.Verifikasi
+	if err := db.First(&approved, v.ID).Error; err != nil {
+		t.Fatalf("reload verification: %v", err)
+	}
+	if approved.Status != models.StatusApproved || approved.MeritScore != 62 || approved.MeritRank != 6 ||
+		approved.DataCompleteness != 70 || approved.ScoringVersion != baseline.Version ||
+		approved.AppliedUMR == nil || *approved.AppliedUMR != umr {
+		t.Errorf("approved with score %v, rank %d, completeness %d, version %d, UMR %v; want the stored 62, 6, 70, %d and %d",
+			approved.MeritScore, approved.MeritRank, approved.DataCompleteness, approved.ScoringVersion, approved.AppliedUMR, baseline.Version, umr)
+	}
+}
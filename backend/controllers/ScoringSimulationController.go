@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+
+	"sibestie/config"
+	"sibestie/models"
+	"sibestie/tools/scoring"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Eligibility changes of a simulated application
+const (
+	EligibilityGained = "gained"
+	EligibilityLost   = "lost"
+)
+
+// ScoringSimulationRequest proposes rules for a profile, either written out
+// or as a saved version, to compare against the ranks the profile's
+// applications have now. AwardMinRank is the rank from which an
+// application counts as eligible for an award.
+type ScoringSimulationRequest struct {
+	ProfileID    uint           `json:"profile_id"`
+	Rules        *scoring.Rules `json:"rules"`
+	Version      int            `json:"version"`
+	AwardMinRank int            `json:"award_min_rank" binding:"required"`
+}
+
+// RankDistribution counts the applications with one rank
+type RankDistribution struct {
+	Rank     int `json:"rank"`
+	Current  int `json:"current"`
+	Proposed int `json:"proposed"`
+	Change   int `json:"change"`
+}
+
+// SimulatedChange is an application whose rank or award eligibility would
+// change under the proposed rules
+type SimulatedChange struct {
+	VerifikasiID  uint    `json:"verifikasi_id"`
+	UserID        uint    `json:"user_id"`
+	NamaLengkap   string  `json:"nama_lengkap"`
+	BeasiswaID    uint    `json:"beasiswa_id"`
+	Status        string  `json:"status"`
+	CurrentRank   int     `json:"current_rank"`
+	ProposedRank  int     `json:"proposed_rank"`
+	CurrentScore  float64 `json:"current_score"`
+	ProposedScore float64 `json:"proposed_score"`
+	Eligibility   string  `json:"eligibility,omitempty"`
+}
+
+// profileApplications narrows query to the applications scored with a
+// profile: those to scholarships using it and, for the default profile,
+// applications to scholarships without a profile or without a scholarship
+func profileApplications(query *gorm.DB, profile models.ScoringProfile) *gorm.DB {
+	scholarships := query.Session(&gorm.Session{NewDB: true}).Model(&models.Beasiswa{}).Select("id")
+	if profile.Code == models.DefaultScoringProfile {
+		return query.Where("beasiswa_id = 0 OR beasiswa_id IN (?)",
+			scholarships.Where("scoring_profile_id = 0 OR scoring_profile_id = ? OR scoring_profile_id IS NULL", profile.ID))
+	}
+	return query.Where("beasiswa_id IN (?)", scholarships.Where("scoring_profile_id = ?", profile.ID))
+}
+
+// POST /api/scoring/simulate
+func SimulateScoring(c *gin.Context) {
+	var input ScoringSimulationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid simulation: " + err.Error()})
+		return
+	}
+	if (input.Rules == nil) == (input.Version == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Give either rules or a version to simulate"})
+		return
+	}
+	awardMinRank := input.AwardMinRank
+	if awardMinRank < 1 || awardMinRank > scoring.MaxRank {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "award_min_rank must be between 1 and 10"})
+		return
+	}
+
+	profile, ok := findProfile(c, input.ProfileID)
+	if !ok {
+		return
+	}
+	var proposed scoring.Rules
+	var err error
+	if input.Rules != nil {
+		proposed = *input.Rules
+	} else {
+		var ruleSet models.ScoringRuleSet
+		ruleSet, proposed, err = scoringRules(config.DB, input.Version)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Scoring rule set not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("Error loading scoring rules: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load scoring rules"})
+			return
+		}
+		if ruleSet.ProfileID != profile.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Rule set version belongs to another scoring profile"})
+			return
+		}
+	}
+	if err := proposed.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scoring rules: " + err.Error()})
+		return
+	}
+
+	// Only scored applications have a rank to compare with
+	var applications []models.Verifikasi
+	err = profileApplications(withApplicant(config.DB), profile).
+		Where("status <> ? AND anonymized_at IS NULL AND merit_rank > 0", models.StatusWithdrawn).
+		Order("id").
+		Find(&applications).Error
+	if err != nil {
+		log.Printf("Error querying applications for simulation: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query applications"})
+		return
+	}
+
+	distribution := make([]RankDistribution, scoring.MaxRank)
+	for i := range distribution {
+		distribution[i].Rank = i + 1
+	}
+	changes := []SimulatedChange{}
+	var currentEligible, proposedEligible, currentRankSum, proposedRankSum int
+	for _, verifikasi := range applications {
+		applicant, err := scoringApplicant(config.DB, verifikasiToData(verifikasi), verifikasi.AppliedUMR)
+		if err != nil {
+			log.Printf("Error scoring application %d: %v", verifikasi.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to score applications"})
+			return
+		}
+		// The current rank is the one stored, each application scored under
+		// its own rule version. A rank override stays in force under any rules.
+		currentRank := verifikasi.Rank()
+		after := scoring.Score(proposed, applicant)
+		proposedRank := after.Rank
+		if verifikasi.OverrideRank > 0 {
+			proposedRank = verifikasi.OverrideRank
+		}
+
+		distribution[currentRank-1].Current++
+		distribution[proposedRank-1].Proposed++
+		currentRankSum += currentRank
+		proposedRankSum += proposedRank
+
+		wasEligible, isEligible := currentRank >= awardMinRank, proposedRank >= awardMinRank
+		if wasEligible {
+			currentEligible++
+		}
+		if isEligible {
+			proposedEligible++
+		}
+		if currentRank == proposedRank {
+			continue
+		}
+
+		change := SimulatedChange{
+			VerifikasiID:  verifikasi.ID,
+			UserID:        verifikasi.UserID,
+			NamaLengkap:   verifikasi.UserData.FullName,
+			BeasiswaID:    verifikasi.BeasiswaID,
+			Status:        verifikasi.Status,
+			CurrentRank:   currentRank,
+			ProposedRank:  proposedRank,
+			CurrentScore:  verifikasi.MeritScore,
+			ProposedScore: after.Total * 100,
+		}
+		switch {
+		case isEligible && !wasEligible:
+			change.Eligibility = EligibilityGained
+		case wasEligible && !isEligible:
+			change.Eligibility = EligibilityLost
+		}
+		changes = append(changes, change)
+	}
+	for i := range distribution {
+		distribution[i].Change = distribution[i].Proposed - distribution[i].Current
+	}
+
+	// Eligibility flips first, then the largest rank changes
+	sort.SliceStable(changes, func(i, j int) bool {
+		if (changes[i].Eligibility != "") != (changes[j].Eligibility != "") {
+			return changes[i].Eligibility != ""
+		}
+		return abs(changes[i].ProposedRank-changes[i].CurrentRank) > abs(changes[j].ProposedRank-changes[j].CurrentRank)
+	})
+
+	averageRank := gin.H{"current": 0.0, "proposed": 0.0}
+	if len(applications) > 0 {
+		averageRank["current"] = float64(currentRankSum) / float64(len(applications))
+		averageRank["proposed"] = float64(proposedRankSum) / float64(len(applications))
+	}
+	flips := 0
+	for _, change := range changes {
+		if change.Eligibility != "" {
+			flips++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profile":           profile,
+		"applications":      len(applications),
+		"award_min_rank":    awardMinRank,
+		"distribution":      distribution,
+		"average_rank":      averageRank,
+		"eligible":          gin.H{"current": currentEligible, "proposed": proposedEligible},
+		"rank_changes":      len(changes),
+		"eligibility_flips": flips,
+		"changes":           changes,
+	})
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
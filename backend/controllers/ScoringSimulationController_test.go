@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sibestie/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func simulate(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/scoring/simulate", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SimulateScoring(c)
+	return w
+}
+
+func TestSimulateScoringComparesStoredRanks(t *testing.T) {
+	db := setupTestDB(t)
+	SeedScoringRules()
+	baseline, err := baselineRuleSet(db)
+	if err != nil {
+		t.Fatalf("baselineRuleSet: %v", err)
+	}
+
+	// Neither application gives any data, so any rules score them at rank 1
+	scored := models.Verifikasi{UserID: 4, Status: models.StatusPending, MeritScore: 83, MeritRank: 8}
+	overridden := models.Verifikasi{UserID: 5, Status: models.StatusPending, MeritScore: 20, MeritRank: 2, OverrideRank: 9}
+	for _, v := range []*models.Verifikasi{&scored, &overridden} {
+		if err := db.Omit("UserData").Create(v).Error; err != nil {
+			t.Fatalf("create verification: %v", err)
+		}
+	}
+
+	if w := simulate(t, `{"version": 1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("simulation without award_min_rank: status %d, want 400", w.Code)
+	}
+
+	w := simulate(t, fmt.Sprintf(`{"version": %d, "award_min_rank": 7}`, baseline.Version))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var response struct {
+		Eligible struct{ Current, Proposed int }
+		Changes  []SimulatedChange
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if response.Eligible.Current != 2 || response.Eligible.Proposed != 1 {
+		t.Errorf("eligible %+v, want 2 now and 1 under the proposed rules", response.Eligible)
+	}
+	if len(response.Changes) != 1 {
+		t.Fatalf("changes = %+v, want only the application without an override", response.Changes)
+	}
+	change := response.Changes[0]
+	if change.VerifikasiID != scored.ID || change.CurrentRank != 8 || change.ProposedRank != 1 ||
+		change.CurrentScore != 83 || change.Eligibility != EligibilityLost {
+		t.Errorf("change = %+v, want rank 8 (score 83) losing eligibility at rank 1", change)
+	}
+}
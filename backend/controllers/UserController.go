@@ -24,7 +24,7 @@ func GetVerificationUsers(c *gin.Context) {
 	var verifications []models.Verifikasi
 
 	// Get all verification records
-	if err := config.DB.Find(&verifications).Error; err != nil {
+	if err := config.DB.Preload("UserData").Find(&verifications).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data verifikasi"})
 		return
 	}
@@ -42,7 +42,7 @@ func GetVerificationUsers(c *gin.Context) {
 		response = append(response, map[string]interface{}{
 			"id":           v.ID,
 			"user_id":      v.UserID,
-			"nama_lengkap": v.NamaLengkap,
+			"nama_lengkap": v.UserData.FullName,
 			"email":        user.Email,
 			"status":       v.Status,
 			"created_at":   v.CreatedAt,
@@ -1,18 +1,22 @@
 package controllers
 
 import (
-	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"sibestie/config"
 	"sibestie/models"
+	"sibestie/tools/identity"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Test endpoint for connection testing
@@ -31,10 +35,12 @@ func TestConnection(c *gin.Context) {
 type VerifikasiData struct {
 	ID           int    `json:"id"`
 	UserID       int    `json:"user_id"`
+	BeasiswaID   uint   `json:"beasiswa_id"`
 	NIK          string `json:"nik"`
 	NISN         string `json:"nisn"`
 	NamaLengkap  string `json:"nama_lengkap"`
 	TanggalLahir string `json:"tanggal_lahir"`
+	JenisKelamin string `json:"jenis_kelamin"`
 	TempatLahir  string `json:"tempat_lahir"`
 	Alamat       string `json:"alamat"`
 	FotoKTP      string `json:"foto_ktp"`
@@ -55,29 +61,39 @@ type VerifikasiData struct {
 	Telegram  string `json:"telegram"`
 	Other     string `json:"other"`
 
-	NamaIbu        string `json:"nama_ibu"`
-	PekerjaanIbu   string `json:"pekerjaan_ibu"`
-	PendapatanIbu  int    `json:"pendapatan_ibu"`
-	NamaAyah       string `json:"nama_ayah"`
-	PekerjaanAyah  string `json:"pekerjaan_ayah"`
-	PendapatanAyah int    `json:"pendapatan_ayah"`
-	AlamatKeluarga string `json:"alamat_keluarga"`
-	FotoKK         string `json:"foto_kk"`
-	Saudara        string `json:"saudara"`
-	AsalSekolah    string `json:"asal_sekolah"`
-	TahunLulus     string `json:"tahun_lulus"`
-	NilaiSemester1 string `json:"nilai_semester_1"`
-	NilaiSemester2 string `json:"nilai_semester_2"`
-	FotoIjazah     string `json:"foto_ijazah"`
-	FotoSKL        string `json:"foto_skl"`
-	FotoSertifikat string `json:"foto_sertifikat"`
-	Status         string `json:"status"`
+	NamaIbu        string      `json:"nama_ibu"`
+	PekerjaanIbu   string      `json:"pekerjaan_ibu"`
+	PendapatanIbu  int         `json:"pendapatan_ibu"`
+	NamaAyah       string      `json:"nama_ayah"`
+	PekerjaanAyah  string      `json:"pekerjaan_ayah"`
+	PendapatanAyah int         `json:"pendapatan_ayah"`
+	AlamatKeluarga string      `json:"alamat_keluarga"`
+	FotoKK         string      `json:"foto_kk"`
+	Saudara        SiblingList `json:"saudara"`
+	AsalSekolah    string      `json:"asal_sekolah"`
+	TahunLulus     string      `json:"tahun_lulus"`
+	NilaiSemester1 string      `json:"nilai_semester_1"`
+	NilaiSemester2 string      `json:"nilai_semester_2"`
+	// Riwayat nilai semua semester; menggantikan nilai_semester_1/2
+	NilaiSemester  []SemesterGradeData `json:"nilai_semester"`
+	FotoIjazah     string              `json:"foto_ijazah"`
+	FotoSKL        string              `json:"foto_skl"`
+	FotoSertifikat string              `json:"foto_sertifikat"`
+	Status         string              `json:"status"`
+
+	// Uploaded documents referenced by ID (see POST /api/documents)
+	Documents []DocumentRef `json:"documents,omitempty"`
 
 	// Verifikator Feedback
-	VerifikatorMessage   string  `json:"verifikator_message"`
-	DataCompletenessRank int     `json:"data_completeness_rank"`
-	VerifikatorID        int     `json:"verifikator_id"`
-	VerifiedAt           *string `json:"verified_at"`
+	VerifikatorMessage string  `json:"verifikator_message"`
+	VerifikatorID      int     `json:"verifikator_id"`
+	VerifiedAt         *string `json:"verified_at"`
+
+	// Computed data completeness (0-100) and merit rank, and the rank the
+	// application is judged by after any verifikator override
+	DataCompleteness int `json:"data_completeness"`
+	MeritRank        int `json:"merit_rank"`
+	Rank             int `json:"rank"`
 
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
@@ -91,150 +107,26 @@ type VerificationStatus struct {
 
 // VerificationStats represents verification statistics
 type VerificationStats struct {
-	TotalUsers    int `json:"total_users"`
-	VerifiedUsers int `json:"verified_users"`
-	PendingUsers  int `json:"pending_users"`
-	RejectedUsers int `json:"rejected_users"`
+	TotalUsers     int `json:"total_users"`
+	VerifiedUsers  int `json:"verified_users"`
+	PendingUsers   int `json:"pending_users"`
+	RejectedUsers  int `json:"rejected_users"`
+	WithdrawnUsers int `json:"withdrawn_users"`
+
+	RejectionReasons []RejectionReasonCount `json:"rejection_reasons"`
 }
 
 // VerificationFeedbackRequest represents the feedback data from verifikator
 type VerificationFeedbackRequest struct {
-	Message              string  `json:"message" binding:"required"`
-	DataCompletenessRank int     `json:"data_completeness_rank"`
-	PersonalMatch        float64 `json:"personal_match"`
-	AcademicMatch        float64 `json:"academic_match"`
-	FamilyMatch          float64 `json:"family_match"`
-}
-
-// CalculateDataCompletenessRank calculates the completeness rank based on weighted criteria
-func CalculateDataCompletenessRank(data VerifikasiData) int {
-	totalScore := 0.0
-
-	// 1. Data Personal (10%)
-	personalScore := calculatePersonalDataScore(data)
-	totalScore += personalScore * 0.10
-
-	// 2. Data Akademik (40%)
-	academicScore := calculateAcademicDataScore(data)
-	totalScore += academicScore * 0.40
-
-	// 3. Data Ekonomi Keluarga (50%)
-	familyScore := calculateFamilyDataScore(data)
-	totalScore += familyScore * 0.50
-
-	// Convert to 1-10 scale
-	rank := int(totalScore * 10)
-	if rank < 1 {
-		rank = 1
-	} else if rank > 10 {
-		rank = 10
-	}
-
-	return rank
-}
-
-// calculatePersonalDataScore calculates score for personal data (10% weight)
-func calculatePersonalDataScore(data VerifikasiData) float64 {
-	personalFields := []bool{
-		data.NIK != "",
-		data.NISN != "",
-		data.NamaLengkap != "",
-		data.TanggalLahir != "",
-		data.TempatLahir != "",
-		data.Alamat != "",
-		data.FotoKTP != "",
-		data.NomorTelepon != "",
-		data.Email != "",
-	}
-	filledCount := 0
-	for _, filled := range personalFields {
-		if filled {
-			filledCount++
-		}
-	}
-	fieldWeight := 1.0 / float64(len(personalFields))
-	return float64(filledCount) * fieldWeight
-}
-
-// calculateAcademicDataScore calculates score for academic data (40% weight)
-func calculateAcademicDataScore(data VerifikasiData) float64 {
-	// 5 field: asal_sekolah, tahun_lulus, nilai_semester_1, nilai_semester_2, foto_ijazah
-	fieldCount := 5.0
-	fieldWeight := 1.0 / fieldCount
-	filled := 0.0
-
-	if data.AsalSekolah != "" {
-		filled += fieldWeight
-	}
-	if data.TahunLulus != "" {
-		filled += fieldWeight
-	}
-	// Nilai semester 1
-	if data.NilaiSemester1 != "" {
-		if nilai, err := strconv.ParseFloat(data.NilaiSemester1, 64); err == nil {
-			if nilai >= 8.0 {
-				filled += fieldWeight
-			} else if nilai >= 6.0 {
-				filled += fieldWeight * 0.5
-			}
-		}
-	}
-	// Nilai semester 2
-	if data.NilaiSemester2 != "" {
-		if nilai, err := strconv.ParseFloat(data.NilaiSemester2, 64); err == nil {
-			if nilai >= 8.0 {
-				filled += fieldWeight
-			} else if nilai >= 6.0 {
-				filled += fieldWeight * 0.5
-			}
-		}
-	}
-	if data.FotoIjazah != "" {
-		filled += fieldWeight
-	}
-	return filled
-}
-
-// calculateFamilyDataScore calculates score for family economic data (50% weight)
-func calculateFamilyDataScore(data VerifikasiData) float64 {
-	// 5 field: pekerjaan_ibu, pendapatan_ibu, pekerjaan_ayah, pendapatan_ayah, alamat_keluarga
-	fieldCount := 5.0
-	fieldWeight := 1.0 / fieldCount
-	filled := 0.0
-
-	if data.PekerjaanIbu != "" {
-		filled += fieldWeight
-	}
-	// Pendapatan Ibu
-	if data.PendapatanIbu > 0 {
-		income := float64(data.PendapatanIbu)
-		if income < 1000000 {
-			filled += fieldWeight
-		} else if income >= 1000000 && income <= 2000000 {
-			filled += fieldWeight * 0.6
-		} else if income > 2000000 && income <= 5000000 {
-			filled += fieldWeight * 0.3
-		}
-		// >5jt tidak dapat skor
-	}
-	if data.PekerjaanAyah != "" {
-		filled += fieldWeight
-	}
-	// Pendapatan Ayah
-	if data.PendapatanAyah > 0 {
-		income := float64(data.PendapatanAyah)
-		if income < 1000000 {
-			filled += fieldWeight
-		} else if income >= 1000000 && income <= 2000000 {
-			filled += fieldWeight * 0.6
-		} else if income > 2000000 && income <= 5000000 {
-			filled += fieldWeight * 0.3
-		}
-	}
-	if data.AlamatKeluarga != "" {
-		filled += fieldWeight
-	}
-	return filled
+	Message       string   `json:"message" binding:"required"`
+	PersonalMatch float64  `json:"personal_match"`
+	AcademicMatch float64  `json:"academic_match"`
+	FamilyMatch   float64  `json:"family_match"`
+	ReasonCodes   []string `json:"reason_codes"`
+	// Optional rank set by hand in place of the computed merit rank; a
+	// reason is required with it
+	RankOverride   int    `json:"rank_override"`
+	OverrideReason string `json:"override_reason"`
 }
 
 // POST /api/verifikasi
@@ -242,16 +134,23 @@ func SubmitVerifikasi(c *gin.Context) {
 	var data VerifikasiData
 	if err := c.ShouldBindJSON(&data); err != nil {
 		log.Printf("Error binding JSON: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid submission data", "fields": bindingFieldErrors(err)})
 		return
 	}
+	if fieldErrors := validateSubmission(&data); len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid submission data", "fields": fieldErrors})
+		return
+	}
+	// Applicants submit for themselves; a user_id in the body is ignored
+	data.UserID = int(currentUserID(c))
 
-	// Check if user already has a verification record
+	// Check if user already applied for this scholarship; applications for
+	// other scholarships may share the same uploaded documents
 	var existingVerifikasi models.Verifikasi
-	result := config.DB.Where("user_id = ?", data.UserID).First(&existingVerifikasi)
+	result := config.DB.Where("user_id = ? AND beasiswa_id = ?", data.UserID, data.BeasiswaID).First(&existingVerifikasi)
 	if result.Error == nil {
 		// User already has a verification record
-		c.JSON(http.StatusConflict, gin.H{"error": "User already has a verification record"})
+		c.JSON(http.StatusConflict, gin.H{"error": "User already has a verification record for this scholarship"})
 		return
 	} else if result.Error.Error() != "record not found" {
 		// Database error
@@ -260,100 +159,75 @@ func SubmitVerifikasi(c *gin.Context) {
 		return
 	}
 
-	// Marshal saudara data to ensure it's valid JSON
-	saudaraJSON := data.Saudara
-	var saudaraObj interface{}
-	if err := json.Unmarshal([]byte(data.Saudara), &saudaraObj); err != nil {
-		// If not valid JSON, attempt to fix it
-		saudaraJSON = "[]"
+	// Documents are uploaded separately and referenced by ID
+	if data.FotoKTP != "" || data.FotoKK != "" || data.FotoIjazah != "" || data.FotoSKL != "" || data.FotoSertifikat != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Inline document data is not accepted; upload files to /api/documents and reference them in documents"})
+		return
+	}
+	files, err := resolveDocumentRefs(config.DB, uint(data.UserID), data.Documents)
+	if err != nil {
+		if errors.Is(err, errInvalidDocument) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			log.Printf("Error resolving documents: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve documents"})
+		}
+		return
+	}
+	missing, unexpected, err := checkSubmittedDocuments(config.DB, data.BeasiswaID, files)
+	if err != nil {
+		if errors.Is(err, errUnknownScholarship) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Scholarship not found"})
+		} else {
+			log.Printf("Error checking submitted documents: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check submitted documents"})
+		}
+		return
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Required documents are missing: " + strings.Join(missing, ", "), "missing": missing})
+		return
+	}
+	if len(unexpected) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Documents not requested by this scholarship: " + strings.Join(unexpected, ", "), "unexpected": unexpected})
+		return
+	}
+	for docType, file := range files {
+		setDocumentField(&data, docType, documentURL(file.ID))
 	}
 
-	// Create new verification record using GORM
+	// Applicant data is kept as a normalized snapshot of the submission
 	verifikasi := models.Verifikasi{
-		UserID:         uint(data.UserID),
-		NIK:            data.NIK,
-		NISN:           data.NISN,
-		NamaLengkap:    data.NamaLengkap,
-		TanggalLahir:   data.TanggalLahir,
-		TempatLahir:    data.TempatLahir,
-		Alamat:         data.Alamat,
-		FotoKTP:        data.FotoKTP,
-		NomorTelepon:   data.NomorTelepon,
-		Email:          data.Email,
-		Instagram:      data.Instagram,
-		Facebook:       data.Facebook,
-		Tiktok:         data.Tiktok,
-		Website:        data.Website,
-		LinkedIn:       data.LinkedIn,
-		Twitter:        data.Twitter,
-		Youtube:        data.Youtube,
-		Whatsapp:       data.Whatsapp,
-		Telegram:       data.Telegram,
-		Other:          data.Other,
-		NamaIbu:        data.NamaIbu,
-		PekerjaanIbu:   data.PekerjaanIbu,
-		PendapatanIbu:  data.PendapatanIbu,
-		NamaAyah:       data.NamaAyah,
-		PekerjaanAyah:  data.PekerjaanAyah,
-		PendapatanAyah: data.PendapatanAyah,
-		AlamatKeluarga: data.AlamatKeluarga,
-		FotoKK:         data.FotoKK,
-		Saudara:        saudaraJSON,
-		AsalSekolah:    data.AsalSekolah,
-		TahunLulus:     data.TahunLulus,
-		NilaiSemester1: data.NilaiSemester1,
-		NilaiSemester2: data.NilaiSemester2,
-		FotoIjazah:     data.FotoIjazah,
-		FotoSKL:        data.FotoSKL,
-		FotoSertifikat: data.FotoSertifikat,
-		Status:         "pending",
-	}
-
-	// Calculate automatic data completeness rank
-	verifikasiData := VerifikasiData{
-		UserID:         data.UserID,
-		NIK:            data.NIK,
-		NISN:           data.NISN,
-		NamaLengkap:    data.NamaLengkap,
-		TanggalLahir:   data.TanggalLahir,
-		TempatLahir:    data.TempatLahir,
-		Alamat:         data.Alamat,
-		FotoKTP:        data.FotoKTP,
-		NomorTelepon:   data.NomorTelepon,
-		Email:          data.Email,
-		Instagram:      data.Instagram,
-		Facebook:       data.Facebook,
-		Tiktok:         data.Tiktok,
-		Website:        data.Website,
-		LinkedIn:       data.LinkedIn,
-		Twitter:        data.Twitter,
-		Youtube:        data.Youtube,
-		Whatsapp:       data.Whatsapp,
-		Telegram:       data.Telegram,
-		Other:          data.Other,
-		NamaIbu:        data.NamaIbu,
-		PekerjaanIbu:   data.PekerjaanIbu,
-		PendapatanIbu:  data.PendapatanIbu,
-		NamaAyah:       data.NamaAyah,
-		PekerjaanAyah:  data.PekerjaanAyah,
-		PendapatanAyah: data.PendapatanAyah,
-		AlamatKeluarga: data.AlamatKeluarga,
-		FotoKK:         data.FotoKK,
-		Saudara:        data.Saudara,
-		AsalSekolah:    data.AsalSekolah,
-		TahunLulus:     data.TahunLulus,
-		NilaiSemester1: data.NilaiSemester1,
-		NilaiSemester2: data.NilaiSemester2,
-		FotoIjazah:     data.FotoIjazah,
-		FotoSKL:        data.FotoSKL,
-		FotoSertifikat: data.FotoSertifikat,
-		Status:         "pending",
-	}
-
-	verifikasi.DataCompletenessRank = CalculateDataCompletenessRank(verifikasiData)
+		UserID:     uint(data.UserID),
+		BeasiswaID: data.BeasiswaID,
+		UserData:   applicantFromData(data),
+		Status:     models.StatusPending,
+	}
+
+	// Score the application with the active scoring rules and measure
+	// how complete it is
+	data.Status = models.StatusPending
+	docTypes := make([]string, 0, len(files))
+	for docType := range files {
+		docTypes = append(docTypes, docType)
+	}
+	if err := assessApplication(config.DB, &verifikasi, data, docTypes); err != nil {
+		log.Printf("Error scoring verification data: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to score verification data"})
+		return
+	}
 
 	// Insert the verification data
-	if err := config.DB.Create(&verifikasi).Error; err != nil {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&verifikasi).Error; err != nil {
+			return err
+		}
+		if err := attachDocuments(tx, verifikasi.ID, files); err != nil {
+			return err
+		}
+		return flagDuplicates(tx, &verifikasi)
+	})
+	if err != nil {
 		log.Printf("Error inserting verification data: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save verification data: " + err.Error()})
 		return
@@ -367,7 +241,10 @@ func SubmitVerifikasi(c *gin.Context) {
 func ListPendingVerifikasi(c *gin.Context) {
 	var pendingVerifications []models.Verifikasi
 
-	result := config.DB.Where("status = ?", "pending").Find(&pendingVerifications)
+	// Applications enter review only once every document has a scan verdict
+	result := config.DB.Preload("UserData").Where("status = ?", "pending").
+		Where("id NOT IN (?)", unscannedVerifikasiIDs(config.DB)).
+		Find(&pendingVerifications)
 	if result.Error != nil {
 		log.Printf("Error querying pending verifications: %v", result.Error)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query pending verifications"})
@@ -379,9 +256,11 @@ func ListPendingVerifikasi(c *gin.Context) {
 		response = append(response, map[string]interface{}{
 			"id":           v.ID,
 			"user_id":      v.UserID,
-			"nik":          v.NIK,
-			"nama_lengkap": v.NamaLengkap,
+			"nik":          v.UserData.NIK,
+			"nama_lengkap": v.UserData.FullName,
 			"created_at":   v.CreatedAt,
+
+			"duplicate_suspected": v.DuplicateSuspected,
 		})
 	}
 
@@ -397,7 +276,7 @@ func GetVerifikasiDetail(c *gin.Context) {
 	}
 
 	var verifikasi models.Verifikasi
-	result := config.DB.First(&verifikasi, verifikasiID)
+	result := withApplicant(config.DB).First(&verifikasi, verifikasiID)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Verification data not found"})
@@ -408,117 +287,360 @@ func GetVerifikasiDetail(c *gin.Context) {
 		return
 	}
 
+	role := currentUserRole(c)
+	if verifikasi.UserID != currentUserID(c) && role != "verifikator" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to view this verification"})
+		return
+	}
+
 	// Convert to response format
+	data := verifikasiToData(verifikasi)
+
+	var rejectionReasons []models.VerifikasiRejectionReason
+	if err := config.DB.Where("verifikasi_id = ?", verifikasi.ID).Find(&rejectionReasons).Error; err != nil {
+		log.Printf("Error getting rejection reasons: %v", err)
+	}
+	reasonCodes := make([]string, 0, len(rejectionReasons))
+	for _, r := range rejectionReasons {
+		reasonCodes = append(reasonCodes, r.Code)
+	}
+
+	averageNilai, _ := averageGrade(data.NilaiSemester)
+
+	// Hitung ulang skor pembobotan untuk detail breakdown dengan versi
+	// aturan yang dipakai saat penilaian
+	score, err := explainScore(config.DB, verifikasi, data)
+	if err != nil {
+		log.Printf("Error scoring verification: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to score verification"})
+		return
+	}
+
+	// Documents are never returned inline; the Foto* fields carry short-lived
+	// links for callers allowed to view them and are empty otherwise
+	documents, err := documentsForViewer(config.DB, verifikasi.ID, currentUserID(c), role)
+	if err != nil {
+		log.Printf("Error getting verification documents: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get verification documents"})
+		return
+	}
+	for _, docType := range models.DocumentTypes {
+		setDocumentField(&data, docType, "")
+	}
+	scanStatus, err := documentScanStatus(config.DB, verifikasi.ID)
+	if err != nil {
+		log.Printf("Error checking document scans: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check document scans"})
+		return
+	}
+	for _, doc := range documents {
+		if doc.URL != "" {
+			setDocumentField(&data, doc.Type, doc.URL)
+		}
+	}
+	checklist, checklistSummary, err := documentChecklist(config.DB, verifikasi)
+	if err != nil {
+		log.Printf("Error building document checklist: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build document checklist"})
+		return
+	}
+
+	// Suspected duplicates and rank overrides are only shown to staff
+	var duplicates []DuplicateLink
+	var overrides []models.RankOverride
+	duplicateSuspected := false
+	if role == "verifikator" || role == "admin" {
+		duplicateSuspected = verifikasi.DuplicateSuspected
+		duplicates, err = verifikasiDuplicates(config.DB, verifikasi.ID)
+		if err != nil {
+			log.Printf("Error loading duplicate flags: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load duplicate flags"})
+			return
+		}
+		overrides, err = rankOverrides(config.DB, verifikasi.ID)
+		if err != nil {
+			log.Printf("Error loading rank overrides: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load rank overrides"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":                  data.ID,
+		"user_id":             data.UserID,
+		"beasiswa_id":         verifikasi.BeasiswaID,
+		"nik":                 data.NIK,
+		"nisn":                data.NISN,
+		"nama_lengkap":        data.NamaLengkap,
+		"tanggal_lahir":       data.TanggalLahir,
+		"jenis_kelamin":       data.JenisKelamin,
+		"tempat_lahir":        data.TempatLahir,
+		"alamat":              data.Alamat,
+		"foto_ktp":            data.FotoKTP,
+		"nomor_telepon":       data.NomorTelepon,
+		"email":               data.Email,
+		"instagram":           data.Instagram,
+		"facebook":            data.Facebook,
+		"tiktok":              data.Tiktok,
+		"website":             data.Website,
+		"linkedin":            data.LinkedIn,
+		"twitter":             data.Twitter,
+		"youtube":             data.Youtube,
+		"whatsapp":            data.Whatsapp,
+		"telegram":            data.Telegram,
+		"other":               data.Other,
+		"nama_ibu":            data.NamaIbu,
+		"pekerjaan_ibu":       data.PekerjaanIbu,
+		"pendapatan_ibu":      data.PendapatanIbu,
+		"nama_ayah":           data.NamaAyah,
+		"pekerjaan_ayah":      data.PekerjaanAyah,
+		"pendapatan_ayah":     data.PendapatanAyah,
+		"alamat_keluarga":     data.AlamatKeluarga,
+		"foto_kk":             data.FotoKK,
+		"saudara":             data.Saudara,
+		"jumlah_tanggungan":   data.Saudara.Dependents(),
+		"rumah_tangga":        score.Household,
+		"asal_sekolah":        data.AsalSekolah,
+		"tahun_lulus":         data.TahunLulus,
+		"nilai_semester_1":    data.NilaiSemester1,
+		"nilai_semester_2":    data.NilaiSemester2,
+		"nilai_semester":      data.NilaiSemester,
+		"rata_rata_nilai":     averageNilai,
+		"foto_ijazah":         data.FotoIjazah,
+		"foto_skl":            data.FotoSKL,
+		"foto_sertifikat":     data.FotoSertifikat,
+		"status":              data.Status,
+		"verifikator_message": data.VerifikatorMessage,
+		"rejection_reasons":   reasonCodes,
+		"documents":           documents,
+		"scan_status":         scanStatus,
+		"document_checklist":  checklist,
+		"checklist_summary":   checklistSummary,
+		"duplicate_suspected": duplicateSuspected,
+		"duplicates":          duplicates,
+		"data_completeness":   data.DataCompleteness,
+		"merit_score":         verifikasi.MeritScore,
+		"merit_rank":          data.MeritRank,
+		"override_rank":       verifikasi.OverrideRank,
+		"rank_overrides":      overrides,
+		"rank":                data.Rank,
+		"verifikator_id":      data.VerifikatorID,
+		"verified_at":         data.VerifiedAt,
+		"created_at":          data.CreatedAt,
+		"updated_at":          data.UpdatedAt,
+		// Tambahan breakdown pembobotan
+		"personal_score":  score.Personal * 100.0,
+		"academic_score":  score.Academic * 100.0,
+		"family_score":    score.Family * 100.0,
+		"scoring_version": verifikasi.ScoringVersion,
+		"score_breakdown": score,
+	})
+}
+
+// Verification decisions
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+var (
+	errCannotDecide       = errors.New("verification can no longer be decided")
+	errNotAssigned        = errors.New("verification is assigned to another verifikator")
+	errConflictOfInterest = errors.New("verifikator has a declared conflict of interest with this applicant")
+)
+
+// verifikasiToData converts a verification record, loaded withApplicant,
+// to its API representation
+func verifikasiToData(verifikasi models.Verifikasi) VerifikasiData {
+	applicant := verifikasi.UserData
+	social := applicant.SocialMedia
+	family := applicant.Family
+	academic := applicant.Academic
+
 	data := VerifikasiData{
-		ID:                   int(verifikasi.ID),
-		UserID:               int(verifikasi.UserID),
-		NIK:                  verifikasi.NIK,
-		NISN:                 verifikasi.NISN,
-		NamaLengkap:          verifikasi.NamaLengkap,
-		TanggalLahir:         verifikasi.TanggalLahir,
-		TempatLahir:          verifikasi.TempatLahir,
-		Alamat:               verifikasi.Alamat,
-		FotoKTP:              verifikasi.FotoKTP,
-		NomorTelepon:         verifikasi.NomorTelepon,
-		Email:                verifikasi.Email,
-		Instagram:            verifikasi.Instagram,
-		Facebook:             verifikasi.Facebook,
-		Tiktok:               verifikasi.Tiktok,
-		Website:              verifikasi.Website,
-		LinkedIn:             verifikasi.LinkedIn,
-		Twitter:              verifikasi.Twitter,
-		Youtube:              verifikasi.Youtube,
-		Whatsapp:             verifikasi.Whatsapp,
-		Telegram:             verifikasi.Telegram,
-		Other:                verifikasi.Other,
-		NamaIbu:              verifikasi.NamaIbu,
-		PekerjaanIbu:         verifikasi.PekerjaanIbu,
-		PendapatanIbu:        verifikasi.PendapatanIbu,
-		NamaAyah:             verifikasi.NamaAyah,
-		PekerjaanAyah:        verifikasi.PekerjaanAyah,
-		PendapatanAyah:       verifikasi.PendapatanAyah,
-		AlamatKeluarga:       verifikasi.AlamatKeluarga,
-		FotoKK:               verifikasi.FotoKK,
-		Saudara:              verifikasi.Saudara,
-		AsalSekolah:          verifikasi.AsalSekolah,
-		TahunLulus:           verifikasi.TahunLulus,
-		NilaiSemester1:       verifikasi.NilaiSemester1,
-		NilaiSemester2:       verifikasi.NilaiSemester2,
-		FotoIjazah:           verifikasi.FotoIjazah,
-		FotoSKL:              verifikasi.FotoSKL,
-		FotoSertifikat:       verifikasi.FotoSertifikat,
-		Status:               verifikasi.Status,
-		VerifikatorMessage:   verifikasi.VerifikatorMessage,
-		DataCompletenessRank: verifikasi.DataCompletenessRank,
-		VerifikatorID:        int(verifikasi.VerifikatorID),
-		CreatedAt:            verifikasi.CreatedAt.Format("2006-01-02 15:04:05"),
-	}
-
-	// Handle VerifiedAt field
+		ID:                 int(verifikasi.ID),
+		UserID:             int(verifikasi.UserID),
+		BeasiswaID:         verifikasi.BeasiswaID,
+		NIK:                applicant.NIK,
+		NISN:               applicant.NISN,
+		NamaLengkap:        applicant.FullName,
+		JenisKelamin:       applicant.Gender,
+		TempatLahir:        applicant.BirthPlace,
+		Alamat:             applicant.Address,
+		NomorTelepon:       applicant.NomorTelepon,
+		Email:              applicant.Email,
+		Instagram:          social.Instagram,
+		Facebook:           social.Facebook,
+		Tiktok:             social.Tiktok,
+		Website:            social.Website,
+		LinkedIn:           social.LinkedIn,
+		Twitter:            social.Twitter,
+		Youtube:            social.Youtube,
+		Whatsapp:           social.Whatsapp,
+		Telegram:           social.Telegram,
+		Other:              social.Other,
+		NamaIbu:            family.MotherName,
+		PekerjaanIbu:       family.MotherJob,
+		PendapatanIbu:      family.MotherSalary,
+		NamaAyah:           family.FatherName,
+		PekerjaanAyah:      family.FatherJob,
+		PendapatanAyah:     family.FatherSalary,
+		AlamatKeluarga:     family.Address,
+		Saudara:            siblingsFromChildren(family.Children),
+		AsalSekolah:        academic.SchoolName,
+		TahunLulus:         academic.GraduationYear,
+		Status:             verifikasi.Status,
+		VerifikatorMessage: verifikasi.VerifikatorMessage,
+		VerifikatorID:      int(verifikasi.VerifikatorID),
+		DataCompleteness:   verifikasi.DataCompleteness,
+		MeritRank:          verifikasi.MeritRank,
+		Rank:               verifikasi.Rank(),
+		CreatedAt:          verifikasi.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if applicant.BirthDate != nil {
+		data.TanggalLahir = applicant.BirthDate.Format(identity.DateLayout)
+	}
+	data.NilaiSemester = gradesFromHistory(academic.Grades)
+	for _, grade := range data.NilaiSemester {
+		switch grade.Semester {
+		case 1:
+			data.NilaiSemester1 = strconv.FormatFloat(*grade.Nilai, 'f', -1, 64)
+		case 2:
+			data.NilaiSemester2 = strconv.FormatFloat(*grade.Nilai, 'f', -1, 64)
+		}
+	}
+	for _, link := range verifikasi.Documents {
+		setDocumentField(&data, link.DocType, documentURL(link.SourceFileID))
+	}
+
 	if verifikasi.VerifiedAt != nil {
 		verifiedAtStr := verifikasi.VerifiedAt.Format("2006-01-02 15:04:05")
 		data.VerifiedAt = &verifiedAtStr
 	}
+	return data
+}
 
-	// Hitung ulang skor pembobotan untuk detail breakdown
-	personalScore := calculatePersonalDataScore(data) * 100.0
-	academicScore := calculateAcademicDataScore(data) * 100.0
-	familyScore := calculateFamilyDataScore(data) * 100.0
+// setDocumentField stores a document reference in the matching Foto* field
+func setDocumentField(data *VerifikasiData, docType, value string) {
+	switch docType {
+	case models.DocKTP:
+		data.FotoKTP = value
+	case models.DocKK:
+		data.FotoKK = value
+	case models.DocIjazah:
+		data.FotoIjazah = value
+	case models.DocSKL:
+		data.FotoSKL = value
+	case models.DocSertifikat:
+		data.FotoSertifikat = value
+	}
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"id":                     data.ID,
-		"user_id":                data.UserID,
-		"nik":                    data.NIK,
-		"nisn":                   data.NISN,
-		"nama_lengkap":           data.NamaLengkap,
-		"tanggal_lahir":          data.TanggalLahir,
-		"tempat_lahir":           data.TempatLahir,
-		"alamat":                 data.Alamat,
-		"foto_ktp":               data.FotoKTP,
-		"nomor_telepon":          data.NomorTelepon,
-		"email":                  data.Email,
-		"instagram":              data.Instagram,
-		"facebook":               data.Facebook,
-		"tiktok":                 data.Tiktok,
-		"website":                data.Website,
-		"linkedin":               data.LinkedIn,
-		"twitter":                data.Twitter,
-		"youtube":                data.Youtube,
-		"whatsapp":               data.Whatsapp,
-		"telegram":               data.Telegram,
-		"other":                  data.Other,
-		"nama_ibu":               data.NamaIbu,
-		"pekerjaan_ibu":          data.PekerjaanIbu,
-		"pendapatan_ibu":         data.PendapatanIbu,
-		"nama_ayah":              data.NamaAyah,
-		"pekerjaan_ayah":         data.PekerjaanAyah,
-		"pendapatan_ayah":        data.PendapatanAyah,
-		"alamat_keluarga":        data.AlamatKeluarga,
-		"foto_kk":                data.FotoKK,
-		"saudara":                data.Saudara,
-		"asal_sekolah":           data.AsalSekolah,
-		"tahun_lulus":            data.TahunLulus,
-		"nilai_semester_1":       data.NilaiSemester1,
-		"nilai_semester_2":       data.NilaiSemester2,
-		"foto_ijazah":            data.FotoIjazah,
-		"foto_skl":               data.FotoSKL,
-		"foto_sertifikat":        data.FotoSertifikat,
-		"status":                 data.Status,
-		"verifikator_message":    data.VerifikatorMessage,
-		"data_completeness_rank": data.DataCompletenessRank,
-		"verifikator_id":         data.VerifikatorID,
-		"verified_at":            data.VerifiedAt,
-		"created_at":             data.CreatedAt,
-		"updated_at":             data.UpdatedAt,
-		// Tambahan breakdown pembobotan
-		"personal_score": personalScore,
-		"academic_score": academicScore,
-		"family_score":   familyScore,
-	})
+// decideVerifikasi applies an approve or reject decision to a verification
+// record inside tx. It enforces the status transition, assignment and
+// conflict-of-interest rules shared by the single and bulk handlers.
+func decideVerifikasi(tx *gorm.DB, verifikasi *models.Verifikasi, decision string, feedback VerificationFeedbackRequest, reasons []models.RejectionReason, verifikatorID uint, isAdmin bool) error {
+	target := models.StatusApproved
+	if decision == DecisionReject {
+		target = models.StatusRejected
+	}
+	if !models.CanTransition(verifikasi.Status, target) || verifikasi.AnonymizedAt != nil {
+		return errCannotDecide
+	}
+	if verifikasi.AssignedVerifikatorID != 0 && verifikasi.AssignedVerifikatorID != verifikatorID && !isAdmin {
+		return errNotAssigned
+	}
+
+	conflict, err := findConflict(tx, verifikatorID, *verifikasi)
+	if err != nil {
+		return err
+	}
+	if conflict != nil {
+		return errConflictOfInterest
+	}
+
+	scanStatus, err := documentScanStatus(tx, verifikasi.ID)
+	if err != nil {
+		return err
+	}
+	if scanStatus == models.ScanPending {
+		return errScanIncomplete
+	}
+	if scanStatus == models.ScanInfected && decision == DecisionApprove {
+		return errDocumentsInfected
+	}
+	if decision == DecisionApprove {
+		invalid, err := invalidRequiredDocuments(tx, verifikasi.ID)
+		if err != nil {
+			return err
+		}
+		if len(invalid) > 0 {
+			return fmt.Errorf("%w: %s", errRequiredDocumentInvalid, strings.Join(invalid, ", "))
+		}
+	}
+
+	// Update verification status and feedback. The score stays as it was
+	// stored at submission, under the rules and minimum wage in force then.
+	now := time.Now()
+	verifikasi.Status = target
+	verifikasi.VerifikatorMessage = feedback.Message
+	verifikasi.VerifikatorID = verifikatorID
+	verifikasi.VerifiedAt = &now
+	// Simpan input manual verifikator
+	verifikasi.PersonalMatch = feedback.PersonalMatch
+	verifikasi.AcademicMatch = feedback.AcademicMatch
+	verifikasi.FamilyMatch = feedback.FamilyMatch
+
+	if err := tx.Omit(clause.Associations).Save(verifikasi).Error; err != nil {
+		return err
+	}
+	if feedback.RankOverride != 0 {
+		if err := overrideRank(tx, verifikasi, feedback.RankOverride, feedback.OverrideReason, verifikatorID); err != nil {
+			return err
+		}
+	}
+
+	if decision == DecisionApprove {
+		// A verification rejected earlier keeps no rejection reasons
+		if err := saveRejectionReasons(tx, verifikasi.ID, nil); err != nil {
+			return err
+		}
+		return recordAudit(tx, verifikatorID, "verifikasi.approve", "verifikasi", verifikasi.ID, feedback.Message)
+	}
+
+	if err := saveRejectionReasons(tx, verifikasi.ID, reasons); err != nil {
+		return err
+	}
+	reasonCodes := make([]string, 0, len(reasons))
+	for _, r := range reasons {
+		reasonCodes = append(reasonCodes, r.Code)
+	}
+	return recordAudit(tx, verifikatorID, "verifikasi.reject", "verifikasi", verifikasi.ID,
+		fmt.Sprintf("reasons=%s message=%q", strings.Join(reasonCodes, ","), feedback.Message))
 }
 
-// POST /api/verifikasi/:id/approve
-func ApproveVerifikasi(c *gin.Context) {
+// decisionErrorStatus maps a decideVerifikasi error to an HTTP status and message
+func decisionErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errCannotDecide):
+		return http.StatusConflict, "Withdrawn or anonymized verifications cannot be approved or rejected"
+	case errors.Is(err, errNotAssigned):
+		return http.StatusForbidden, "Verification is assigned to another verifikator"
+	case errors.Is(err, errConflictOfInterest):
+		return http.StatusConflict, "Verifikator has a declared conflict of interest with this applicant"
+	case errors.Is(err, errScanIncomplete):
+		return http.StatusConflict, "Documents are still being scanned for malware"
+	case errors.Is(err, errDocumentsInfected):
+		return http.StatusConflict, "Verification has documents that failed the malware scan"
+	case errors.Is(err, errRequiredDocumentInvalid):
+		return http.StatusConflict, "Cannot approve: " + err.Error()
+	default:
+		return http.StatusInternalServerError, "Failed to save verification decision"
+	}
+}
+
+// handleDecision is shared by ApproveVerifikasi and RejectVerifikasi
+func handleDecision(c *gin.Context, decision string) {
 	verifikasiID := c.Param("id")
 	if verifikasiID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification ID is required"})
@@ -531,152 +653,277 @@ func ApproveVerifikasi(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feedback data: " + err.Error()})
 		return
 	}
+	if feedback.RankOverride != 0 {
+		if err := checkRankOverride(feedback.RankOverride, feedback.OverrideReason); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	// Get verifikator ID from token (assuming it's stored in context)
-	// For now, we'll use a default value or extract from token
-	verifikatorID := uint(1) // TODO: Extract from JWT token
+	verifikatorID := currentUserID(c)
 
 	var verifikasi models.Verifikasi
-	result := config.DB.First(&verifikasi, verifikasiID)
+	result := withApplicant(config.DB).First(&verifikasi, verifikasiID)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Verification data not found"})
 		} else {
-			log.Printf("Error finding verification for approval: %v", result.Error)
+			log.Printf("Error finding verification for %s: %v", decision, result.Error)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find verification data"})
 		}
 		return
 	}
 
-	// Use automatic ranking if not provided or use provided ranking
-	ranking := feedback.DataCompletenessRank
-	if ranking == 0 {
-		// Convert verifikasi model to VerifikasiData for calculation
-		verifikasiData := VerifikasiData{
-			UserID:         int(verifikasi.UserID),
-			NIK:            verifikasi.NIK,
-			NISN:           verifikasi.NISN,
-			NamaLengkap:    verifikasi.NamaLengkap,
-			TanggalLahir:   verifikasi.TanggalLahir,
-			TempatLahir:    verifikasi.TempatLahir,
-			Alamat:         verifikasi.Alamat,
-			FotoKTP:        verifikasi.FotoKTP,
-			NomorTelepon:   verifikasi.NomorTelepon,
-			Email:          verifikasi.Email,
-			Instagram:      verifikasi.Instagram,
-			Facebook:       verifikasi.Facebook,
-			Tiktok:         verifikasi.Tiktok,
-			Website:        verifikasi.Website,
-			LinkedIn:       verifikasi.LinkedIn,
-			Twitter:        verifikasi.Twitter,
-			Youtube:        verifikasi.Youtube,
-			Whatsapp:       verifikasi.Whatsapp,
-			Telegram:       verifikasi.Telegram,
-			Other:          verifikasi.Other,
-			NamaIbu:        verifikasi.NamaIbu,
-			PekerjaanIbu:   verifikasi.PekerjaanIbu,
-			PendapatanIbu:  verifikasi.PendapatanIbu,
-			NamaAyah:       verifikasi.NamaAyah,
-			PekerjaanAyah:  verifikasi.PekerjaanAyah,
-			PendapatanAyah: verifikasi.PendapatanAyah,
-			AlamatKeluarga: verifikasi.AlamatKeluarga,
-			FotoKK:         verifikasi.FotoKK,
-			Saudara:        verifikasi.Saudara,
-			AsalSekolah:    verifikasi.AsalSekolah,
-			TahunLulus:     verifikasi.TahunLulus,
-			NilaiSemester1: verifikasi.NilaiSemester1,
-			NilaiSemester2: verifikasi.NilaiSemester2,
-			FotoIjazah:     verifikasi.FotoIjazah,
-			FotoSKL:        verifikasi.FotoSKL,
-			FotoSertifikat: verifikasi.FotoSertifikat,
-			Status:         verifikasi.Status,
+	var reasons []models.RejectionReason
+	if decision == DecisionReject {
+		var unknown []string
+		var err error
+		reasons, unknown, err = resolveRejectionReasons(config.DB, feedback.ReasonCodes)
+		if err != nil {
+			log.Printf("Error resolving rejection reasons: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve rejection reasons"})
+			return
+		}
+		if len(unknown) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown or inactive rejection reasons", "codes": unknown})
+			return
 		}
-		ranking = CalculateDataCompletenessRank(verifikasiData)
 	}
 
-	// Update verification status and feedback
-	now := time.Now()
-	verifikasi.Status = "approved"
-	verifikasi.VerifikatorMessage = feedback.Message
-	verifikasi.DataCompletenessRank = ranking
-	verifikasi.VerifikatorID = verifikatorID
-	verifikasi.VerifiedAt = &now
-	// Simpan input manual verifikator
-	verifikasi.PersonalMatch = feedback.PersonalMatch
-	verifikasi.AcademicMatch = feedback.AcademicMatch
-	verifikasi.FamilyMatch = feedback.FamilyMatch
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return decideVerifikasi(tx, &verifikasi, decision, feedback, reasons, verifikatorID, currentUserRole(c) == "admin")
+	})
+	if err != nil {
+		if errors.Is(err, errConflictOfInterest) {
+			recordAudit(config.DB, verifikatorID, "verifikasi.conflict_refused", "verifikasi", verifikasi.ID, decision)
+		}
+		status, message := decisionErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Error saving %s decision: %v", decision, err)
+		}
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	response := gin.H{
+		"message":           feedback.Message,
+		"data_completeness": verifikasi.DataCompleteness,
+		"merit_rank":        verifikasi.MeritRank,
+		"override_rank":     verifikasi.OverrideRank,
+		"rank":              verifikasi.Rank(),
+	}
+	message := "Verification approved successfully"
+	if decision == DecisionReject {
+		message = "Verification rejected successfully"
+		reasonCodes := make([]string, 0, len(reasons))
+		for _, r := range reasons {
+			reasonCodes = append(reasonCodes, r.Code)
+		}
+		response["reason_codes"] = reasonCodes
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message, "feedback": response})
+}
+
+// POST /api/verifikasi/:id/approve
+func ApproveVerifikasi(c *gin.Context) {
+	handleDecision(c, DecisionApprove)
+}
+
+// POST /api/verifikasi/:id/reject
+func RejectVerifikasi(c *gin.Context) {
+	handleDecision(c, DecisionReject)
+}
+
+// BulkDecisionRequest represents a decision applied to several verification records
+type BulkDecisionRequest struct {
+	IDs      []uint                      `json:"ids" binding:"required,min=1,max=500"`
+	Decision string                      `json:"decision" binding:"required,oneof=approve reject"`
+	Feedback VerificationFeedbackRequest `json:"feedback" binding:"required"`
+}
+
+// BulkDecisionResult is the outcome of a bulk decision for a single record
+type BulkDecisionResult struct {
+	ID      uint   `json:"id"`
+	Success bool   `json:"success"`
+	Status  string `json:"status,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// POST /api/verifikasi/bulk
+func BulkDecideVerifikasi(c *gin.Context) {
+	var input BulkDecisionRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bulk request data: " + err.Error()})
+		return
+	}
+	if input.Feedback.RankOverride != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ranks can only be overridden one verification at a time"})
+		return
+	}
+
+	verifikatorID := currentUserID(c)
+	isAdmin := currentUserRole(c) == "admin"
+
+	var reasons []models.RejectionReason
+	if input.Decision == DecisionReject {
+		var unknown []string
+		var err error
+		reasons, unknown, err = resolveRejectionReasons(config.DB, input.Feedback.ReasonCodes)
+		if err != nil {
+			log.Printf("Error resolving rejection reasons: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve rejection reasons"})
+			return
+		}
+		if len(unknown) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown or inactive rejection reasons", "codes": unknown})
+			return
+		}
+	}
+
+	results := make([]BulkDecisionResult, 0, len(input.IDs))
+	succeeded := 0
+
+	// Each item runs in its own savepoint so a refused item does not undo the others
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range input.IDs {
+			result := BulkDecisionResult{ID: id}
+
+			var verifikasi models.Verifikasi
+			if err := withApplicant(tx).First(&verifikasi, id).Error; err != nil {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+				result.Error = "Verification data not found"
+				results = append(results, result)
+				continue
+			}
+
+			savepoint := fmt.Sprintf("bulk_item_%d", i)
+			if err := tx.SavePoint(savepoint).Error; err != nil {
+				return err
+			}
 
-	if err := config.DB.Save(&verifikasi).Error; err != nil {
-		log.Printf("Error approving verification: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve verification"})
+			err := decideVerifikasi(tx, &verifikasi, input.Decision, input.Feedback, reasons, verifikatorID, isAdmin)
+			if err != nil {
+				if rbErr := tx.RollbackTo(savepoint).Error; rbErr != nil {
+					return rbErr
+				}
+				status, message := decisionErrorStatus(err)
+				if status == http.StatusInternalServerError {
+					return err
+				}
+				if errors.Is(err, errConflictOfInterest) {
+					if err := recordAudit(tx, verifikatorID, "verifikasi.conflict_refused", "verifikasi", id, input.Decision); err != nil {
+						return err
+					}
+				}
+				result.Status = verifikasi.Status
+				result.Error = message
+				results = append(results, result)
+				continue
+			}
+
+			result.Success = true
+			result.Status = verifikasi.Status
+			results = append(results, result)
+			succeeded++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error executing bulk %s: %v", input.Decision, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute bulk action"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Verification approved successfully",
-		"feedback": gin.H{
-			"message":                feedback.Message,
-			"data_completeness_rank": ranking,
-		},
+		"message":   "Bulk action completed",
+		"decision":  input.Decision,
+		"total":     len(input.IDs),
+		"succeeded": succeeded,
+		"failed":    len(input.IDs) - succeeded,
+		"results":   results,
 	})
 }
 
-// POST /api/verifikasi/:id/reject
-func RejectVerifikasi(c *gin.Context) {
+// AssignVerifikatorRequest represents the assignment of a verifikator to a verification record
+type AssignVerifikatorRequest struct {
+	VerifikatorID uint `json:"verifikator_id"`
+}
+
+// POST /api/verifikasi/:id/assign
+func AssignVerifikasi(c *gin.Context) {
 	verifikasiID := c.Param("id")
 	if verifikasiID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification ID is required"})
 		return
 	}
 
-	// Parse feedback request
-	var feedback VerificationFeedbackRequest
-	if err := c.ShouldBindJSON(&feedback); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feedback data: " + err.Error()})
+	var input AssignVerifikatorRequest
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignment data: " + err.Error()})
+		return
+	}
+
+	// Verifikators may only assign themselves; admins may assign anyone
+	verifikatorID := input.VerifikatorID
+	if verifikatorID == 0 {
+		verifikatorID = currentUserID(c)
+	}
+	if verifikatorID != currentUserID(c) && currentUserRole(c) != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can assign other verifikators"})
 		return
 	}
 
-	// Get verifikator ID from token (assuming it's stored in context)
-	// For now, we'll use a default value or extract from token
-	verifikatorID := uint(1) // TODO: Extract from JWT token
+	var verifikator models.User
+	if err := config.DB.First(&verifikator, verifikatorID).Error; err != nil || verifikator.Role != "verifikator" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assignee is not a verifikator"})
+		return
+	}
 
 	var verifikasi models.Verifikasi
-	result := config.DB.First(&verifikasi, verifikasiID)
+	result := withApplicant(config.DB).First(&verifikasi, verifikasiID)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Verification data not found"})
 		} else {
-			log.Printf("Error finding verification for rejection: %v", result.Error)
+			log.Printf("Error finding verification for assignment: %v", result.Error)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find verification data"})
 		}
 		return
 	}
 
-	// Update verification status and feedback
-	now := time.Now()
-	verifikasi.Status = "rejected"
-	verifikasi.VerifikatorMessage = feedback.Message
-	verifikasi.DataCompletenessRank = feedback.DataCompletenessRank
-	verifikasi.VerifikatorID = verifikatorID
-	verifikasi.VerifiedAt = &now
-	// Simpan input manual verifikator
-	verifikasi.PersonalMatch = feedback.PersonalMatch
-	verifikasi.AcademicMatch = feedback.AcademicMatch
-	verifikasi.FamilyMatch = feedback.FamilyMatch
+	if !checkConflict(c, verifikatorID, verifikasi) {
+		return
+	}
 
-	if err := config.DB.Save(&verifikasi).Error; err != nil {
-		log.Printf("Error rejecting verification: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject verification"})
+	scanStatus, err := documentScanStatus(config.DB, verifikasi.ID)
+	if err != nil {
+		log.Printf("Error checking document scans: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check document scans"})
+		return
+	}
+	if scanStatus == models.ScanPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Documents are still being scanned for malware"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Verification rejected successfully",
-		"feedback": gin.H{
-			"message":                feedback.Message,
-			"data_completeness_rank": feedback.DataCompletenessRank,
-		},
+	verifikasi.AssignedVerifikatorID = verifikatorID
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(&verifikasi).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, currentUserID(c), "verifikasi.assign", "verifikasi", verifikasi.ID,
+			fmt.Sprintf("verifikator_id=%d", verifikatorID))
 	})
+	if err != nil {
+		log.Printf("Error assigning verification: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign verification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification assigned successfully", "verifikator_id": verifikatorID})
 }
 
 // GET /api/verifikasi/status/:user_id
@@ -687,8 +934,14 @@ func GetVerificationStatus(c *gin.Context) {
 		return
 	}
 
+	// An applicant may apply for several scholarships: ?beasiswa_id= picks
+	// one, otherwise the latest application is reported
+	query := config.DB.Where("user_id = ?", userID)
+	if beasiswaID := c.Query("beasiswa_id"); beasiswaID != "" {
+		query = query.Where("beasiswa_id = ?", beasiswaID)
+	}
 	var verifikasi models.Verifikasi
-	result := config.DB.Where("user_id = ?", userID).First(&verifikasi)
+	result := query.Order("id desc").First(&verifikasi)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			// No verification record found - this is normal, no error logging
@@ -744,5 +997,23 @@ func GetVerificationStats(c *gin.Context) {
 	}
 	stats.RejectedUsers = int(rejectedCount)
 
+	// Get withdrawn users count
+	var withdrawnCount int64
+	if err := config.DB.Model(&models.Verifikasi{}).Where("status = ?", models.StatusWithdrawn).Count(&withdrawnCount).Error; err != nil {
+		log.Printf("Error getting withdrawn users count: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get withdrawn users count"})
+		return
+	}
+	stats.WithdrawnUsers = int(withdrawnCount)
+
+	// Get per-reason rejection counts
+	reasonCounts, err := rejectionReasonCounts(config.DB)
+	if err != nil {
+		log.Printf("Error getting rejection reason counts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rejection reason counts"})
+		return
+	}
+	stats.RejectionReasons = reasonCounts
+
 	c.JSON(http.StatusOK, stats)
 }
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"sibestie/config"
+	"sibestie/models"
+	"sibestie/scanner"
+
+	"gorm.io/gorm"
+)
+
+// createApplication creates a verification record of an applicant with
+// the given status
+func createApplication(t *testing.T, db *gorm.DB, userID uint, status string) models.Verifikasi {
+	t.Helper()
+	v := models.Verifikasi{UserID: userID, Status: status}
+	if err := db.Omit("UserData").Create(&v).Error; err != nil {
+		t.Fatalf("create verification: %v", err)
+	}
+	return v
+}
+
+// auditActions lists the audit actions recorded for a verification
+func auditActions(t *testing.T, db *gorm.DB, verifikasiID uint) []string {
+	t.Helper()
+	var actions []string
+	if err := db.Model(&models.AuditLog{}).Where("entity = ? AND entity_id = ?", "verifikasi", verifikasiID).
+		Order("id").Pluck("action", &actions).Error; err != nil {
+		t.Fatalf("query audit log: %v", err)
+	}
+	return actions
+}
+
+func TestBulkDecisionKeepsOtherItemsWhenOneFails(t *testing.T) {
+	db := setupTestDB(t)
+	SeedRejectionReasons()
+	const verifikatorID = 2
+
+	first := createApplication(t, db, 4, models.StatusPending)
+	withdrawn := createApplication(t, db, 5, models.StatusWithdrawn)
+	conflicted := createApplication(t, db, 6, models.StatusPending)
+	last := createApplication(t, db, 7, models.StatusPending)
+	declaration := models.ConflictDeclaration{VerifikatorID: verifikatorID, Kind: models.ConflictApplicant, ApplicantID: 6}
+	if err := db.Create(&declaration).Error; err != nil {
+		t.Fatalf("declare conflict: %v", err)
+	}
+
+	body := fmt.Sprintf(`{"ids": [%d, %d, %d, %d, 999], "decision": "reject",
+		"feedback": {"message": "Foto KTP buram", "reason_codes": ["KTP_UNREADABLE"]}}`,
+		first.ID, withdrawn.ID, conflicted.ID, last.ID)
+	w := serve(BulkDecideVerifikasi, verifikatorID, "verifikator", http.MethodPost, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var response struct {
+		Succeeded int                  `json:"succeeded"`
+		Results   []BulkDecisionResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if response.Succeeded != 2 || len(response.Results) != 5 {
+		t.Fatalf("response = %+v, want 2 of 5 decided", response)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		id      uint
+		status  string
+		reasons int64
+		actions []string
+	}{
+		{"first", first.ID, models.StatusRejected, 1, []string{"verifikasi.reject"}},
+		{"withdrawn", withdrawn.ID, models.StatusWithdrawn, 0, nil},
+		{"conflicted", conflicted.ID, models.StatusPending, 0, []string{"verifikasi.conflict_refused"}},
+		{"last", last.ID, models.StatusRejected, 1, []string{"verifikasi.reject"}},
+	} {
+		var v models.Verifikasi
+		if err := db.First(&v, tt.id).Error; err != nil {
+			t.Fatalf("%s: reload: %v", tt.name, err)
+		}
+		decided := tt.status == models.StatusRejected
+		if v.Status != tt.status || (v.VerifiedAt != nil) != decided || (v.VerifikatorMessage != "") != decided {
+			t.Errorf("%s: status %s, verified at %v, message %q; want status %s", tt.name, v.Status, v.VerifiedAt, v.VerifikatorMessage, tt.status)
+		}
+		var reasons int64
+		db.Model(&models.VerifikasiRejectionReason{}).Where("verifikasi_id = ?", tt.id).Count(&reasons)
+		if reasons != tt.reasons {
+			t.Errorf("%s: %d rejection reasons, want %d", tt.name, reasons, tt.reasons)
+		}
+		if got := auditActions(t, db, tt.id); fmt.Sprint(got) != fmt.Sprint(tt.actions) {
+			t.Errorf("%s: audit actions %v, want %v", tt.name, got, tt.actions)
+		}
+	}
+}
+
+func TestOneUploadSharedByTwoApplications(t *testing.T) {
+	setupScanTest(t, scanner.NewFake())
+	db := config.DB
+	SeedScoringRules()
+	file := storeScanFixture(t, "kartu keluarga", 1)[0]
+	db.Model(&file).Updates(map[string]interface{}{"source_type": "kk", "scan_status": models.ScanClean})
+
+	var scholarships []models.Beasiswa
+	for _, title := range []string{"Beasiswa Prestasi", "Beasiswa Daerah"} {
+		beasiswa := models.Beasiswa{Judul: title}
+		if err := db.Create(&beasiswa).Error; err != nil {
+			t.Fatalf("create scholarship: %v", err)
+		}
+		if err := db.Create(&models.BeasiswaDocumentRequirement{BeasiswaID: beasiswa.ID, DocType: "kk", Required: true}).Error; err != nil {
+			t.Fatalf("create requirement: %v", err)
+		}
+		scholarships = append(scholarships, beasiswa)
+	}
+
+	submit := func(beasiswaID uint) int {
+		body := fmt.Sprintf(`{"beasiswa_id": %d, "documents": [{"type": "kk", "document_id": %d}]}`, beasiswaID, file.ID)
+		return serve(SubmitVerifikasi, file.UserID, "applicant", http.MethodPost, body).Code
+	}
+	if code := submit(scholarships[0].ID); code != http.StatusOK {
+		t.Fatalf("first application: status %d", code)
+	}
+	if code := submit(scholarships[0].ID); code != http.StatusConflict {
+		t.Errorf("second application for the same scholarship: status %d, want 409", code)
+	}
+	if code := submit(scholarships[1].ID); code != http.StatusOK {
+		t.Fatalf("application for another scholarship: status %d", code)
+	}
+
+	var links []models.VerifikasiDocument
+	db.Where("source_file_id = ?", file.ID).Order("verifikasi_id").Find(&links)
+	if len(links) != 2 || links[0].VerifikasiID == links[1].VerifikasiID {
+		t.Fatalf("links = %+v, want the upload attached to both applications", links)
+	}
+	var files int64
+	db.Model(&models.SourceFile{}).Count(&files)
+	if files != 1 {
+		t.Errorf("%d stored files, want the one upload", files)
+	}
+
+	// Erasing one application keeps the content the other still uses
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var v models.Verifikasi
+		if err := withApplicant(tx).First(&v, links[0].VerifikasiID).Error; err != nil {
+			return err
+		}
+		return anonymizeVerifikasi(tx, &v)
+	})
+	if err != nil {
+		t.Fatalf("anonymize: %v", err)
+	}
+	if got := reloadFile(t, file.ID); got.StorageKey != file.StorageKey {
+		t.Errorf("shared file changed to %+v", got)
+	}
+	content, err := config.Storage.Get(context.Background(), file.StorageKey)
+	if err != nil {
+		t.Fatalf("shared content deleted: %v", err)
+	}
+	content.Close()
+}
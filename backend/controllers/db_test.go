@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sibestie/config"
+	"sibestie/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupTestDB points config.DB at a fresh database in a temporary directory,
+// migrated like the real one, and restores the previous one after the test
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Beasiswa{},
+		&models.UserData{},
+		&models.SocialMedia{},
+		&models.Academic{},
+		&models.SemesterGrade{},
+		&models.Family{},
+		&models.Children{},
+		&models.SourceFile{},
+		&models.Verifikasi{},
+		&models.AuditLog{},
+		&models.ConflictDeclaration{},
+		&models.RejectionReason{},
+		&models.VerifikasiRejectionReason{},
+		&models.VerifikasiDocument{},
+		&models.DocumentAnnotation{},
+		&models.BeasiswaDocumentRequirement{},
+		&models.DuplicateFlag{},
+		&models.ScoringProfile{},
+		&models.ScoringRuleSet{},
+		&models.MinimumWage{},
+		&models.RankOverride{},
+	)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	prev := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		config.DB = prev
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// serve calls a handler as the given user, with a JSON body when body is
+// not empty and the given route parameters
+func serve(handler gin.HandlerFunc, userID uint, role, method, body string, params ...gin.Param) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/", strings.NewReader(body))
+	if body != "" {
+		c.Request.Header.Set("Content-Type", "application/json")
+	}
+	c.Params = params
+	if userID != 0 {
+		c.Set("user_id", userID)
+		c.Set("user_role", role)
+	}
+	handler(c)
+	return w
+}
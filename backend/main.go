@@ -35,11 +35,16 @@ func main() {
 	}
 
 	config.ConnectDatabase()
+	config.ConnectStorage()
+	config.ConnectScanner()
 
 	config.DB.AutoMigrate(
 		&models.User{},
 		&models.Beasiswa{},
 		&models.UserData{},
+		&models.SocialMedia{},
+		&models.Academic{},
+		&models.SemesterGrade{},
 		&models.Family{},
 		&models.Children{},
 		&models.SourceFile{},
@@ -47,8 +52,29 @@ func main() {
 		&models.VerificationStack{},
 		&models.Pendaftar{},
 		&models.Verifikasi{},
+		&models.AuditLog{},
+		&models.ConflictDeclaration{},
+		&models.RejectionReason{},
+		&models.VerifikasiRejectionReason{},
+		&models.VerifikasiDocument{},
+		&models.DocumentAnnotation{},
+		&models.BeasiswaDocumentRequirement{},
+		&models.DuplicateFlag{},
+		&models.ScoringProfile{},
+		&models.ScoringRuleSet{},
+		&models.MinimumWage{},
+		&models.RankOverride{},
 	)
 
+	controllers.SeedRejectionReasons()
+	controllers.SeedScoringRules()
+	controllers.SeedMinimumWages()
+	controllers.MigrateApplicantData()
+	controllers.SnapshotMinimumWages()
+	controllers.MigrateScores()
+	controllers.StartRetentionScheduler()
+	controllers.StartScanWorker()
+
 	r := gin.Default()
 
 	r.Use(cors.New(cors.Config{
@@ -86,18 +112,71 @@ func setupRoutes(r *gin.Engine) {
 
 	r.GET("/api/scholarships", controllers.GetScholarships)
 	r.POST("/api/scholarships", controllers.CreateScholarship)
+	r.GET("/api/scholarships/:id/documents", controllers.GetScholarshipDocuments)
+	r.PUT("/api/scholarships/:id/documents", controllers.AuthRequired("admin"), controllers.SetScholarshipDocuments)
+	r.PUT("/api/scholarships/:id/scoring-profile", controllers.AuthRequired("admin"), controllers.SetScholarshipScoringProfile)
+	r.GET("/api/scholarships/:id/rankings", controllers.AuthRequired("verifikator", "admin"), controllers.GetScholarshipRankings)
 
 	// User endpoints
 	r.GET("/api/getuser", controllers.GetUsers)
 	r.GET("/api/verification-users", controllers.GetVerificationUsers)
+	r.DELETE("/api/account", controllers.AuthRequired("user"), controllers.DeleteAccount)
 
 	// Verifikasi endpoints
-	r.POST("/api/verifikasi", controllers.SubmitVerifikasi)
+	r.POST("/api/verifikasi", controllers.AuthRequired("user"), controllers.SubmitVerifikasi)
 	r.POST("/api/verifikasi/test", controllers.TestConnection)
-	r.GET("/api/verifikasi/pending", controllers.ListPendingVerifikasi)
-	r.GET("/api/verifikasi/:id", controllers.GetVerifikasiDetail)
-	r.POST("/api/verifikasi/:id/approve", controllers.ApproveVerifikasi)
-	r.POST("/api/verifikasi/:id/reject", controllers.RejectVerifikasi)
+	r.POST("/api/verifikasi/bulk", controllers.AuthRequired("verifikator", "admin"), controllers.BulkDecideVerifikasi)
+	r.GET("/api/verifikasi/pending", controllers.AuthRequired("verifikator", "admin"), controllers.ListPendingVerifikasi)
+	r.GET("/api/verifikasi/duplicates", controllers.AuthRequired("verifikator", "admin"), controllers.ListDuplicateClusters)
+	r.GET("/api/verifikasi/:id", controllers.AuthRequired(), controllers.GetVerifikasiDetail)
+	r.POST("/api/verifikasi/:id/assign", controllers.AuthRequired("verifikator", "admin"), controllers.AssignVerifikasi)
+	r.POST("/api/verifikasi/:id/approve", controllers.AuthRequired("verifikator", "admin"), controllers.ApproveVerifikasi)
+	r.POST("/api/verifikasi/:id/reject", controllers.AuthRequired("verifikator", "admin"), controllers.RejectVerifikasi)
+	r.POST("/api/verifikasi/:id/withdraw", controllers.AuthRequired("user"), controllers.WithdrawVerifikasi)
+	r.GET("/api/verifikasi/:id/checklist", controllers.AuthRequired(), controllers.GetDocumentChecklist)
+	r.GET("/api/verifikasi/:id/score", controllers.AuthRequired(), controllers.GetVerifikasiScore)
+	r.PUT("/api/verifikasi/:id/rank-override", controllers.AuthRequired("verifikator", "admin"), controllers.SetRankOverride)
+	r.PUT("/api/verifikasi/:id/documents/:document_id/review", controllers.AuthRequired("verifikator", "admin"), controllers.ReviewVerifikasiDocument)
+	r.POST("/api/verifikasi/:id/documents/:document_id/annotations", controllers.AuthRequired("verifikator", "admin"), controllers.CreateDocumentAnnotation)
+	r.DELETE("/api/verifikasi/:id/documents/:document_id/annotations/:annotation_id", controllers.AuthRequired("verifikator", "admin"), controllers.DeleteDocumentAnnotation)
 	r.GET("/api/verifikasi/status/:user_id", controllers.GetVerificationStatus)
 	r.GET("/api/verifikasi/stats", controllers.GetVerificationStats)
+	r.GET("/api/verifikasi/stats/rejection-reasons", controllers.GetRejectionReasonStats)
+
+	// Rejection reason catalog
+	r.GET("/api/rejection-reasons", controllers.ListRejectionReasons)
+	r.POST("/api/rejection-reasons", controllers.AuthRequired("admin"), controllers.CreateRejectionReason)
+	r.PUT("/api/rejection-reasons/:id", controllers.AuthRequired("admin"), controllers.UpdateRejectionReason)
+
+	// Document endpoints
+	r.GET("/api/documents", controllers.AuthRequired(), controllers.ListDocuments)
+	r.POST("/api/documents", controllers.AuthRequired(), controllers.UploadDocument)
+	r.GET("/api/documents/:id", controllers.AuthRequired(), controllers.GetDocument)
+	r.GET("/api/documents/:id/download", controllers.DownloadDocument)
+	r.POST("/api/admin/documents/:id/rescan", controllers.AuthRequired("admin"), controllers.RescanDocument)
+
+	// Conflict of interest endpoints
+	r.POST("/api/conflicts", controllers.AuthRequired("verifikator"), controllers.DeclareConflict)
+	r.GET("/api/conflicts", controllers.AuthRequired("verifikator", "admin"), controllers.ListConflicts)
+	r.DELETE("/api/conflicts/:id", controllers.AuthRequired("verifikator", "admin"), controllers.RevokeConflict)
+
+	// Data retention
+	r.GET("/api/admin/retention/report", controllers.AuthRequired("admin"), controllers.GetRetentionReport)
+	r.POST("/api/admin/retention/run", controllers.AuthRequired("admin"), controllers.RunRetention)
+
+	// Audit trail
+	r.GET("/api/audit", controllers.AuthRequired("admin"), controllers.ListAuditLogs)
+
+	// Scoring rules
+	r.GET("/api/scoring/rules", controllers.AuthRequired("admin"), controllers.ListScoringRules)
+	r.POST("/api/scoring/rules", controllers.AuthRequired("admin"), controllers.CreateScoringRules)
+	r.GET("/api/scoring/rules/:version", controllers.AuthRequired("admin"), controllers.GetScoringRules)
+	r.POST("/api/scoring/rules/:version/activate", controllers.AuthRequired("admin"), controllers.ActivateScoringRules)
+	r.POST("/api/scoring/simulate", controllers.AuthRequired("admin"), controllers.SimulateScoring)
+	r.GET("/api/scoring/profiles", controllers.AuthRequired("admin"), controllers.ListScoringProfiles)
+	r.POST("/api/scoring/profiles", controllers.AuthRequired("admin"), controllers.CreateScoringProfile)
+
+	// Reference data
+	r.GET("/api/minimum-wages", controllers.AuthRequired("verifikator", "admin"), controllers.ListMinimumWages)
+	r.PUT("/api/minimum-wages", controllers.AuthRequired("admin"), controllers.SetMinimumWages)
 }
@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// ---------- AUDIT LOG ----------
+// AuditLog records an action taken by a user on a record
+type AuditLog struct {
+	gorm.Model
+	ActorID  uint   `gorm:"index" json:"actor_id"`
+	Action   string `gorm:"type:varchar(100);index" json:"action"`
+	Entity   string `gorm:"type:varchar(50)" json:"entity"`
+	EntityID uint   `json:"entity_id"`
+	Detail   string `gorm:"type:text" json:"detail"`
+}
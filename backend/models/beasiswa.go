@@ -20,6 +20,23 @@ type Beasiswa struct {
 
 	// Relasi ke pendaftar (opsional)
 	Pendaftar []Pendaftar `json:"pendaftar,omitempty"`
+
+	// Dokumen yang diminta beasiswa ini
+	DocumentRequirements []BeasiswaDocumentRequirement `gorm:"foreignKey:BeasiswaID" json:"document_requirements,omitempty"`
+
+	// Profil penilaian pendaftar; 0 memakai profil default
+	ScoringProfileID uint `json:"scoring_profile_id"`
+}
+
+// ---------- BEASISWA DOCUMENT REQUIREMENT ----------
+// BeasiswaDocumentRequirement lists a document type a scholarship asks for
+type BeasiswaDocumentRequirement struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	BeasiswaID  uint      `gorm:"uniqueIndex:idx_beasiswa_doc_type" json:"beasiswa_id"`
+	DocType     string    `gorm:"type:varchar(50);uniqueIndex:idx_beasiswa_doc_type" json:"type"`
+	Required    bool      `json:"required"`
+	Description string    `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 // ---------- PENDAFTAR ----------
@@ -0,0 +1,23 @@
+package models
+
+import "gorm.io/gorm"
+
+// Conflict kinds a verifikator can declare
+const (
+	ConflictApplicant  = "applicant"
+	ConflictSchool     = "school"
+	ConflictFamilyName = "family_name"
+)
+
+// ---------- CONFLICT DECLARATION ----------
+// ConflictDeclaration is a conflict of interest declared by a verifikator.
+// Depending on Kind it matches an applicant user ID, a school name
+// (Verifikasi.AsalSekolah) or a family name.
+type ConflictDeclaration struct {
+	gorm.Model
+	VerifikatorID uint   `gorm:"index" json:"verifikator_id"`
+	Kind          string `gorm:"type:varchar(20)" json:"kind"`
+	ApplicantID   uint   `json:"applicant_id"`
+	Value         string `gorm:"type:varchar(255)" json:"value"`
+	Reason        string `gorm:"type:text" json:"reason"`
+}
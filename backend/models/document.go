@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// Document types an applicant can upload
+const (
+	DocKTP        = "ktp"
+	DocKK         = "kk"
+	DocIjazah     = "ijazah"
+	DocSKL        = "skl"
+	DocSertifikat = "sertifikat"
+	DocSKTM       = "sktm"
+	DocRapor      = "rapor"
+)
+
+// DocumentTypes lists every accepted document type
+var DocumentTypes = []string{DocKTP, DocKK, DocIjazah, DocSKL, DocSertifikat, DocSKTM, DocRapor}
+
+// DocumentLabels names each document type for checklists
+var DocumentLabels = map[string]string{
+	DocKTP:        "KTP",
+	DocKK:         "Kartu Keluarga",
+	DocIjazah:     "Ijazah",
+	DocSKL:        "Surat Keterangan Lulus",
+	DocSertifikat: "Sertifikat Prestasi",
+	DocSKTM:       "Surat Keterangan Tidak Mampu",
+	DocRapor:      "Rapor",
+}
+
+// IsDocumentType reports whether t is an accepted document type
+func IsDocumentType(t string) bool {
+	for _, known := range DocumentTypes {
+		if known == t {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiredDocumentTypes lists the documents an application needs when its
+// scholarship does not define its own requirements
+var RequiredDocumentTypes = []string{DocKTP, DocKK, DocIjazah}
+
+// IsRequiredDocument reports whether t is a required document type
+func IsRequiredDocument(t string) bool {
+	for _, required := range RequiredDocumentTypes {
+		if required == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Review states of a document attached to a verification
+const (
+	DocumentUnchecked = "unchecked"
+	DocumentValid     = "valid"
+	DocumentInvalid   = "invalid"
+)
+
+// Malware scan states of an uploaded file
+const (
+	ScanPending  = "pending"
+	ScanClean    = "clean"
+	ScanInfected = "infected"
+	// ScanFailed means the scanner kept failing; an admin has to rescan
+	ScanFailed = "failed"
+)
+
+// ---------- VERIFIKASI DOCUMENT ----------
+// VerifikasiDocument attaches an uploaded SourceFile to a verification
+type VerifikasiDocument struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	VerifikasiID uint      `gorm:"index" json:"verifikasi_id"`
+	SourceFileID uint      `gorm:"index" json:"document_id"`
+	DocType      string    `gorm:"type:varchar(50)" json:"type"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Verifikator's judgement of this document (DocumentUnchecked, ...)
+	ReviewStatus string     `gorm:"type:varchar(20);default:'unchecked'" json:"review_status"`
+	ReviewNote   string     `gorm:"type:text" json:"review_note"`
+	ReviewedBy   uint       `json:"reviewed_by"`
+	ReviewedAt   *time.Time `json:"reviewed_at"`
+}
+
+// ---------- DOCUMENT ANNOTATION ----------
+// DocumentAnnotation marks a region of an attached document with a note.
+// Coordinates are fractions (0-1) of the page width and height so they do
+// not depend on the size the document is displayed at.
+type DocumentAnnotation struct {
+	ID                   uint      `gorm:"primaryKey" json:"id"`
+	VerifikasiDocumentID uint      `gorm:"index" json:"-"`
+	AuthorID             uint      `json:"author_id"`
+	Page                 int       `gorm:"default:1" json:"page"` // halaman PDF, 1 untuk gambar
+	X                    float64   `json:"x"`
+	Y                    float64   `json:"y"`
+	Width                float64   `json:"width"`
+	Height               float64   `json:"height"`
+	Note                 string    `gorm:"type:text" json:"note"`
+	CreatedAt            time.Time `json:"created_at"`
+}
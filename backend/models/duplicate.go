@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Reasons an application is suspected to come from the same person as
+// another application
+const (
+	DuplicateNIK       = "nik"
+	DuplicateNISN      = "nisn"
+	DuplicateNameBirth = "name_birth_date"
+	DuplicateDocument  = "document"
+)
+
+// ---------- DUPLICATE FLAG ----------
+// DuplicateFlag links an application to an earlier application of another
+// account that looks like the same applicant. One flag is kept per pair and
+// reason.
+type DuplicateFlag struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	VerifikasiID  uint   `gorm:"uniqueIndex:idx_duplicate_pair" json:"verifikasi_id"`
+	DuplicateOfID uint   `gorm:"uniqueIndex:idx_duplicate_pair;index" json:"duplicate_of_id"`
+	Reason        string `gorm:"type:varchar(20);uniqueIndex:idx_duplicate_pair" json:"reason"`
+	// Nilai yang cocok, misalnya nama kedua pendaftar atau jenis dokumen
+	Detail    string    `gorm:"type:varchar(255)" json:"detail"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ---------- REJECTION REASON ----------
+// RejectionReason is an admin-managed reason code a verifikator can cite
+// when rejecting a verification
+type RejectionReason struct {
+	gorm.Model
+	Code        string `gorm:"uniqueIndex;type:varchar(50)" json:"code"`
+	Label       string `gorm:"type:varchar(255)" json:"label"`
+	Description string `gorm:"type:text" json:"description"`
+	Active      bool   `json:"active"`
+}
+
+// ---------- VERIFIKASI REJECTION REASON ----------
+// VerifikasiRejectionReason links a rejected verification to the reasons cited
+type VerifikasiRejectionReason struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	VerifikasiID      uint      `gorm:"index" json:"verifikasi_id"`
+	RejectionReasonID uint      `gorm:"index" json:"rejection_reason_id"`
+	Code              string    `gorm:"type:varchar(50);index" json:"code"`
+	CreatedAt         time.Time `json:"created_at"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// DefaultScoringProfile is the code of the profile used for applications
+// whose scholarship has no scoring profile of its own
+const DefaultScoringProfile = "default"
+
+// ---------- SCORING PROFILE ----------
+// ScoringProfile is a named way of scoring applications, for instance merit
+// or need based. Each profile has its own versions of the scoring rules.
+type ScoringProfile struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Code        string    `gorm:"type:varchar(50);uniqueIndex" json:"code"`
+	Name        string    `gorm:"type:varchar(100)" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ---------- SCORING RULE SET ----------
+// ScoringRuleSet is one version of the scoring rules (weights, income
+// brackets and thresholds) of a profile. Version numbers are unique across
+// profiles. A version is never changed once saved; editing the rules saves
+// a new version, so a Verifikasi scored under an older version can still be
+// scored again the same way.
+type ScoringRuleSet struct {
+	ID      uint `gorm:"primaryKey" json:"id"`
+	Version int  `gorm:"uniqueIndex" json:"version"`
+	// Profil pemilik versi ini; hanya satu versi aktif per profil
+	ProfileID uint `gorm:"index" json:"profile_id"`
+	// Aturan dalam format JSON (lihat tools/scoring.Rules)
+	Rules     string    `gorm:"type:text" json:"-"`
+	Note      string    `gorm:"type:text" json:"note"`
+	Active    bool      `gorm:"default:false;index" json:"active"`
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ---------- RANK OVERRIDE ----------
+// RankOverride records a verifikator setting the rank of an application by
+// hand. The computed merit rank on the Verifikasi is kept; the latest
+// override sets Verifikasi.OverrideRank, and a Rank of 0 lifts it again.
+type RankOverride struct {
+	ID           uint `gorm:"primaryKey" json:"id"`
+	VerifikasiID uint `gorm:"index" json:"verifikasi_id"`
+	// Peringkat hasil perhitungan saat override dibuat
+	ComputedRank  int       `json:"computed_rank"`
+	Rank          int       `json:"rank"`
+	Reason        string    `gorm:"type:text" json:"reason"`
+	VerifikatorID uint      `json:"verifikator_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"gorm.io/gorm"
@@ -41,11 +42,13 @@ type UserData struct {
 	gorm.Model
 
 	// Personal Data
-	UserID       uint
-	NIK          string `gorm:"type:varchar(20)"`
-	NISN         string `gorm:"type:varchar(20)"`
-	FullName     string `gorm:"type:varchar(100)"`
-	BirthDate    time.Time
+	UserID    uint
+	NIK       string `gorm:"type:varchar(20)"`
+	NISN      string `gorm:"type:varchar(20)"`
+	FullName  string `gorm:"type:varchar(100)"`
+	BirthDate *time.Time
+	// Jenis kelamin: L atau P
+	Gender       string `gorm:"type:varchar(1)"`
 	BirthPlace   string
 	NomorTelepon string
 	Address      string
@@ -91,20 +94,87 @@ type Academic struct {
 	SchoolOrigin   string `gorm:"type:varchar(100)"`
 	GraduationYear string
 
-	RangeSemester []string `gorm:"type:varchar(100)"`
+	// Riwayat nilai rata-rata per semester
+	Grades []SemesterGrade `gorm:"foreignKey:AcademicID"`
 
 	SourceCertificate []SourceFile `gorm:"foreignKey:UserDataID"`
 	SourceIjazah      []SourceFile `gorm:"foreignKey:UserDataID"`
 	SourceSKL         []SourceFile `gorm:"foreignKey:UserDataID"`
 }
 
+// ---------- SEMESTER GRADE ----------
+// Grading scales of a semester grade
+const (
+	GradeScale100 = "0-100"
+	GradeScale10  = "0-10"
+	GradeScale4   = "0-4"
+)
+
+// GradeScales maps each grading scale to its maximum value
+var GradeScales = map[string]float64{
+	GradeScale100: 100,
+	GradeScale10:  10,
+	GradeScale4:   4,
+}
+
+// NormalizeGrade converts a grade on scale to the common 0-100 scale. It
+// returns false for unknown scales and for grades that are outside the
+// scale or not a finite number.
+func NormalizeGrade(score float64, scale string) (float64, bool) {
+	maximum, ok := GradeScales[scale]
+	if !ok || math.IsNaN(score) || math.IsInf(score, 0) || score < 0 || score > maximum {
+		return 0, false
+	}
+	return score * 100 / maximum, true
+}
+
+// SemesterGrade is the average grade of one semester
+type SemesterGrade struct {
+	gorm.Model
+	AcademicID uint `gorm:"index"`
+	Semester   int
+	Score      float64
+	Scale      string `gorm:"type:varchar(10)"`
+	// Nilai pada skala 0-100
+	Normalized float64
+}
+
 // ---------- CHILDREN ----------
+// Education statuses of a child of the family
+const (
+	EducationNotYet  = "belum_sekolah"
+	EducationSD      = "sd"
+	EducationSMP     = "smp"
+	EducationSMA     = "sma"
+	EducationKuliah  = "kuliah"
+	EducationLulus   = "lulus"
+	EducationDropout = "tidak_sekolah"
+)
+
+// EducationStatuses lists every accepted education status
+var EducationStatuses = []string{EducationNotYet, EducationSD, EducationSMP, EducationSMA, EducationKuliah, EducationLulus, EducationDropout}
+
+// IsEducationStatus reports whether s is a known education status
+func IsEducationStatus(s string) bool {
+	for _, status := range EducationStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
 // Children is a child of the family
 type Children struct {
 	gorm.Model
-	FamilyID uint
-	FullName string
-	Status   string
+	FamilyID        uint
+	FullName        string
+	Age             int
+	EducationStatus string `gorm:"type:varchar(20)"`
+	// Masih menjadi tanggungan keluarga
+	Dependent bool
+	// Keterangan bebas dari data saudara sebelum terstruktur
+	Status string
 }
 
 // ---------- FAMILY ----------
@@ -136,4 +206,20 @@ type SourceFile struct {
 	SourceType string `gorm:"type:varchar(100)"`
 	SourceName string `gorm:"type:varchar(100)"`
 	SourceData []byte `gorm:"type:longblob"`
+
+	// Uploaded through the document endpoint and kept in document storage
+	UserID      uint   `gorm:"index;default:0"`
+	StorageKey  string `gorm:"type:varchar(255)"`
+	ContentType string `gorm:"type:varchar(100)"`
+	Size        int64
+	// Downscaled JPEG preview for image documents
+	ThumbnailKey string `gorm:"type:varchar(255)"`
+	// SHA-256 of the stored content; files with the same checksum share one
+	// stored object
+	Checksum string `gorm:"type:char(64);index"`
+	// Malware scan of the stored content (ScanPending, ScanClean, ...)
+	ScanStatus    string `gorm:"type:varchar(20);index;default:'pending'"`
+	ScanSignature string `gorm:"type:varchar(255)"`
+	ScanAttempts  int    `gorm:"default:0"`
+	ScannedAt     *time.Time
 }
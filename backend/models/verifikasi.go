@@ -2,61 +2,102 @@ package models
 
 import "time"
 
+// Verification statuses
+const (
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusWithdrawn = "withdrawn"
+)
+
+// verifikasiTransitions lists the statuses each status may move to. A
+// decided verification may be decided again, as it always could; only a
+// withdrawn one is final.
+var verifikasiTransitions = map[string][]string{
+	StatusPending:  {StatusApproved, StatusRejected, StatusWithdrawn},
+	StatusApproved: {StatusApproved, StatusRejected},
+	StatusRejected: {StatusApproved, StatusRejected},
+}
+
+// CanTransition reports whether a verification may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, allowed := range verifikasiTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Verifikasi is an application under review. The applicant's personal,
+// family and academic data live in UserData; documents are attached
+// through VerifikasiDocument.
 type Verifikasi struct {
-	ID           uint   `gorm:"primaryKey" json:"id"`
-	UserID       uint   `json:"user_id"`
-	NIK          string `json:"nik"`
-	NISN         string `json:"nisn"`
-	NamaLengkap  string `json:"nama_lengkap"`
-	TanggalLahir string `json:"tanggal_lahir"`
-	TempatLahir  string `json:"tempat_lahir"`
-	Alamat       string `json:"alamat"`
-	FotoKTP      string `json:"foto_ktp"`
-
-	// Contact Information
-	NomorTelepon string `json:"nomor_telepon"`
-	Email        string `json:"email"`
-
-	// Social Media
-	Instagram string `json:"instagram"`
-	Facebook  string `json:"facebook"`
-	Tiktok    string `json:"tiktok"`
-	Website   string `json:"website"`
-	LinkedIn  string `json:"linkedin"`
-	Twitter   string `json:"twitter"`
-	Youtube   string `json:"youtube"`
-	Whatsapp  string `json:"whatsapp"`
-	Telegram  string `json:"telegram"`
-	Other     string `json:"other"`
-
-	NamaIbu        string `json:"nama_ibu"`
-	PekerjaanIbu   string `json:"pekerjaan_ibu"`
-	PendapatanIbu  int    `json:"pendapatan_ibu"`
-	NamaAyah       string `json:"nama_ayah"`
-	PekerjaanAyah  string `json:"pekerjaan_ayah"`
-	PendapatanAyah int    `json:"pendapatan_ayah"`
-	AlamatKeluarga string `json:"alamat_keluarga"`
-	FotoKK         string `json:"foto_kk"`
-	Saudara        string `json:"saudara"`
-	AsalSekolah    string `json:"asal_sekolah"`
-	TahunLulus     string `json:"tahun_lulus"`
-	NilaiSemester1 string `json:"nilai_semester_1"`
-	NilaiSemester2 string `json:"nilai_semester_2"`
-	FotoIjazah     string `json:"foto_ijazah"`
-	FotoSKL        string `json:"foto_skl"`
-	FotoSertifikat string `json:"foto_sertifikat"`
-	Status         string `gorm:"default:pending" json:"status"`
+	ID         uint `gorm:"primaryKey" json:"id"`
+	UserID     uint `json:"user_id"`
+	BeasiswaID uint `gorm:"index" json:"beasiswa_id"`
+
+	// Data pendaftar saat pengajuan
+	UserDataID uint                 `gorm:"index" json:"user_data_id"`
+	UserData   UserData             `gorm:"foreignKey:UserDataID" json:"-"`
+	Documents  []VerifikasiDocument `gorm:"foreignKey:VerifikasiID" json:"-"`
+
+	Status string `gorm:"default:pending" json:"status"`
 
 	// Verifikator Feedback
-	VerifikatorMessage   string     `json:"verifikator_message"`
-	DataCompletenessRank int        `json:"data_completeness_rank"` // 1-10 scale
-	VerifikatorID        uint       `json:"verifikator_id"`
-	VerifiedAt           *time.Time `json:"verified_at"`
+	VerifikatorMessage string     `json:"verifikator_message"`
+	VerifikatorID      uint       `json:"verifikator_id"`
+	VerifiedAt         *time.Time `json:"verified_at"`
+
+	// Penilaian otomatis, disimpan terpisah: kelengkapan data isian dan
+	// dokumen wajib, serta skor kelayakan/kebutuhan (merit/need)
+	DataCompleteness int     `json:"data_completeness"` // 0-100 percent
+	MeritScore       float64 `json:"merit_score"`       // 0-100
+	MeritRank        int     `json:"merit_rank"`        // 1-10 scale
+
+	// Versi ScoringRuleSet yang menghasilkan MeritRank; data yang dinilai
+	// sebelum aturan penilaian berversi diberi versi dasar (aturan bawaan)
+	ScoringVersion int `json:"scoring_version"`
+
+	// Peringkat dipindahkan apa adanya dari sistem lama (sebelum aturan
+	// penilaian berversi); tidak dapat dihitung ulang dengan aturan mana pun
+	RankMigrated bool `gorm:"default:false" json:"rank_migrated"`
+
+	// UMR wilayah pemohon (rupiah per bulan) yang dipakai saat penilaian,
+	// agar perubahan tabel upah minimum tidak mengubah skor yang sudah ada;
+	// 0 bila tidak tercatat, nil untuk data yang belum disimpan UMR-nya
+	AppliedUMR *int64 `json:"applied_umr"`
+
+	// Peringkat yang ditetapkan verifikator menggantikan MeritRank; 0 bila
+	// tidak diubah (riwayat dan alasannya di RankOverride)
+	OverrideRank int `json:"override_rank"`
+
+	// Verifikator yang ditugaskan untuk meninjau data ini
+	AssignedVerifikatorID uint `json:"assigned_verifikator_id"`
+
+	// Ditandai bila pengajuan mirip dengan pengajuan akun lain (lihat DuplicateFlag)
+	DuplicateSuspected bool `gorm:"default:false;index" json:"duplicate_suspected"`
 
 	// Hasil Kesesuaian Data (input manual verifikator)
 	PersonalMatch float64 `json:"personal_match"`
 	AcademicMatch float64 `json:"academic_match"`
 	FamilyMatch   float64 `json:"family_match"`
 
+	// Penarikan dan penghapusan data oleh pendaftar
+	WithdrawnAt  *time.Time `json:"withdrawn_at"`
+	AnonymizedAt *time.Time `json:"anonymized_at"`
+
+	// Waktu dokumen dihapus oleh kebijakan retensi
+	DocumentsPurgedAt *time.Time `json:"documents_purged_at"`
+
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// Rank is the rank the application is judged by: the verifikator's
+// override when there is one, otherwise the computed merit rank
+func (v Verifikasi) Rank() int {
+	if v.OverrideRank > 0 {
+		return v.OverrideRank
+	}
+	return v.MeritRank
+}
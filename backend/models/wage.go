@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ---------- MINIMUM WAGE ----------
+// MinimumWage is the monthly minimum wage (UMR) of a region in a year: the
+// UMP of a province or the UMK of a regency or city. Regions use the codes
+// of the NIK, two digits for a province and four for a regency or city.
+type MinimumWage struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	RegionCode string    `gorm:"type:varchar(4);uniqueIndex:idx_wage_region_year" json:"region_code"`
+	RegionName string    `gorm:"type:varchar(100)" json:"region_name"`
+	Year       int       `gorm:"uniqueIndex:idx_wage_region_year" json:"year"`
+	Amount     int64     `json:"amount"`
+	UpdatedBy  uint      `json:"updated_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,111 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// chunkSize is the size of the INSTREAM chunks sent to clamd
+const chunkSize = 64 << 10
+
+// ClamAV scans content with a clamd daemon using its INSTREAM command, so
+// the daemon never needs access to the document storage
+type ClamAV struct {
+	// Network is "unix" for the local socket or "tcp"
+	Network string
+	Address string
+	Timeout time.Duration
+}
+
+// NewClamAV returns a scanner for the clamd listening on network/address
+func NewClamAV(network, address string) *ClamAV {
+	return &ClamAV{Network: network, Address: address, Timeout: 2 * time.Minute}
+}
+
+func (s *ClamAV) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.Network, s.Address)
+	if err != nil {
+		return Result{}, fmt.Errorf("scanner: connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(s.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	if err := s.stream(conn, r); err != nil {
+		// clamd closes the connection when a stream exceeds StreamMaxLength;
+		// its reply explains why, so prefer it over the write error
+		if reply, replyErr := readReply(conn); replyErr == nil && reply != "" {
+			return parseReply(reply)
+		}
+		return Result{}, fmt.Errorf("scanner: send to clamd: %w", err)
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return Result{}, fmt.Errorf("scanner: read clamd reply: %w", err)
+	}
+	return parseReply(reply)
+}
+
+// stream sends r as an INSTREAM command: length-prefixed chunks terminated
+// by a zero-length chunk
+func (s *ClamAV) stream(conn net.Conn, r io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, err := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := conn.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// readReply reads one NUL-terminated reply
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(reply, "\x00\n"), nil
+}
+
+// parseReply turns "stream: OK", "stream: <name> FOUND" or "<message> ERROR"
+// into a result
+func parseReply(reply string) (Result, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return Result{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	default:
+		return Result{}, fmt.Errorf("scanner: clamd: %s", reply)
+	}
+}
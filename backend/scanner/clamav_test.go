@@ -0,0 +1,135 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeClamd accepts INSTREAM sessions on a unix socket, collects the
+// streamed content and answers with reply(content)
+func fakeClamd(t *testing.T, reply func(content []byte) string) (address string, received chan []byte) {
+	t.Helper()
+	address = filepath.Join(t.TempDir(), "clamd.ctl")
+	listener, err := net.Listen("unix", address)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received = make(chan []byte, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				content, err := readInstream(conn)
+				if err != nil {
+					conn.Write([]byte("INSTREAM: " + err.Error() + " ERROR\x00"))
+					return
+				}
+				received <- content
+				conn.Write([]byte(reply(content) + "\x00"))
+			}()
+		}
+	}()
+	return address, received
+}
+
+// readInstream reads a zINSTREAM command and its chunks the way clamd does
+func readInstream(conn net.Conn) ([]byte, error) {
+	command := make([]byte, len("zINSTREAM\x00"))
+	if _, err := io.ReadFull(conn, command); err != nil {
+		return nil, err
+	}
+	if string(command) != "zINSTREAM\x00" {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	var content bytes.Buffer
+	for {
+		var size uint32
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return content.Bytes(), nil
+		}
+		if size > chunkSize {
+			return nil, io.ErrShortBuffer
+		}
+		if _, err := io.CopyN(&content, conn, int64(size)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func TestClamAVStreamsContent(t *testing.T) {
+	address, received := fakeClamd(t, func([]byte) string { return "stream: OK" })
+
+	// More than one chunk, so the chunk framing is exercised
+	content := bytes.Repeat([]byte("0123456789abcdef"), chunkSize/8+3)
+	result, err := NewClamAV("unix", address).Scan(context.Background(), bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if result.Infected {
+		t.Errorf("Scan = %+v, want clean", result)
+	}
+	if got := <-received; !bytes.Equal(got, content) {
+		t.Errorf("clamd received %d bytes, want the %d bytes sent", len(got), len(content))
+	}
+}
+
+func TestClamAVReplies(t *testing.T) {
+	tests := []struct {
+		reply     string
+		infected  bool
+		signature string
+		wantErr   bool
+	}{
+		{reply: "stream: OK"},
+		{reply: "stream: Eicar-Test-Signature FOUND", infected: true, signature: "Eicar-Test-Signature"},
+		{reply: "INSTREAM size limit exceeded. ERROR", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reply, func(t *testing.T) {
+			address, _ := fakeClamd(t, func([]byte) string { return tt.reply })
+
+			result, err := NewClamAV("unix", address).Scan(context.Background(), strings.NewReader("content"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result.Infected != tt.infected || result.Signature != tt.signature {
+				t.Errorf("Scan = %+v, want infected=%v signature=%q", result, tt.infected, tt.signature)
+			}
+		})
+	}
+}
+
+func TestClamAVUnavailable(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "missing.ctl")
+
+	_, err := NewClamAV("unix", address).Scan(context.Background(), strings.NewReader("content"))
+	if err == nil || !strings.Contains(err.Error(), "connect to clamd") {
+		t.Fatalf("Scan without clamd: err = %v, want a connect error", err)
+	}
+}
+
+func TestFakeDetectsEicar(t *testing.T) {
+	result, err := NewFake().Scan(context.Background(), strings.NewReader("prefix "+eicar+" suffix"))
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if !result.Infected || result.Signature != "Eicar-Test-Signature" {
+		t.Errorf("Scan = %+v, want the EICAR signature", result)
+	}
+}
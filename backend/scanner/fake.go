@@ -0,0 +1,40 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// eicar is the standard anti-virus test file
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// Fake reports content as infected when it contains one of its patterns.
+// It stands in for a real scanner in development and tests.
+type Fake struct {
+	// Patterns maps byte patterns to the signature name reported for them
+	Patterns map[string]string
+	// Err, when set, is returned by every scan
+	Err error
+}
+
+// NewFake returns a fake scanner that detects the EICAR test file
+func NewFake() *Fake {
+	return &Fake{Patterns: map[string]string{eicar: "Eicar-Test-Signature"}}
+}
+
+func (s *Fake) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return Result{}, err
+	}
+	if s.Err != nil {
+		return Result{}, s.Err
+	}
+	for pattern, signature := range s.Patterns {
+		if bytes.Contains(content, []byte(pattern)) {
+			return Result{Infected: true, Signature: signature}, nil
+		}
+	}
+	return Result{}, nil
+}
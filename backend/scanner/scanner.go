@@ -0,0 +1,21 @@
+// Package scanner checks uploaded documents for malware before staff open them
+package scanner
+
+import (
+	"context"
+	"io"
+)
+
+// Result is the verdict for one piece of content
+type Result struct {
+	Infected bool
+	// Signature names the malware found, empty when the content is clean
+	Signature string
+}
+
+// Scanner inspects content for malware
+type Scanner interface {
+	// Scan reads r to the end and reports whether it is infected. An error
+	// means no verdict could be reached and the scan should be retried.
+	Scan(ctx context.Context, r io.Reader) (Result, error)
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("storage: object not found")
+
+// ErrPresignUnsupported is returned by backends that cannot hand out URLs
+// for reading an object directly
+var ErrPresignUnsupported = errors.New("storage: presigned URLs are not supported")
+
+// Storage stores uploaded documents as opaque objects addressed by key
+type Storage interface {
+	// Put stores the content of r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// Presign returns a URL that allows reading the object without other credentials until it expires
+	Presign(ctx context.Context, key string, expires time.Duration) (string, error)
+}
@@ -0,0 +1,180 @@
+// Package identity validates the Indonesian identity numbers and contact
+// details of applicants: the 16-digit NIK, the 10-digit NISN, phone numbers
+// normalized to E.164 and ISO dates.
+package identity
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Sexes encoded in a NIK
+const (
+	Male   = "L"
+	Female = "P"
+)
+
+// DateLayout is the ISO 8601 calendar date format
+const DateLayout = "2006-01-02"
+
+var (
+	ErrNIKFormat      = errors.New("must be 16 digits")
+	ErrNIKRegion      = errors.New("has an unknown province, regency or district code")
+	ErrNIKBirthDate   = errors.New("has an invalid birth date")
+	ErrNIKSerial      = errors.New("has an invalid serial number")
+	ErrNISNFormat     = errors.New("must be 10 digits")
+	ErrPhoneFormat    = errors.New("must be a phone number such as +6281234567890 or 081234567890")
+	ErrDateFormat     = errors.New("must be a date in YYYY-MM-DD format")
+	ErrDateOutOfRange = errors.New("must not be in the future or before 1900")
+)
+
+// provinceCodes lists the province codes used in NIKs
+var provinceCodes = map[string]bool{
+	"11": true, "12": true, "13": true, "14": true, "15": true, "16": true, "17": true, "18": true, "19": true,
+	"21": true,
+	"31": true, "32": true, "33": true, "34": true, "35": true, "36": true,
+	"51": true, "52": true, "53": true,
+	"61": true, "62": true, "63": true, "64": true, "65": true,
+	"71": true, "72": true, "73": true, "74": true, "75": true, "76": true,
+	"81": true, "82": true,
+	"91": true, "92": true, "93": true, "94": true, "95": true, "96": true,
+}
+
+// NIK is a parsed Nomor Induk Kependudukan
+type NIK struct {
+	Number   string
+	Province string
+	Regency  string
+	District string
+	// Birth day and month, and the last two digits of the birth year
+	BirthDay   int
+	BirthMonth int
+	BirthYear  int
+	Sex        string
+	Serial     string
+}
+
+func digitsOnly(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func atoi2(s string) int {
+	return int(s[0]-'0')*10 + int(s[1]-'0')
+}
+
+// ParseNIK checks the structure of a NIK: region codes, the embedded birth
+// date (with 40 added to the day for women) and the serial number
+func ParseNIK(s string) (NIK, error) {
+	s = strings.TrimSpace(s)
+	if len(s) != 16 || !digitsOnly(s) {
+		return NIK{}, ErrNIKFormat
+	}
+
+	nik := NIK{
+		Number:     s,
+		Province:   s[0:2],
+		Regency:    s[2:4],
+		District:   s[4:6],
+		BirthDay:   atoi2(s[6:8]),
+		BirthMonth: atoi2(s[8:10]),
+		BirthYear:  atoi2(s[10:12]),
+		Sex:        Male,
+		Serial:     s[12:16],
+	}
+	if !provinceCodes[nik.Province] || nik.Regency == "00" || nik.District == "00" {
+		return NIK{}, ErrNIKRegion
+	}
+	if nik.BirthDay > 40 {
+		nik.BirthDay -= 40
+		nik.Sex = Female
+	}
+	if nik.BirthMonth < 1 || nik.BirthMonth > 12 || nik.BirthDay < 1 || nik.BirthDay > daysIn(nik.BirthMonth, nik.fullYear()) {
+		return NIK{}, ErrNIKBirthDate
+	}
+	if nik.Serial == "0000" {
+		return NIK{}, ErrNIKSerial
+	}
+	return nik, nil
+}
+
+// fullYear guesses the century of the two-digit birth year: years that
+// would lie in the future belong to the previous century
+func (n NIK) fullYear() int {
+	year := 2000 + n.BirthYear
+	if year > time.Now().Year() {
+		year -= 100
+	}
+	return year
+}
+
+// MatchesBirthDate reports whether date agrees with the birth date
+// embedded in the NIK
+func (n NIK) MatchesBirthDate(date time.Time) bool {
+	return date.Day() == n.BirthDay && int(date.Month()) == n.BirthMonth && date.Year()%100 == n.BirthYear
+}
+
+func daysIn(month, year int) int {
+	return time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// ValidateNISN checks a Nomor Induk Siswa Nasional
+func ValidateNISN(s string) error {
+	s = strings.TrimSpace(s)
+	if len(s) != 10 || !digitsOnly(s) {
+		return ErrNISNFormat
+	}
+	return nil
+}
+
+// NormalizePhone converts a phone number to E.164. Numbers without a
+// country code are taken to be Indonesian, written either with the
+// national 0 prefix or without it.
+func NormalizePhone(s string) (string, error) {
+	cleaned := strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(strings.TrimSpace(s))
+
+	var digits string
+	switch {
+	case strings.HasPrefix(cleaned, "+"):
+		digits = cleaned[1:]
+	case strings.HasPrefix(cleaned, "00"):
+		digits = cleaned[2:]
+	case strings.HasPrefix(cleaned, "0"):
+		digits = "62" + cleaned[1:]
+	case strings.HasPrefix(cleaned, "62"):
+		digits = cleaned
+	default:
+		digits = "62" + cleaned
+	}
+	if !digitsOnly(digits) || digits[0] == '0' || len(digits) > 15 {
+		return "", ErrPhoneFormat
+	}
+
+	if national, ok := strings.CutPrefix(digits, "62"); ok {
+		// Indonesian subscriber numbers have 8 to 12 digits after the
+		// country code and never start with 0
+		if len(national) < 8 || len(national) > 12 || national[0] == '0' {
+			return "", ErrPhoneFormat
+		}
+	} else if len(digits) < 8 {
+		return "", ErrPhoneFormat
+	}
+	return "+" + digits, nil
+}
+
+// ParseDate parses an ISO calendar date that must not lie in the future
+func ParseDate(s string) (time.Time, error) {
+	date, err := time.Parse(DateLayout, strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, ErrDateFormat
+	}
+	if date.Year() < 1900 || date.After(time.Now()) {
+		return time.Time{}, ErrDateOutOfRange
+	}
+	return date, nil
+}
@@ -0,0 +1,199 @@
+package identity
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseNIK(t *testing.T) {
+	tests := []struct {
+		nik     string
+		want    NIK
+		wantErr error
+	}{
+		{
+			nik:  "3173051203980002",
+			want: NIK{Number: "3173051203980002", Province: "31", Regency: "73", District: "05", BirthDay: 12, BirthMonth: 3, BirthYear: 98, Sex: Male, Serial: "0002"},
+		},
+		// Women have 40 added to the birth day
+		{
+			nik:  "3273015208050001",
+			want: NIK{Number: "3273015208050001", Province: "32", Regency: "73", District: "01", BirthDay: 12, BirthMonth: 8, BirthYear: 5, Sex: Female, Serial: "0001"},
+		},
+		{
+			nik:  " 3273017112990123 ",
+			want: NIK{Number: "3273017112990123", Province: "32", Regency: "73", District: "01", BirthDay: 31, BirthMonth: 12, BirthYear: 99, Sex: Female, Serial: "0123"},
+		},
+		{nik: "3273014112990123", want: NIK{Number: "3273014112990123", Province: "32", Regency: "73", District: "01", BirthDay: 1, BirthMonth: 12, BirthYear: 99, Sex: Female, Serial: "0123"}},
+
+		{nik: "327301120398000", wantErr: ErrNIKFormat},
+		{nik: "32730112039800021", wantErr: ErrNIKFormat},
+		{nik: "32730112039800a2", wantErr: ErrNIKFormat},
+		{nik: "", wantErr: ErrNIKFormat},
+		{nik: "9973051203980002", wantErr: ErrNIKRegion},
+		{nik: "3100051203980002", wantErr: ErrNIKRegion},
+		{nik: "3173001203980002", wantErr: ErrNIKRegion},
+		{nik: "3173050013980002", wantErr: ErrNIKBirthDate},
+		{nik: "3173053204980002", wantErr: ErrNIKBirthDate},
+		{nik: "3173057204980002", wantErr: ErrNIKBirthDate},
+		{nik: "3173054000980002", wantErr: ErrNIKBirthDate},
+		{nik: "3173051200980002", wantErr: ErrNIKBirthDate},
+		{nik: "3173051213980002", wantErr: ErrNIKBirthDate},
+		{nik: "3173051203980000", wantErr: ErrNIKSerial},
+	}
+	for _, tt := range tests {
+		got, err := ParseNIK(tt.nik)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseNIK(%q) error = %v, want %v", tt.nik, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseNIK(%q) = %+v, want %+v", tt.nik, got, tt.want)
+		}
+	}
+}
+
+func TestParseNIKCentury(t *testing.T) {
+	// 29 February only exists in leap years, so it shows which century a
+	// two-digit year was taken to be in
+	tests := []struct {
+		year  string
+		valid bool
+	}{
+		{"00", true},  // 2000
+		{"04", true},  // 2004
+		{"01", false}, // 2001
+		{"96", true},  // 1996
+		{"97", false}, // 1997
+	}
+	for _, tt := range tests {
+		for _, day := range []string{"29", "69"} {
+			nik := "317305" + day + "02" + tt.year + "0001"
+			if _, err := ParseNIK(nik); (err == nil) != tt.valid {
+				t.Errorf("ParseNIK(%q) error = %v, want valid=%v", nik, err, tt.valid)
+			}
+		}
+	}
+
+	now := time.Now().Year()
+	for _, tt := range []struct {
+		year int
+		want int
+	}{
+		{now % 100, now},
+		{(now - 1) % 100, now - 1},
+		// A year that would lie in the future belongs to the previous century
+		{(now + 1) % 100, now + 1 - 100},
+		{99, 1999},
+	} {
+		if got := (NIK{BirthYear: tt.year}).fullYear(); got != tt.want {
+			t.Errorf("fullYear of %02d = %d, want %d", tt.year, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesBirthDate(t *testing.T) {
+	nik, err := ParseNIK("3273015208050001")
+	if err != nil {
+		t.Fatalf("ParseNIK: %v", err)
+	}
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"2005-08-12", true},
+		{"2005-08-13", false},
+		{"2005-09-12", false},
+		{"2006-08-12", false},
+	}
+	for _, tt := range tests {
+		date, _ := time.Parse(DateLayout, tt.date)
+		if got := nik.MatchesBirthDate(date); got != tt.want {
+			t.Errorf("MatchesBirthDate(%s) = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestValidateNISN(t *testing.T) {
+	tests := []struct {
+		nisn string
+		want error
+	}{
+		{"0051234567", nil},
+		{" 0051234567 ", nil},
+		{"005123456", ErrNISNFormat},
+		{"00512345678", ErrNISNFormat},
+		{"00512345a7", ErrNISNFormat},
+		{"", ErrNISNFormat},
+	}
+	for _, tt := range tests {
+		if err := ValidateNISN(tt.nisn); !errors.Is(err, tt.want) {
+			t.Errorf("ValidateNISN(%q) = %v, want %v", tt.nisn, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+		err   error
+	}{
+		{"081234567890", "+6281234567890", nil},
+		{"0812-3456-7890", "+6281234567890", nil},
+		{"(0812) 3456 7890", "+6281234567890", nil},
+		{"+62 812 3456 7890", "+6281234567890", nil},
+		{"6281234567890", "+6281234567890", nil},
+		{"0062.812.3456.7890", "+6281234567890", nil},
+		{"81234567890", "+6281234567890", nil},
+		{"02112345678", "+622112345678", nil},
+		{"+6512345678", "+6512345678", nil},
+
+		{"", "", ErrPhoneFormat},
+		{"0812345", "", ErrPhoneFormat},
+		{"0812345678901234", "", ErrPhoneFormat},
+		{"+62081234567890", "", ErrPhoneFormat},
+		{"0812abc67890", "", ErrPhoneFormat},
+		{"+0812345678", "", ErrPhoneFormat},
+		{"+1234567", "", ErrPhoneFormat},
+		{"+1234567890123456", "", ErrPhoneFormat},
+	}
+	for _, tt := range tests {
+		got, err := NormalizePhone(tt.phone)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("NormalizePhone(%q) = %q, %v, want %q, %v", tt.phone, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(DateLayout)
+	tests := []struct {
+		date string
+		want string
+		err  error
+	}{
+		{"2005-08-12", "2005-08-12", nil},
+		{" 1900-01-01 ", "1900-01-01", nil},
+		{"2000-02-29", "2000-02-29", nil},
+
+		{"1899-12-31", "", ErrDateOutOfRange},
+		{tomorrow, "", ErrDateOutOfRange},
+		{"2001-02-29", "", ErrDateFormat},
+		{"12-08-2005", "", ErrDateFormat},
+		{"2005/08/12", "", ErrDateFormat},
+		{"2005-8-12", "", ErrDateFormat},
+		{"", "", ErrDateFormat},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.date)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("ParseDate(%q) error = %v, want %v", tt.date, err, tt.err)
+			continue
+		}
+		if err == nil && got.Format(DateLayout) != tt.want {
+			t.Errorf("ParseDate(%q) = %s, want %s", tt.date, got.Format(DateLayout), tt.want)
+		}
+	}
+}
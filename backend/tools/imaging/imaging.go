@@ -0,0 +1,255 @@
+// Package imaging normalizes uploaded document images: it applies the EXIF
+// orientation, drops all metadata by re-encoding, downscales large scans and
+// produces thumbnails. Only the standard library is used.
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+)
+
+// Content types produced by Normalize
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+)
+
+// MaxPixels bounds the decoded size of an image so a small, highly
+// compressed upload cannot exhaust memory. 12 megapixels covers phone
+// photos and 300 dpi scans of ID cards and A4 certificates; decoded as RGBA
+// such an image takes about 48MB.
+const MaxPixels = 12_000_000
+
+// MaxConcurrent bounds how many images Normalize decodes at once, so that
+// parallel uploads use at most a few times the memory of one
+const MaxConcurrent = 4
+
+// slots holds one token per image being decoded
+var slots = make(chan struct{}, MaxConcurrent)
+
+var (
+	// ErrUnsupported is returned for content that is not a JPEG or PNG image
+	ErrUnsupported = errors.New("imaging: unsupported image format")
+	// ErrTooManyPixels is returned for images larger than MaxPixels
+	ErrTooManyPixels = errors.New("imaging: image dimensions too large")
+)
+
+// Normalize decodes a JPEG or PNG image, applies its EXIF orientation,
+// scales it down so neither side exceeds maxSide (0 keeps the size) and
+// re-encodes it without any metadata. It returns the decoded, oriented
+// image alongside the encoded bytes so callers can derive thumbnails.
+func Normalize(content []byte, contentType string, maxSide int) ([]byte, image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, nil, ErrTooManyPixels
+	}
+	slots <- struct{}{}
+	defer func() { <-slots }()
+
+	var img image.Image
+	switch contentType {
+	case TypeJPEG:
+		img, err = jpeg.Decode(bytes.NewReader(content))
+		if err == nil {
+			img = applyOrientation(toRGBA(img), exifOrientation(content))
+		}
+	case TypePNG:
+		img, err = png.Decode(bytes.NewReader(content))
+	default:
+		return nil, nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	img = Fit(img, maxSide)
+
+	encoded, err := Encode(img, contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encoded, img, nil
+}
+
+// Encode writes img in the given format. Go's encoders write no EXIF or
+// other metadata, which is what strips camera and GPS information.
+func Encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == TypePNG {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	}
+	return buf.Bytes(), err
+}
+
+// Thumbnail returns a JPEG no larger than side x side
+func Thumbnail(img image.Image, side int) ([]byte, error) {
+	return Encode(Fit(img, side), TypeJPEG)
+}
+
+// Fit scales img down, keeping its aspect ratio, so that neither side exceeds
+// maxSide. Smaller images and maxSide <= 0 return img unchanged.
+func Fit(img image.Image, maxSide int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if maxSide <= 0 || (w <= maxSide && h <= maxSide) {
+		return img
+	}
+	if w >= h {
+		h = max(1, h*maxSide/w)
+		w = maxSide
+	} else {
+		w = max(1, w*maxSide/h)
+		h = maxSide
+	}
+	return Resize(img, w, h)
+}
+
+// Resize scales img to w x h by averaging the source pixels covered by each
+// destination pixel, which keeps text on scanned documents legible
+func Resize(img image.Image, w, h int) *image.RGBA {
+	src := toRGBA(img)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0 := y * sh / h
+		y1 := max(y0+1, (y+1)*sh/h)
+		for x := 0; x < w; x++ {
+			x0 := x * sw / w
+			x1 := max(x0+1, (x+1)*sw/w)
+
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				i := sy*src.Stride + x0*4
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					bl += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+
+			j := y*dst.Stride + x*4
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(bl / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// toRGBA returns img as an *image.RGBA whose bounds start at the origin
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// exifOrientation returns the EXIF orientation (1-8) of a JPEG, or 1 when absent
+func exifOrientation(content []byte) int {
+	// Walk the JPEG segments up to the start of scan looking for APP1/Exif
+	i := 2
+	for i+4 <= len(content) && content[i] == 0xFF {
+		marker := content[i+1]
+		length := int(binary.BigEndian.Uint16(content[i+2:]))
+		if marker == 0xDA || length < 2 || i+2+length > len(content) {
+			break
+		}
+		segment := content[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 14 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag (0x0112) from IFD0 of a TIFF block
+func tiffOrientation(tiff []byte) int {
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for e := 0; e < entries; e++ {
+		p := ifd + 2 + e*12
+		if p+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[p:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[p+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation rotates and flips img so it displays upright without EXIF
+func applyOrientation(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			si := y*img.Stride + x*4
+			di := dy*dst.Stride + dx*4
+			copy(dst.Pix[di:di+4], img.Pix[si:si+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,191 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// gpsMarker stands in for the GPS coordinates a phone camera writes
+const gpsMarker = "GPS -6.2088 106.8456"
+
+// exifTIFF builds a TIFF block whose IFD0 holds the orientation tag and a
+// free-text entry carrying gpsMarker
+func exifTIFF(order binary.ByteOrder, orientation int) []byte {
+	var b bytes.Buffer
+	if order == binary.LittleEndian {
+		b.WriteString("II")
+	} else {
+		b.WriteString("MM")
+	}
+	binary.Write(&b, order, uint16(42))
+	binary.Write(&b, order, uint32(8)) // IFD0 right after the header
+
+	text := gpsMarker + "\x00"
+	textOffset := uint32(8 + 2 + 2*12 + 4)
+	binary.Write(&b, order, uint16(2))
+	// Orientation: SHORT, 1 value, stored in the first half of the value field
+	binary.Write(&b, order, uint16(0x0112))
+	binary.Write(&b, order, uint16(3))
+	binary.Write(&b, order, uint32(1))
+	binary.Write(&b, order, uint16(orientation))
+	binary.Write(&b, order, uint16(0))
+	// ImageDescription: ASCII stored after the IFD
+	binary.Write(&b, order, uint16(0x010E))
+	binary.Write(&b, order, uint16(2))
+	binary.Write(&b, order, uint32(len(text)))
+	binary.Write(&b, order, textOffset)
+	binary.Write(&b, order, uint32(0)) // no next IFD
+	b.WriteString(text)
+	return b.Bytes()
+}
+
+// withAPP1 inserts an APP1/Exif segment holding tiff after the SOI marker
+func withAPP1(jpg, tiff []byte) []byte {
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	var b bytes.Buffer
+	b.Write(jpg[:2])
+	b.Write([]byte{0xFF, 0xE1})
+	binary.Write(&b, binary.BigEndian, uint16(len(segment)+2))
+	b.Write(segment)
+	b.Write(jpg[2:])
+	return b.Bytes()
+}
+
+// testJPEG encodes a w x h JPEG with a horizontal gradient
+func testJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 255 / w), 128, 64, 255})
+		}
+	}
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, nil); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return b.Bytes()
+}
+
+func TestExifOrientation(t *testing.T) {
+	jpg := testJPEG(t, 8, 8)
+	truncated := exifTIFF(binary.LittleEndian, 6)[:8+2+6] // entry count says 2, half an entry follows
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    int
+	}{
+		{"no exif", jpg, 1},
+		{"little endian", withAPP1(jpg, exifTIFF(binary.LittleEndian, 6)), 6},
+		{"big endian", withAPP1(jpg, exifTIFF(binary.BigEndian, 8)), 8},
+		{"big endian mirrored", withAPP1(jpg, exifTIFF(binary.BigEndian, 2)), 2},
+		{"out of range", withAPP1(jpg, exifTIFF(binary.LittleEndian, 9)), 1},
+		{"truncated ifd", withAPP1(jpg, truncated), 1},
+		{"unknown byte order", withAPP1(jpg, append([]byte("XX"), exifTIFF(binary.LittleEndian, 6)[2:]...)), 1},
+		{"ifd past the end", withAPP1(jpg, []byte("II*\x00\xff\xff\x00\x00\x00\x00")), 1},
+		{"segment longer than the file", withAPP1(jpg, exifTIFF(binary.LittleEndian, 6))[:30], 1},
+	}
+	for _, tt := range tests {
+		if got := exifOrientation(tt.content); got != tt.want {
+			t.Errorf("%s: orientation %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// A 3x2 image whose pixels are numbered 1-6 in the red channel:
+	//   1 2 3
+	//   4 5 6
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < 6; i++ {
+		src.Set(i%3, i/3, color.RGBA{uint8(i + 1), 0, 0, 255})
+	}
+
+	tests := []struct {
+		orientation int
+		want        [][]uint8
+	}{
+		{1, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+		{2, [][]uint8{{3, 2, 1}, {6, 5, 4}}},
+		{3, [][]uint8{{6, 5, 4}, {3, 2, 1}}},
+		{4, [][]uint8{{4, 5, 6}, {1, 2, 3}}},
+		{5, [][]uint8{{1, 4}, {2, 5}, {3, 6}}},
+		{6, [][]uint8{{4, 1}, {5, 2}, {6, 3}}},
+		{7, [][]uint8{{6, 3}, {5, 2}, {4, 1}}},
+		{8, [][]uint8{{3, 6}, {2, 5}, {1, 4}}},
+	}
+	for _, tt := range tests {
+		got := applyOrientation(src, tt.orientation)
+		b := got.Bounds()
+		rows := make([][]uint8, b.Dy())
+		for y := range rows {
+			for x := 0; x < b.Dx(); x++ {
+				rows[y] = append(rows[y], got.RGBAAt(x, y).R)
+			}
+		}
+		if fmt.Sprint(rows) != fmt.Sprint(tt.want) {
+			t.Errorf("orientation %d: %v, want %v", tt.orientation, rows, tt.want)
+		}
+	}
+}
+
+func TestNormalizeOrientsAndStripsMetadata(t *testing.T) {
+	content := withAPP1(testJPEG(t, 16, 8), exifTIFF(binary.BigEndian, 6))
+	if !bytes.Contains(content, []byte(gpsMarker)) {
+		t.Fatal("fixture carries no metadata")
+	}
+
+	encoded, img, err := Normalize(content, TypeJPEG, 0)
+	if err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 8 || b.Dy() != 16 {
+		t.Errorf("oriented size %dx%d, want 8x16", b.Dx(), b.Dy())
+	}
+	if bytes.Contains(encoded, []byte("Exif")) || bytes.Contains(encoded, []byte(gpsMarker)) {
+		t.Error("re-encoded image still carries EXIF metadata")
+	}
+	if exifOrientation(encoded) != 1 {
+		t.Error("re-encoded image still carries an orientation")
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(encoded))
+	if err != nil || cfg.Width != 8 || cfg.Height != 16 {
+		t.Errorf("re-encoded image %dx%d (%v), want 8x16", cfg.Width, cfg.Height, err)
+	}
+}
+
+// withSize rewrites the dimensions in the SOF0 header of a baseline JPEG
+func withSize(t *testing.T, jpg []byte, w, h int) []byte {
+	t.Helper()
+	out := append([]byte(nil), jpg...)
+	i := bytes.Index(out, []byte{0xFF, 0xC0})
+	if i < 0 {
+		t.Fatal("no SOF0 marker")
+	}
+	binary.BigEndian.PutUint16(out[i+5:], uint16(h))
+	binary.BigEndian.PutUint16(out[i+7:], uint16(w))
+	return out
+}
+
+func TestNormalizeRejectsTooManyPixels(t *testing.T) {
+	jpg := testJPEG(t, 8, 8)
+
+	// The header alone decides: the pixel data is never decoded
+	if _, _, err := Normalize(withSize(t, jpg, 4000, 3001), TypeJPEG, 0); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("4000x3001: err = %v, want ErrTooManyPixels", err)
+	}
+	if _, _, err := Normalize(withSize(t, jpg, 60000, 60000), TypeJPEG, 0); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("60000x60000: err = %v, want ErrTooManyPixels", err)
+	}
+	// At the limit the size is accepted; this fixture then fails to decode
+	if _, _, err := Normalize(withSize(t, jpg, 4000, 3000), TypeJPEG, 0); errors.Is(err, ErrTooManyPixels) {
+		t.Error("4000x3000 rejected as too large")
+	}
+}
@@ -0,0 +1,474 @@
+// Package scoring ranks scholarship applications on a 1-10 scale and
+// measures how complete they are. The
+// weights, income brackets and thresholds live in Rules so they can be
+// versioned and edited without changing the code.
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// MaxRank is the best rank an application can get
+const MaxRank = 10
+
+// Weights divide the total score between the score categories
+type Weights struct {
+	Personal float64 `json:"personal"`
+	Academic float64 `json:"academic"`
+	Family   float64 `json:"family"`
+}
+
+// Bracket awards points to a value up to and including Max
+type Bracket struct {
+	Max    float64 `json:"max"`
+	Points float64 `json:"points"`
+}
+
+// Threshold awards points to a value of at least Min
+type Threshold struct {
+	Min    float64 `json:"min"`
+	Points float64 `json:"points"`
+}
+
+// Rules are the adjustable parameters of the scoring. Every filled-in field
+// is worth one point; brackets and thresholds award points in the same
+// unit.
+type Rules struct {
+	Weights Weights `json:"weights"`
+	// Points for each parent's monthly income; incomes above the last
+	// bracket earn nothing
+	IncomeBrackets []Bracket `json:"income_brackets"`
+	// Points for the average grade on the 0-100 scale
+	GradeThresholds []Threshold `json:"grade_thresholds"`
+	// Points for the number of siblings the family supports
+	DependentThresholds []Threshold `json:"dependent_thresholds"`
+	// When set, the family's income is scored per household member
+	// instead of each parent's income on its own
+	Household *HouseholdRules `json:"household,omitempty"`
+	// When set, filled-in fields earn no points and the score measures
+	// merit and need only; how complete an application is is measured by
+	// Completeness instead. Personal data then has nothing to score, so
+	// its weight must be 0.
+	MeritOnly bool `json:"merit_only,omitempty"`
+}
+
+// HouseholdRules score the monthly income per household member. The
+// maxima of the brackets are in rupiah, or with RelativeToUMR fractions of
+// the regional minimum wage (UMR). The per-capita income is scored once in
+// place of the two parents' incomes, so its best bracket is usually worth
+// two points.
+type HouseholdRules struct {
+	PerCapitaBrackets []Bracket `json:"per_capita_brackets"`
+	RelativeToUMR     bool      `json:"relative_to_umr"`
+	// Minimum wage used when the applicant's region has none on record
+	FallbackUMR float64 `json:"fallback_umr"`
+}
+
+// DefaultRules are the first versioned rules: 10% personal, 40% academic and
+// 50% family data, with incomes below 1jt, up to 2jt and up to 5jt, average
+// grades of 80 and 60 and the number of dependents. Ranks given before the
+// rules were versioned came from an older formula that these rules do not
+// reproduce.
+func DefaultRules() Rules {
+	return Rules{
+		Weights: Weights{Personal: 0.10, Academic: 0.40, Family: 0.50},
+		IncomeBrackets: []Bracket{
+			{Max: 999999, Points: 1},
+			{Max: 2000000, Points: 0.6},
+			{Max: 5000000, Points: 0.3},
+		},
+		GradeThresholds: []Threshold{
+			{Min: 80, Points: 2},
+			{Min: 60, Points: 1},
+		},
+		DependentThresholds: []Threshold{
+			{Min: 3, Points: 1},
+			{Min: 2, Points: 0.7},
+			{Min: 1, Points: 0.4},
+		},
+	}
+}
+
+// PerCapitaRules are DefaultRules with the family's income scored per
+// household member instead of each parent's income: up to a quarter, half
+// and the whole regional minimum wage. Regions without a minimum wage on
+// record use 2.8jt, about the average of the 2024 provincial wages.
+func PerCapitaRules() Rules {
+	rules := DefaultRules()
+	rules.Household = &HouseholdRules{
+		PerCapitaBrackets: []Bracket{
+			{Max: 0.25, Points: 2},
+			{Max: 0.5, Points: 1.2},
+			{Max: 1, Points: 0.6},
+		},
+		RelativeToUMR: true,
+		FallbackUMR:   2800000,
+	}
+	return rules
+}
+
+// MeritRules are PerCapitaRules scoring merit and need only: filled-in
+// fields earn nothing, and the personal weight goes to academic and family
+// data in about the proportion they had
+func MeritRules() Rules {
+	rules := PerCapitaRules()
+	rules.MeritOnly = true
+	rules.Weights = Weights{Personal: 0, Academic: 0.45, Family: 0.55}
+	return rules
+}
+
+// Validate checks that the weights add up to 1 and that no bracket or
+// threshold is negative or listed twice
+func (r Rules) Validate() error {
+	w := r.Weights
+	if w.Personal < 0 || w.Academic < 0 || w.Family < 0 {
+		return errors.New("weights must not be negative")
+	}
+	if math.Abs(w.Personal+w.Academic+w.Family-1) > 0.001 {
+		return fmt.Errorf("weights must add up to 1, not %.3f", w.Personal+w.Academic+w.Family)
+	}
+	if r.MeritOnly && w.Personal > 0 {
+		return errors.New("personal weight must be 0 for merit_only rules")
+	}
+
+	brackets := map[string][]Bracket{"income": r.IncomeBrackets}
+	if r.Household != nil {
+		brackets["per capita income"] = r.Household.PerCapitaBrackets
+		if r.Household.RelativeToUMR && r.Household.FallbackUMR <= 0 {
+			return errors.New("household fallback_umr is required for brackets relative to the UMR")
+		}
+	}
+	for name, list := range brackets {
+		maxima := map[float64]bool{}
+		for _, b := range list {
+			if b.Max < 0 || b.Points < 0 {
+				return fmt.Errorf("%s brackets must not be negative", name)
+			}
+			if maxima[b.Max] {
+				return fmt.Errorf("%s bracket %g is listed twice", name, b.Max)
+			}
+			maxima[b.Max] = true
+		}
+	}
+	for name, thresholds := range map[string][]Threshold{"grade": r.GradeThresholds, "dependent": r.DependentThresholds} {
+		minima := map[float64]bool{}
+		for _, t := range thresholds {
+			if t.Min < 0 || t.Points < 0 {
+				return fmt.Errorf("%s thresholds must not be negative", name)
+			}
+			if minima[t.Min] {
+				return fmt.Errorf("%s threshold %g is listed twice", name, t.Min)
+			}
+			minima[t.Min] = true
+		}
+	}
+	return nil
+}
+
+// Field is a field of the application and whether it was filled in
+type Field struct {
+	Name   string
+	Filled bool
+}
+
+// Applicant holds the application data the scoring looks at
+type Applicant struct {
+	PersonalFields []Field
+
+	SchoolName     string
+	GraduationYear string
+	// Average of all semester grades on the 0-100 scale; nil when unknown
+	AverageGrade *float64
+	HasIjazah    bool
+
+	MotherJob     string
+	MotherIncome  int64
+	FatherJob     string
+	FatherIncome  int64
+	FamilyAddress string
+	Dependents    int
+	// Parents living in the household
+	Parents int
+	// Monthly minimum wage of the applicant's region; 0 when unknown
+	UMR float64
+}
+
+// Household is the economic situation of an applicant's household
+type Household struct {
+	Income  int64 `json:"income"`
+	Members int   `json:"members"`
+	// Monthly income per household member
+	PerCapita float64 `json:"per_capita"`
+	UMR       float64 `json:"umr"`
+	// PerCapita as a fraction of UMR; 0 when the UMR is unknown
+	PerCapitaUMR float64 `json:"per_capita_umr"`
+}
+
+// HouseholdOf works out the household of an applicant: the applicant, the
+// parents and the siblings the family supports share the parents' income.
+// fallbackUMR is used when the applicant's region has no minimum wage.
+func HouseholdOf(a Applicant, fallbackUMR float64) Household {
+	h := Household{
+		Income:  max(a.MotherIncome, 0) + max(a.FatherIncome, 0),
+		Members: 1 + max(a.Parents, 0) + max(a.Dependents, 0),
+		UMR:     a.UMR,
+	}
+	if h.UMR <= 0 {
+		h.UMR = fallbackUMR
+	}
+	h.PerCapita = float64(h.Income) / float64(h.Members)
+	if h.UMR > 0 {
+		h.PerCapitaUMR = h.PerCapita / h.UMR
+	}
+	return h
+}
+
+// Score categories
+const (
+	Personal = "personal"
+	Academic = "academic"
+	Family   = "family"
+)
+
+// Item explains one criterion of a score: the value looked at, the rule
+// that matched it and the points it earned out of the points possible
+type Item struct {
+	Category  string  `json:"category"`
+	Criterion string  `json:"criterion"`
+	Value     any     `json:"value"`
+	Rule      string  `json:"rule"`
+	Earned    float64 `json:"earned"`
+	Possible  float64 `json:"possible"`
+}
+
+// CategoryScore is the score of one category and its share of the total
+type CategoryScore struct {
+	Category string  `json:"category"`
+	Earned   float64 `json:"earned"`
+	Possible float64 `json:"possible"`
+	// Earned out of Possible, from 0 to 1
+	Score    float64 `json:"score"`
+	Weight   float64 `json:"weight"`
+	Weighted float64 `json:"weighted"`
+}
+
+// Result is the score of an application. Category scores and the total
+// run from 0 to 1.
+type Result struct {
+	Personal float64 `json:"personal"`
+	Academic float64 `json:"academic"`
+	Family   float64 `json:"family"`
+	Total    float64 `json:"total"`
+	Rank     int     `json:"rank"`
+
+	Categories  []CategoryScore `json:"categories"`
+	Items       []Item          `json:"items"`
+	Explanation string          `json:"explanation"`
+}
+
+// matchBracket returns the lowest bracket holding value
+func matchBracket(brackets []Bracket, value float64) (Bracket, bool) {
+	sorted := append([]Bracket(nil), brackets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Max < sorted[j].Max })
+	for _, b := range sorted {
+		if value <= b.Max {
+			return b, true
+		}
+	}
+	return Bracket{}, false
+}
+
+// matchThreshold returns the highest threshold value reaches
+func matchThreshold(thresholds []Threshold, value float64) (Threshold, bool) {
+	sorted := append([]Threshold(nil), thresholds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Min > sorted[j].Min })
+	for _, t := range sorted {
+		if value >= t.Min {
+			return t, true
+		}
+	}
+	return Threshold{}, false
+}
+
+func maxBracketPoints(brackets []Bracket) float64 {
+	best := 0.0
+	for _, b := range brackets {
+		best = max(best, b.Points)
+	}
+	return best
+}
+
+func maxThresholdPoints(thresholds []Threshold) float64 {
+	best := 0.0
+	for _, t := range thresholds {
+		best = max(best, t.Points)
+	}
+	return best
+}
+
+// tally adds up the points of one score category
+type tally struct {
+	category         string
+	earned, possible float64
+	items            []Item
+	// skipFields leaves out the points for filled-in fields
+	skipFields bool
+}
+
+func (t *tally) add(criterion string, value any, rule string, earned, possible float64) {
+	t.earned += earned
+	t.possible += possible
+	t.items = append(t.items, Item{
+		Category:  t.category,
+		Criterion: criterion,
+		Value:     value,
+		Rule:      rule,
+		Earned:    earned,
+		Possible:  possible,
+	})
+}
+
+func (t *tally) field(criterion string, value any, filled bool) {
+	switch {
+	case t.skipFields:
+		return
+	case filled:
+		t.add(criterion, value, "filled in", 1, 1)
+	default:
+		t.add(criterion, value, "missing", 0, 1)
+	}
+}
+
+// number formats a rule value without an exponent
+func number(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// bracket scores value by the bracket it falls in
+func (t *tally) bracket(criterion string, value float64, unit string, brackets []Bracket) {
+	possible := maxBracketPoints(brackets)
+	if b, ok := matchBracket(brackets, value); ok {
+		t.add(criterion, value, fmt.Sprintf("up to %s%s earns %s", number(b.Max), unit, number(b.Points)), b.Points, possible)
+	} else {
+		t.add(criterion, value, "above every bracket", 0, possible)
+	}
+}
+
+// threshold scores value by the highest threshold it reaches
+func (t *tally) threshold(criterion string, value float64, thresholds []Threshold) {
+	possible := maxThresholdPoints(thresholds)
+	if th, ok := matchThreshold(thresholds, value); ok {
+		t.add(criterion, value, fmt.Sprintf("at least %s earns %s", number(th.Min), number(th.Points)), th.Points, possible)
+	} else {
+		t.add(criterion, value, "below every threshold", 0, possible)
+	}
+}
+
+func (t tally) score() float64 {
+	if t.possible == 0 {
+		return 0
+	}
+	return t.earned / t.possible
+}
+
+// Score ranks an application under the given rules
+func Score(rules Rules, a Applicant) Result {
+	personal := tally{category: Personal, skipFields: rules.MeritOnly}
+	for _, f := range a.PersonalFields {
+		personal.field(f.Name, f.Filled, f.Filled)
+	}
+
+	academic := tally{category: Academic, skipFields: rules.MeritOnly}
+	academic.field("asal_sekolah", a.SchoolName, a.SchoolName != "")
+	academic.field("tahun_lulus", a.GraduationYear, a.GraduationYear != "")
+	if a.AverageGrade != nil {
+		academic.threshold("rata_rata_nilai", *a.AverageGrade, rules.GradeThresholds)
+	} else {
+		academic.add("rata_rata_nilai", nil, "no grades given", 0, maxThresholdPoints(rules.GradeThresholds))
+	}
+	academic.field("foto_ijazah", a.HasIjazah, a.HasIjazah)
+
+	family := tally{category: Family, skipFields: rules.MeritOnly}
+	family.field("pekerjaan_ibu", a.MotherJob, a.MotherJob != "")
+	family.field("pekerjaan_ayah", a.FatherJob, a.FatherJob != "")
+	if rules.Household == nil {
+		for _, parent := range []struct {
+			criterion string
+			income    int64
+		}{{"pendapatan_ibu", a.MotherIncome}, {"pendapatan_ayah", a.FatherIncome}} {
+			if parent.income <= 0 {
+				family.add(parent.criterion, parent.income, "no income given", 0, maxBracketPoints(rules.IncomeBrackets))
+				continue
+			}
+			family.bracket(parent.criterion, float64(parent.income), "", rules.IncomeBrackets)
+		}
+	} else {
+		household := HouseholdOf(a, rules.Household.FallbackUMR)
+		switch {
+		case household.Income <= 0:
+			family.add("pendapatan_per_kapita", household, "no income given", 0, maxBracketPoints(rules.Household.PerCapitaBrackets))
+		case rules.Household.RelativeToUMR:
+			family.bracket("pendapatan_per_kapita", household.PerCapitaUMR, " x UMR", rules.Household.PerCapitaBrackets)
+		default:
+			family.bracket("pendapatan_per_kapita", household.PerCapita, "", rules.Household.PerCapitaBrackets)
+		}
+	}
+	family.field("alamat_keluarga", a.FamilyAddress, a.FamilyAddress != "")
+	family.threshold("jumlah_tanggungan", float64(a.Dependents), rules.DependentThresholds)
+
+	result := Result{
+		Personal: personal.score(),
+		Academic: academic.score(),
+		Family:   family.score(),
+	}
+	weights := map[string]float64{Personal: rules.Weights.Personal, Academic: rules.Weights.Academic, Family: rules.Weights.Family}
+	for _, t := range []tally{personal, academic, family} {
+		category := CategoryScore{
+			Category: t.category,
+			Earned:   t.earned,
+			Possible: t.possible,
+			Score:    t.score(),
+			Weight:   weights[t.category],
+		}
+		category.Weighted = category.Score * category.Weight
+		result.Total += category.Weighted
+		result.Categories = append(result.Categories, category)
+		result.Items = append(result.Items, t.items...)
+	}
+
+	result.Rank = min(max(int(result.Total*MaxRank), 1), MaxRank)
+	result.Explanation = fmt.Sprintf("weighted total %.3f x %d = %.2f, rounded down to rank %d (limited to 1-%d)",
+		result.Total, MaxRank, result.Total*MaxRank, result.Rank, MaxRank)
+	return result
+}
+
+// Completeness is how much of the required data and documents an
+// application gives, kept apart from its score
+type Completeness struct {
+	Filled  int      `json:"filled"`
+	Total   int      `json:"total"`
+	Percent int      `json:"percent"`
+	Missing []string `json:"missing"`
+}
+
+// CompletenessOf measures the required fields of an application. The
+// percentage is rounded down, so only a complete application reaches 100.
+func CompletenessOf(fields []Field) Completeness {
+	c := Completeness{Total: len(fields), Missing: []string{}}
+	for _, f := range fields {
+		if f.Filled {
+			c.Filled++
+		} else {
+			c.Missing = append(c.Missing, f.Name)
+		}
+	}
+	c.Percent = 100
+	if c.Total > 0 {
+		c.Percent = c.Filled * 100 / c.Total
+	}
+	return c
+}
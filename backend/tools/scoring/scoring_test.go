@@ -0,0 +1,231 @@
+package scoring
+
+import (
+	"strings"
+	"testing"
+)
+
+// itemFor returns the item of a criterion in a result
+func itemFor(t *testing.T, r Result, criterion string) Item {
+	t.Helper()
+	for _, item := range r.Items {
+		if item.Criterion == criterion {
+			return item
+		}
+	}
+	t.Fatalf("no item for %s", criterion)
+	return Item{}
+}
+
+func TestScoreIncomeBrackets(t *testing.T) {
+	tests := []struct {
+		income int64
+		points float64
+	}{
+		{1, 1},
+		{999999, 1},
+		{1000000, 0.6},
+		{1999999, 0.6},
+		{2000000, 0.6},
+		{2000001, 0.3},
+		{5000000, 0.3},
+		{5000001, 0},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		r := Score(DefaultRules(), Applicant{MotherIncome: tt.income, FatherIncome: tt.income})
+		for _, criterion := range []string{"pendapatan_ibu", "pendapatan_ayah"} {
+			item := itemFor(t, r, criterion)
+			if item.Earned != tt.points || item.Possible != 1 {
+				t.Errorf("%s %d: earned %v of %v, want %v of 1", criterion, tt.income, item.Earned, item.Possible, tt.points)
+			}
+		}
+	}
+}
+
+func TestScoreThresholds(t *testing.T) {
+	grade := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name      string
+		a         Applicant
+		criterion string
+		points    float64
+		possible  float64
+	}{
+		{"grade 80", Applicant{AverageGrade: grade(80)}, "rata_rata_nilai", 2, 2},
+		{"grade 79.9", Applicant{AverageGrade: grade(79.9)}, "rata_rata_nilai", 1, 2},
+		{"grade 60", Applicant{AverageGrade: grade(60)}, "rata_rata_nilai", 1, 2},
+		{"grade 59", Applicant{AverageGrade: grade(59)}, "rata_rata_nilai", 0, 2},
+		{"no grades", Applicant{}, "rata_rata_nilai", 0, 2},
+		{"no dependents", Applicant{}, "jumlah_tanggungan", 0, 1},
+		{"2 dependents", Applicant{Dependents: 2}, "jumlah_tanggungan", 0.7, 1},
+		{"5 dependents", Applicant{Dependents: 5}, "jumlah_tanggungan", 1, 1},
+	}
+	for _, tt := range tests {
+		item := itemFor(t, Score(DefaultRules(), tt.a), tt.criterion)
+		if item.Earned != tt.points || item.Possible != tt.possible {
+			t.Errorf("%s: earned %v of %v, want %v of %v", tt.name, item.Earned, item.Possible, tt.points, tt.possible)
+		}
+	}
+}
+
+func TestScoreRank(t *testing.T) {
+	grade := 90.0
+	full := Applicant{
+		PersonalFields: []Field{{"nik", true}, {"nama_lengkap", true}},
+		SchoolName:     "SMAN 1 Bandung", GraduationYear: "2024", AverageGrade: &grade, HasIjazah: true,
+		MotherJob: "Guru", MotherIncome: 500000, FatherJob: "Petani", FatherIncome: 800000,
+		FamilyAddress: "Bandung", Dependents: 3,
+	}
+	if r := Score(DefaultRules(), full); r.Rank != MaxRank || r.Total < 0.999 {
+		t.Errorf("best application: total %.3f, rank %d, want 1 and %d", r.Total, r.Rank, MaxRank)
+	}
+	if r := Score(DefaultRules(), Applicant{}); r.Rank != 1 || r.Total != 0 {
+		t.Errorf("empty application: total %.3f, rank %d, want 0 and 1", r.Total, r.Rank)
+	}
+
+	// Merit-only rules ignore filled-in fields
+	r := Score(MeritRules(), Applicant{PersonalFields: full.PersonalFields, SchoolName: "SMAN 1 Bandung", MotherJob: "Guru"})
+	if r.Total != 0 || len(r.Items) != 3 {
+		t.Errorf("merit-only score of filled-in fields: total %.3f with items %+v", r.Total, r.Items)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(r *Rules)
+		err    string
+	}{
+		{"default", func(r *Rules) {}, ""},
+		{"weights below 1", func(r *Rules) { r.Weights = Weights{Personal: 0.1, Academic: 0.3, Family: 0.5} }, "add up to 1"},
+		{"weights above 1", func(r *Rules) { r.Weights = Weights{Personal: 0.2, Academic: 0.4, Family: 0.5} }, "add up to 1"},
+		{"rounded weights", func(r *Rules) { r.Weights = Weights{Personal: 0.3333, Academic: 0.3333, Family: 0.3334} }, ""},
+		{"negative weight", func(r *Rules) { r.Weights = Weights{Personal: -0.1, Academic: 0.6, Family: 0.5} }, "negative"},
+		{"duplicate income bracket", func(r *Rules) {
+			r.IncomeBrackets = append(r.IncomeBrackets, Bracket{Max: 2000000, Points: 0.5})
+		}, "income bracket 2e+06 is listed twice"},
+		{"duplicate grade threshold", func(r *Rules) {
+			r.GradeThresholds = append(r.GradeThresholds, Threshold{Min: 60, Points: 0.5})
+		}, "grade threshold 60 is listed twice"},
+		{"duplicate dependent threshold", func(r *Rules) {
+			r.DependentThresholds = append(r.DependentThresholds, Threshold{Min: 1, Points: 0.2})
+		}, "dependent threshold 1 is listed twice"},
+		{"negative bracket", func(r *Rules) { r.IncomeBrackets[0].Points = -1 }, "negative"},
+		{"merit-only with personal weight", func(r *Rules) { r.MeritOnly = true }, "personal weight must be 0"},
+	}
+	for _, tt := range tests {
+		rules := DefaultRules()
+		tt.change(&rules)
+		err := rules.Validate()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+
+	for name, rules := range map[string]Rules{"per capita": PerCapitaRules(), "merit": MeritRules()} {
+		if err := rules.Validate(); err != nil {
+			t.Errorf("%s rules: %v", name, err)
+		}
+	}
+	household := PerCapitaRules()
+	household.Household.PerCapitaBrackets = append(household.Household.PerCapitaBrackets, Bracket{Max: 0.5, Points: 1})
+	if err := household.Validate(); err == nil || !strings.Contains(err.Error(), "per capita income bracket 0.5 is listed twice") {
+		t.Errorf("duplicate per capita bracket: err = %v", err)
+	}
+	household = PerCapitaRules()
+	household.Household.FallbackUMR = 0
+	if err := household.Validate(); err == nil {
+		t.Error("UMR-relative brackets accepted without a fallback UMR")
+	}
+}
+
+func TestHouseholdOf(t *testing.T) {
+	tests := []struct {
+		name      string
+		a         Applicant
+		fallback  float64
+		members   int
+		perCapita float64
+		umr       float64
+		relative  float64
+	}{
+		{"both parents, one sibling", Applicant{MotherIncome: 1000000, FatherIncome: 3000000, Parents: 2, Dependents: 1, UMR: 2000000}, 2800000, 4, 1000000, 2000000, 0.5},
+		{"single parent", Applicant{MotherIncome: 1500000, Parents: 1, UMR: 3000000}, 2800000, 2, 750000, 3000000, 0.25},
+		{"negative income ignored", Applicant{MotherIncome: -500000, FatherIncome: 900000, Parents: 2, UMR: 1800000}, 2800000, 3, 300000, 1800000, 1.0 / 6},
+		{"no UMR on record", Applicant{FatherIncome: 2800000, Parents: 2, Dependents: 1}, 2800000, 4, 700000, 2800000, 0.25},
+		{"no UMR at all", Applicant{FatherIncome: 2800000, Parents: 2, Dependents: 1}, 0, 4, 700000, 0, 0},
+	}
+	for _, tt := range tests {
+		h := HouseholdOf(tt.a, tt.fallback)
+		if h.Members != tt.members || h.PerCapita != tt.perCapita || h.UMR != tt.umr || h.PerCapitaUMR != tt.relative {
+			t.Errorf("%s: %+v, want %d members, %v per capita, UMR %v (%v)", tt.name, h, tt.members, tt.perCapita, tt.umr, tt.relative)
+		}
+	}
+}
+
+func TestScorePerCapitaIncome(t *testing.T) {
+	// A family of eight living on 1.8jt, the applicant and five siblings,
+	// needs the scholarship more than a family of three where each parent
+	// earns 1.1jt. Per parent the second family looks poorer.
+	large := Applicant{FatherIncome: 1800000, Parents: 2, Dependents: 5, UMR: 2000000}
+	small := Applicant{MotherIncome: 1100000, FatherIncome: 1100000, Parents: 2, UMR: 2000000}
+
+	income := func(rules Rules, a Applicant) float64 {
+		var earned float64
+		for _, item := range Score(rules, a).Items {
+			if strings.HasPrefix(item.Criterion, "pendapatan_") {
+				earned += item.Earned
+			}
+		}
+		return earned
+	}
+	if l, s := income(DefaultRules(), large), income(DefaultRules(), small); l >= s {
+		t.Errorf("per parent income: large family %v, small family %v; the formula no longer shows the problem", l, s)
+	}
+	if l, s := income(PerCapitaRules(), large), income(PerCapitaRules(), small); l != 2 || s != 1.2 {
+		t.Errorf("per capita income: large family %v, small family %v, want 2 and 1.2", l, s)
+	}
+	if l, s := Score(PerCapitaRules(), large), Score(PerCapitaRules(), small); l.Family <= s.Family || l.Rank < s.Rank {
+		t.Errorf("large family scored %.3f (rank %d), small family %.3f (rank %d)", l.Family, l.Rank, s.Family, s.Rank)
+	}
+
+	// Brackets relative to the UMR for a family of four with a UMR of 4jt
+	brackets := []struct {
+		income int64
+		points float64
+	}{
+		{4000000, 2},   // 0.25 x UMR per member
+		{4000004, 1.2}, // just above
+		{8000000, 1.2}, // 0.5 x UMR
+		{16000000, 0.6},
+		{16000004, 0},
+	}
+	for _, tt := range brackets {
+		a := Applicant{FatherIncome: tt.income, Parents: 2, Dependents: 1, UMR: 4000000}
+		item := itemFor(t, Score(PerCapitaRules(), a), "pendapatan_per_kapita")
+		if item.Earned != tt.points || item.Possible != 2 {
+			t.Errorf("income %d: earned %v of %v, want %v of 2", tt.income, item.Earned, item.Possible, tt.points)
+		}
+	}
+
+	// Without a UMR on record the fallback wage of 2.8jt applies
+	unknown := Applicant{FatherIncome: 2800000, Parents: 2, Dependents: 1}
+	item := itemFor(t, Score(PerCapitaRules(), unknown), "pendapatan_per_kapita")
+	if item.Earned != 2 || !strings.Contains(item.Rule, "0.25 x UMR") {
+		t.Errorf("fallback UMR: earned %v under %q, want 2 under the 0.25 x UMR bracket", item.Earned, item.Rule)
+	}
+	if item.Value != 0.25 {
+		t.Errorf("fallback UMR: per capita income %v x UMR, want 0.25", item.Value)
+	}
+
+	// Brackets in rupiah ignore the UMR
+	rupiah := PerCapitaRules()
+	rupiah.Household = &HouseholdRules{PerCapitaBrackets: []Bracket{{Max: 500000, Points: 2}, {Max: 1000000, Points: 1}}}
+	if item := itemFor(t, Score(rupiah, Applicant{FatherIncome: 2000000, Parents: 2, Dependents: 1, UMR: 1000000}), "pendapatan_per_kapita"); item.Earned != 2 {
+		t.Errorf("rupiah brackets: earned %v, want 2", item.Earned)
+	}
+}
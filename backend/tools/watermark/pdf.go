@@ -0,0 +1,712 @@
+package watermark
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNotPDF is returned for content that is not a PDF document
+	ErrNotPDF = errors.New("watermark: not a PDF document")
+	// ErrEncrypted is returned for password-protected PDFs, whose content
+	// cannot be rewritten without the key
+	ErrEncrypted = errors.New("watermark: PDF is encrypted")
+	// ErrUnsupportedPDF is returned when the page structure cannot be found
+	ErrUnsupportedPDF = errors.New("watermark: unsupported PDF structure")
+)
+
+// PDF geometry of the watermark, in points
+const (
+	pdfPixel   = 1.4
+	pdfRowStep = 90.0
+	pdfColGap  = 60.0
+	pdfAngle   = 30.0
+)
+
+// defaultMediaBox is US Letter, the default page size of PDF writers
+var defaultMediaBox = [4]float64{0, 0, 612, 792}
+
+var objHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// pdfObject is the latest definition of an indirect object
+type pdfObject struct {
+	num, gen int
+	raw      []byte // the object's value; the stream dictionary for streams
+	pos      int    // where it is defined, to let later definitions win
+	data     []byte // the raw stream data, nil when the object is no stream
+}
+
+// dictEntry is one key of a parsed dictionary with the span of its value
+type dictEntry struct {
+	key        string
+	start, end int
+}
+
+// pdfDocument is the structure of a PDF that watermarking relies on
+type pdfDocument struct {
+	trailer []byte
+	root    []byte
+	// size is one past the highest object number in use
+	size    int
+	objects map[int]pdfObject
+	pages   []pdfObject
+}
+
+// parsePDF finds the trailer, objects and pages of a PDF document
+func parsePDF(content []byte) (*pdfDocument, error) {
+	if !bytes.HasPrefix(content, []byte("%PDF-")) {
+		return nil, ErrNotPDF
+	}
+
+	trailer, err := lastTrailer(content)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := dictValue(trailer, "Encrypt"); ok {
+		return nil, ErrEncrypted
+	}
+	root, ok := dictValue(trailer, "Root")
+	if !ok {
+		return nil, ErrUnsupportedPDF
+	}
+	doc := &pdfDocument{trailer: trailer, root: root, objects: scanObjects(content)}
+	doc.size, _ = strconv.Atoi(string(valueOr(trailer, "Size", "0")))
+
+	for _, obj := range doc.objects {
+		if name, ok := dictValue(obj.raw, "Type"); ok && string(name) == "/Page" {
+			doc.pages = append(doc.pages, obj)
+		}
+		doc.size = max(doc.size, obj.num+1)
+	}
+	if len(doc.pages) == 0 {
+		return nil, ErrUnsupportedPDF
+	}
+	sort.Slice(doc.pages, func(i, j int) bool { return doc.pages[i].num < doc.pages[j].num })
+	return doc, nil
+}
+
+// Validate checks that content is a PDF that PDF can watermark: not
+// encrypted, and with a trailer and pages that can be found
+func Validate(content []byte) error {
+	_, err := parsePDF(content)
+	return err
+}
+
+// PDF rewrites a PDF document so that every page draws text over its
+// content. The document is written out as a single revision, and the
+// content of each page is merged with the watermark into one new stream, so
+// the watermark cannot be dropped by cutting the file back to an earlier
+// revision or by unlinking an added object; removing it takes a tool that
+// decodes and edits page content. Pages whose content uses a filter other
+// than FlateDecode keep their streams and get the watermark as an extra
+// stream, which is easier to strip. Rewriting invalidates any digital
+// signature on the original.
+func PDF(content []byte, text string) ([]byte, error) {
+	doc, err := parsePDF(content)
+	if err != nil {
+		return nil, err
+	}
+
+	added := map[int][]byte{}
+	nextNum := doc.size
+	newObject := func(body []byte) int {
+		num := nextNum
+		nextNum++
+		added[num] = body
+		return num
+	}
+
+	// Content streams merged into a page's new stream are left out of the
+	// output unless a page that could not be merged still uses them
+	merged := map[int]bool{}
+	kept := map[int]bool{}
+	var saveState int
+	overlays := map[[4]float64]int{}
+
+	for _, page := range doc.pages {
+		box := mediaBox(doc.objects, page, 0)
+		streams, err := pageContents(doc.objects, page.raw)
+		if err != nil {
+			return nil, err
+		}
+
+		var contents string
+		if data, ok := decodeContents(doc.objects, streams); ok {
+			// The page content is wrapped in q/Q so the watermark starts
+			// from the default graphics state whatever the page leaves behind
+			stream := append([]byte("q\n"), data...)
+			stream = append(stream, overlayContent(box, text)...)
+			contents = fmt.Sprintf("%d 0 R", newObject(streamObject(stream, true)))
+			for _, num := range streams {
+				merged[num] = true
+			}
+		} else {
+			if saveState == 0 {
+				saveState = newObject(streamObject([]byte("q\n"), false))
+			}
+			overlay, ok := overlays[box]
+			if !ok {
+				overlay = newObject(streamObject(overlayContent(box, text), true))
+				overlays[box] = overlay
+			}
+			refs := []string{fmt.Sprintf("%d 0 R", saveState)}
+			for _, num := range streams {
+				kept[num] = true
+				refs = append(refs, fmt.Sprintf("%d %d R", num, doc.objects[num].gen))
+			}
+			refs = append(refs, fmt.Sprintf("%d 0 R", overlay))
+			contents = "[" + strings.Join(refs, " ") + "]"
+		}
+		added[page.num] = replaceValue(page.raw, "Contents", []byte(contents))
+	}
+
+	var out bytes.Buffer
+	out.Write(pdfHeader(content))
+	out.WriteString("%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, nextNum)
+	gens := make([]int, nextNum)
+	for num := 1; num < nextNum; num++ {
+		obj, ok := doc.objects[num]
+		body, isAdded := added[num]
+		switch {
+		case isAdded:
+		case !ok || (merged[num] && !kept[num]) || dropObject(obj):
+			continue
+		case obj.data != nil:
+			body = rawStream(obj)
+		default:
+			body = obj.raw
+		}
+		offsets[num] = out.Len()
+		gens[num] = obj.gen
+		fmt.Fprintf(&out, "%d %d obj\n", num, obj.gen)
+		out.Write(body)
+		out.WriteString("\nendobj\n")
+	}
+
+	xrefOffset := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", nextNum)
+	for num := 1; num < nextNum; num++ {
+		if offsets[num] == 0 {
+			out.WriteString("0000000000 00000 f \n")
+			continue
+		}
+		fmt.Fprintf(&out, "%010d %05d n \n", offsets[num], gens[num])
+	}
+
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %s", nextNum, doc.root)
+	for _, key := range []string{"Info", "ID"} {
+		if value, ok := dictValue(doc.trailer, key); ok {
+			fmt.Fprintf(&out, " /%s %s", key, value)
+		}
+	}
+	fmt.Fprintf(&out, " >>\nstartxref\n%d\n%%%%EOF\n", xrefOffset)
+	return out.Bytes(), nil
+}
+
+// pdfHeader returns the version line of a PDF document
+func pdfHeader(content []byte) []byte {
+	end := bytes.IndexAny(content, "\r\n")
+	if end < 0 || end > 16 {
+		return []byte("%PDF-1.7\n")
+	}
+	return append(append([]byte(nil), content[:end]...), '\n')
+}
+
+// dropObject reports whether an object only makes sense in the original
+// file layout: object streams and cross-reference streams, whose objects are
+// written out individually, and linearization hints
+func dropObject(obj pdfObject) bool {
+	if name, ok := dictValue(obj.raw, "Type"); ok && (string(name) == "/ObjStm" || string(name) == "/XRef") {
+		return true
+	}
+	_, linearized := dictValue(obj.raw, "Linearized")
+	return linearized
+}
+
+// rawStream returns the body of a stream object copied as is, with a direct
+// Length in case the original refers to another object for it
+func rawStream(obj pdfObject) []byte {
+	var b bytes.Buffer
+	b.Write(replaceValue(obj.raw, "Length", []byte(strconv.Itoa(len(obj.data)))))
+	b.WriteString("\nstream\n")
+	b.Write(obj.data)
+	b.WriteString("\nendstream")
+	return b.Bytes()
+}
+
+// decodeContents returns the decoded content streams of a page joined into
+// one, or false when one of them cannot be decoded
+func decodeContents(objects map[int]pdfObject, streams []int) ([]byte, bool) {
+	var b bytes.Buffer
+	for _, num := range streams {
+		obj, ok := objects[num]
+		if !ok || obj.data == nil {
+			return nil, false
+		}
+		data, ok := decodeStream(obj.raw, obj.data)
+		if !ok {
+			return nil, false
+		}
+		b.Write(data)
+		// Streams of one page are read as if separated by whitespace
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), true
+}
+
+// decodeStream decodes stream data that is stored as is or FlateDecode
+// compressed without predictors
+func decodeStream(dict, data []byte) ([]byte, bool) {
+	filter, ok := dictValue(dict, "Filter")
+	if !ok {
+		return data, true
+	}
+	if strings.Trim(string(filter), "[] ") != "/FlateDecode" {
+		return nil, false
+	}
+	if _, ok := dictValue(dict, "DecodeParms"); ok {
+		return nil, false
+	}
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// lastTrailer finds the most recent cross-reference section and returns its
+// trailer dictionary, from either a trailer or an xref stream
+func lastTrailer(content []byte) ([]byte, error) {
+	i := bytes.LastIndex(content, []byte("startxref"))
+	if i < 0 {
+		return nil, ErrUnsupportedPDF
+	}
+	j := skipSpace(content, i+len("startxref"))
+	k := tokenEnd(content, j)
+	offset, err := strconv.Atoi(string(content[j:k]))
+	if err != nil || offset <= 0 || offset >= len(content) {
+		return nil, ErrUnsupportedPDF
+	}
+
+	var dictStart int
+	if bytes.HasPrefix(content[offset:], []byte("xref")) {
+		t := bytes.Index(content[offset:], []byte("trailer"))
+		if t < 0 {
+			return nil, ErrUnsupportedPDF
+		}
+		dictStart = skipSpace(content, offset+t+len("trailer"))
+	} else {
+		loc := objHeader.FindIndex(content[offset:])
+		if loc == nil || loc[0] != 0 {
+			return nil, ErrUnsupportedPDF
+		}
+		dictStart = skipSpace(content, offset+loc[1])
+	}
+
+	end, err := objectEnd(content, dictStart)
+	if err != nil || !bytes.HasPrefix(content[dictStart:], []byte("<<")) {
+		return nil, ErrUnsupportedPDF
+	}
+	return content[dictStart:end], nil
+}
+
+// scanObjects collects the latest definition of every indirect object,
+// including objects packed in FlateDecode object streams
+func scanObjects(content []byte) map[int]pdfObject {
+	objects := map[int]pdfObject{}
+	record := func(obj pdfObject) {
+		if prev, ok := objects[obj.num]; !ok || prev.pos <= obj.pos {
+			objects[obj.num] = obj
+		}
+	}
+
+	for cursor := 0; cursor < len(content); {
+		loc := objHeader.FindSubmatchIndex(content[cursor:])
+		if loc == nil {
+			break
+		}
+		pos := cursor + loc[0]
+		num, _ := strconv.Atoi(string(content[cursor+loc[2] : cursor+loc[3]]))
+		gen, _ := strconv.Atoi(string(content[cursor+loc[4] : cursor+loc[5]]))
+		start := skipSpace(content, cursor+loc[1])
+		cursor += loc[1]
+
+		end, err := objectEnd(content, start)
+		if err != nil {
+			continue
+		}
+		obj := pdfObject{num: num, gen: gen, raw: content[start:end], pos: pos}
+		cursor = end
+
+		// Skip stream data so binary content is not mistaken for objects
+		s := skipSpace(content, end)
+		if !bytes.HasPrefix(content[s:], []byte("stream")) {
+			record(obj)
+			continue
+		}
+		data := s + len("stream")
+		if bytes.HasPrefix(content[data:], []byte("\r\n")) {
+			data += 2
+		} else if data < len(content) && content[data] == '\n' {
+			data++
+		}
+		dataEnd := -1
+		if length, err := strconv.Atoi(string(valueOr(obj.raw, "Length", ""))); err == nil && data+length <= len(content) &&
+			bytes.HasPrefix(bytes.TrimLeft(content[data+length:], "\r\n "), []byte("endstream")) {
+			dataEnd = data + length
+		} else if e := bytes.Index(content[data:], []byte("endstream")); e >= 0 {
+			// Without a usable Length, the end of line before endstream is
+			// taken as not part of the data
+			dataEnd = data + e
+			for _, eol := range []string{"\n", "\r"} {
+				if dataEnd > data && content[dataEnd-1] == eol[0] {
+					dataEnd--
+				}
+			}
+		}
+		if dataEnd < 0 {
+			record(obj)
+			break
+		}
+		obj.data = content[data:dataEnd]
+		record(obj)
+		cursor = dataEnd
+
+		if name, ok := dictValue(obj.raw, "Type"); ok && string(name) == "/ObjStm" {
+			for _, packed := range objectStream(obj.raw, obj.data) {
+				packed.pos = pos
+				record(packed)
+			}
+		}
+	}
+	return objects
+}
+
+// objectStream unpacks the objects stored in an object stream
+func objectStream(dict, data []byte) []pdfObject {
+	data, ok := decodeStream(dict, data)
+	if !ok {
+		return nil
+	}
+	n, err1 := strconv.Atoi(string(valueOr(dict, "N", "")))
+	first, err2 := strconv.Atoi(string(valueOr(dict, "First", "")))
+	if err1 != nil || err2 != nil || first > len(data) {
+		return nil
+	}
+
+	header := strings.Fields(string(data[:first]))
+	var objects []pdfObject
+	for i := 0; i+1 < len(header) && i/2 < n; i += 2 {
+		num, err1 := strconv.Atoi(header[i])
+		offset, err2 := strconv.Atoi(header[i+1])
+		if err1 != nil || err2 != nil || first+offset >= len(data) {
+			continue
+		}
+		start := skipSpace(data, first+offset)
+		end, err := objectEnd(data, start)
+		if err != nil {
+			continue
+		}
+		objects = append(objects, pdfObject{num: num, raw: data[start:end]})
+	}
+	return objects
+}
+
+// pageContents returns the object numbers of the page's content streams
+func pageContents(objects map[int]pdfObject, page []byte) ([]int, error) {
+	value, ok := dictValue(page, "Contents")
+	if !ok {
+		return nil, nil
+	}
+	// A reference either names a content stream or an array of them
+	if !bytes.HasPrefix(value, []byte("[")) {
+		fields := strings.Fields(string(value))
+		if len(fields) != 3 || fields[2] != "R" {
+			return nil, ErrUnsupportedPDF
+		}
+		num, _ := strconv.Atoi(fields[0])
+		target, ok := objects[num]
+		if !ok || !bytes.HasPrefix(target.raw, []byte("[")) {
+			return []int{num}, nil
+		}
+		value = target.raw
+	}
+
+	fields := strings.Fields(string(bytes.Trim(value, "[] \r\n\t")))
+	if len(fields)%3 != 0 {
+		return nil, ErrUnsupportedPDF
+	}
+	var streams []int
+	for i := 0; i < len(fields); i += 3 {
+		num, err := strconv.Atoi(fields[i])
+		if err != nil || fields[i+2] != "R" {
+			return nil, ErrUnsupportedPDF
+		}
+		streams = append(streams, num)
+	}
+	return streams, nil
+}
+
+// mediaBox returns the page size, following inheritance from parent nodes
+func mediaBox(objects map[int]pdfObject, node pdfObject, depth int) [4]float64 {
+	if value, ok := dictValue(node.raw, "MediaBox"); ok {
+		fields := strings.Fields(strings.Trim(string(value), "[]"))
+		if len(fields) == 4 {
+			var box [4]float64
+			valid := true
+			for i, f := range fields {
+				v, err := strconv.ParseFloat(f, 64)
+				valid = valid && err == nil
+				box[i] = v
+			}
+			if valid {
+				return box
+			}
+		}
+	}
+	if parent, ok := dictValue(node.raw, "Parent"); ok && depth < 32 {
+		if fields := strings.Fields(string(parent)); len(fields) == 3 {
+			num, _ := strconv.Atoi(fields[0])
+			if obj, ok := objects[num]; ok {
+				return mediaBox(objects, obj, depth+1)
+			}
+		}
+	}
+	return defaultMediaBox
+}
+
+// overlayContent draws text tiled diagonally across a page of the given box
+func overlayContent(box [4]float64, text string) []byte {
+	rects, width := textRects(text)
+	tw := float64(width) * pdfPixel
+	th := glyphHeight * pdfPixel
+
+	cx, cy := (box[0]+box[2])/2, (box[1]+box[3])/2
+	reach := math.Hypot(box[2]-box[0], box[3]-box[1]) / 2
+	rad := pdfAngle * math.Pi / 180
+
+	var b bytes.Buffer
+	b.WriteString("\nQ\nq\n0.85 0.3 0.3 rg\n")
+	fmt.Fprintf(&b, "%s %s %s %s %s %s cm\n",
+		num(math.Cos(rad)), num(math.Sin(rad)), num(-math.Sin(rad)), num(math.Cos(rad)), num(cx), num(cy))
+
+	row := 0
+	for y := reach; y > -reach-th; y -= pdfRowStep {
+		offset := float64(row%2) * (tw + pdfColGap) / 2
+		for x := -reach - offset; x < reach; x += tw + pdfColGap {
+			for _, r := range rects {
+				fmt.Fprintf(&b, "%s %s %s %s re\n",
+					num(x+float64(r.x)*pdfPixel), num(y-float64(r.y+r.h)*pdfPixel),
+					num(float64(r.w)*pdfPixel), num(float64(r.h)*pdfPixel))
+			}
+		}
+		row++
+	}
+	b.WriteString("f\nQ\n")
+	return b.Bytes()
+}
+
+// num formats a number compactly for a content stream
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// streamObject returns the body of a stream object holding data
+func streamObject(data []byte, compress bool) []byte {
+	var b bytes.Buffer
+	if compress {
+		var z bytes.Buffer
+		w := zlib.NewWriter(&z)
+		w.Write(data)
+		w.Close()
+		data = z.Bytes()
+		fmt.Fprintf(&b, "<< /Length %d /Filter /FlateDecode >>\nstream\n", len(data))
+	} else {
+		fmt.Fprintf(&b, "<< /Length %d >>\nstream\n", len(data))
+	}
+	b.Write(data)
+	b.WriteString("\nendstream")
+	return b.Bytes()
+}
+
+// dictEntries parses the top-level keys of a dictionary
+func dictEntries(dict []byte) []dictEntry {
+	if !bytes.HasPrefix(dict, []byte("<<")) {
+		return nil
+	}
+	var entries []dictEntry
+	i := 2
+	for {
+		i = skipSpace(dict, i)
+		if i >= len(dict) || bytes.HasPrefix(dict[i:], []byte(">>")) || dict[i] != '/' {
+			return entries
+		}
+		keyEnd := tokenEnd(dict, i+1)
+		key := string(dict[i+1 : keyEnd])
+		start := skipSpace(dict, keyEnd)
+		end, err := objectEnd(dict, start)
+		if err != nil {
+			return entries
+		}
+		entries = append(entries, dictEntry{key: key, start: start, end: end})
+		i = end
+	}
+}
+
+// dictValue returns the raw value of key in a dictionary
+func dictValue(dict []byte, key string) ([]byte, bool) {
+	for _, e := range dictEntries(dict) {
+		if e.key == key {
+			return dict[e.start:e.end], true
+		}
+	}
+	return nil, false
+}
+
+func valueOr(dict []byte, key, fallback string) []byte {
+	if value, ok := dictValue(dict, key); ok {
+		return value
+	}
+	return []byte(fallback)
+}
+
+// replaceValue returns a copy of dict with the value of key replaced, or
+// added when the key is missing
+func replaceValue(dict []byte, key string, value []byte) []byte {
+	var b bytes.Buffer
+	for _, e := range dictEntries(dict) {
+		if e.key == key {
+			b.Write(dict[:e.start])
+			b.Write(value)
+			b.Write(dict[e.end:])
+			return b.Bytes()
+		}
+	}
+	end := bytes.LastIndex(dict, []byte(">>"))
+	b.Write(dict[:end])
+	fmt.Fprintf(&b, " /%s %s", key, value)
+	b.Write(dict[end:])
+	return b.Bytes()
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// skipSpace skips whitespace and comments
+func skipSpace(b []byte, i int) int {
+	for i < len(b) {
+		switch {
+		case isSpace(b[i]):
+			i++
+		case b[i] == '%':
+			for i < len(b) && b[i] != '\n' && b[i] != '\r' {
+				i++
+			}
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// tokenEnd returns the end of a regular token (number, keyword or name body)
+func tokenEnd(b []byte, i int) int {
+	for i < len(b) && !isSpace(b[i]) && !isDelimiter(b[i]) {
+		i++
+	}
+	return i
+}
+
+var errSyntax = errors.New("watermark: PDF syntax error")
+
+// objectEnd returns the end of the object starting at i, treating an
+// indirect reference ("12 0 R") as a single object
+func objectEnd(b []byte, i int) (int, error) {
+	if i >= len(b) {
+		return 0, errSyntax
+	}
+	switch {
+	case bytes.HasPrefix(b[i:], []byte("<<")):
+		return containerEnd(b, i+2, ">>")
+	case b[i] == '[':
+		return containerEnd(b, i+1, "]")
+	case b[i] == '<':
+		end := bytes.IndexByte(b[i:], '>')
+		if end < 0 {
+			return 0, errSyntax
+		}
+		return i + end + 1, nil
+	case b[i] == '(':
+		depth := 0
+		for j := i; j < len(b); j++ {
+			switch b[j] {
+			case '\\':
+				j++
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					return j + 1, nil
+				}
+			}
+		}
+		return 0, errSyntax
+	case b[i] == '/':
+		return tokenEnd(b, i+1), nil
+	}
+
+	end := tokenEnd(b, i)
+	if end == i {
+		return 0, errSyntax
+	}
+	if _, err := strconv.Atoi(string(b[i:end])); err == nil {
+		// Look ahead for "<gen> R"
+		g := skipSpace(b, end)
+		gEnd := tokenEnd(b, g)
+		if _, err := strconv.Atoi(string(b[g:gEnd])); err == nil && gEnd > g {
+			r := skipSpace(b, gEnd)
+			if r < len(b) && b[r] == 'R' && (r+1 == len(b) || isSpace(b[r+1]) || isDelimiter(b[r+1])) {
+				return r + 1, nil
+			}
+		}
+	}
+	return end, nil
+}
+
+// containerEnd returns the end of a dictionary or array whose body starts at i
+func containerEnd(b []byte, i int, closer string) (int, error) {
+	for {
+		i = skipSpace(b, i)
+		if i >= len(b) {
+			return 0, errSyntax
+		}
+		if bytes.HasPrefix(b[i:], []byte(closer)) {
+			return i + len(closer), nil
+		}
+		end, err := objectEnd(b, i)
+		if err != nil {
+			return 0, err
+		}
+		i = end
+	}
+}
@@ -0,0 +1,165 @@
+package watermark
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a PDF with a classic cross-reference table from object
+// bodies numbered from 1
+func buildPDF(objects []string, trailer string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, body := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailer, xref)
+	return b.Bytes()
+}
+
+func plainStream(data string) string {
+	return fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(data), data)
+}
+
+func flateStream(data string) string {
+	var z bytes.Buffer
+	w := zlib.NewWriter(&z)
+	w.Write([]byte(data))
+	w.Close()
+	return fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", z.Len(), z.Bytes())
+}
+
+func testPDF() []byte {
+	return buildPDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /MediaBox [0 0 595 842] >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [6 0 R 7 0 R] >>",
+		flateStream("BT (first page) Tj ET"),
+		plainStream("BT (second"),
+		plainStream("page) Tj ET"),
+		plainStream("not page content"),
+	}, "/Root 1 0 R")
+}
+
+func TestPDFMergesWatermarkIntoPageContent(t *testing.T) {
+	out, err := PDF(testPDF(), "Verifikator | Verifikasi #7")
+	if err != nil {
+		t.Fatalf("PDF: %v", err)
+	}
+	if n := bytes.Count(out, []byte("%%EOF")); n != 1 {
+		t.Errorf("output has %d revisions, want a single one", n)
+	}
+	if bytes.Contains(out, []byte("/Prev")) || bytes.Contains(out, []byte("first page")) || bytes.Contains(out, []byte("(second")) {
+		t.Error("output still holds the original revision or content streams")
+	}
+
+	doc, err := parsePDF(out)
+	if err != nil {
+		t.Fatalf("parsing the output: %v", err)
+	}
+	if len(doc.pages) != 2 {
+		t.Fatalf("output has %d pages, want 2", len(doc.pages))
+	}
+	for i, want := range []string{"q\nBT (first page) Tj ET\n\nQ\nq\n", "q\nBT (second\npage) Tj ET\n\nQ\nq\n"} {
+		streams, err := pageContents(doc.objects, doc.pages[i].raw)
+		if err != nil || len(streams) != 1 {
+			t.Fatalf("page %d: contents %v (%v), want a single stream", i+1, streams, err)
+		}
+		data, ok := decodeContents(doc.objects, streams)
+		if !ok {
+			t.Fatalf("page %d: content cannot be decoded", i+1)
+		}
+		if !bytes.HasPrefix(data, []byte(want)) || !bytes.Contains(data, []byte(" re\n")) {
+			t.Errorf("page %d: content does not wrap the original and draw the watermark:\n%.80q", i+1, data)
+		}
+	}
+	if obj, ok := doc.objects[8]; !ok || string(obj.data) != "not page content" {
+		t.Errorf("unrelated stream not copied: %+v", obj)
+	}
+}
+
+func TestPDFRewritesIncrementalUpdates(t *testing.T) {
+	original := testPDF()
+	// An update that replaces the content of the first page
+	update := fmt.Sprintf("9 0 obj\n%s\nendobj\n3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 9 0 R >>\nendobj\n", plainStream("BT (updated) Tj ET"))
+	content := append(append([]byte(nil), original...), update...)
+	prev := bytes.LastIndex(original, []byte("xref"))
+	content = fmt.Appendf(content, "xref\n0 1\n0000000000 65535 f \ntrailer\n<< /Size 10 /Root 1 0 R /Prev %d >>\nstartxref\n%d\n%%%%EOF\n",
+		prev, len(original)+len(update))
+
+	out, err := PDF(content, "x")
+	if err != nil {
+		t.Fatalf("PDF: %v", err)
+	}
+	doc, err := parsePDF(out)
+	if err != nil {
+		t.Fatalf("parsing the output: %v", err)
+	}
+	streams, _ := pageContents(doc.objects, doc.pages[0].raw)
+	data, _ := decodeContents(doc.objects, streams)
+	if !bytes.HasPrefix(data, []byte("q\nBT (updated) Tj ET")) {
+		t.Errorf("first page does not show the updated content: %.40q", data)
+	}
+}
+
+func TestPDFKeepsStreamsItCannotDecode(t *testing.T) {
+	content := buildPDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		"<< /Length 4 /Filter /ASCIIHexDecode >>\nstream\n4243\nendstream",
+	}, "/Root 1 0 R")
+
+	out, err := PDF(content, "x")
+	if err != nil {
+		t.Fatalf("PDF: %v", err)
+	}
+	doc, err := parsePDF(out)
+	if err != nil {
+		t.Fatalf("parsing the output: %v", err)
+	}
+	streams, _ := pageContents(doc.objects, doc.pages[0].raw)
+	if len(streams) != 3 || streams[1] != 4 {
+		t.Errorf("contents = %v, want the original stream between the save state and the watermark", streams)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    error
+	}{
+		{"valid", testPDF(), nil},
+		{"not a PDF", []byte("GIF89a"), ErrNotPDF},
+		{"encrypted", buildPDF([]string{"<< /Type /Catalog >>", "<< /Filter /Standard >>"}, "/Root 1 0 R /Encrypt 2 0 R"), ErrEncrypted},
+		{"no pages", buildPDF([]string{"<< /Type /Catalog >>"}, "/Root 1 0 R"), ErrUnsupportedPDF},
+		{"no trailer", []byte("%PDF-1.4\n1 0 obj\n<< >>\nendobj\n"), ErrUnsupportedPDF},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.content); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Validate = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestPDFHeader(t *testing.T) {
+	if got := string(pdfHeader([]byte("%PDF-1.6\r\n%..."))); got != "%PDF-1.6\n" {
+		t.Errorf("pdfHeader = %q", got)
+	}
+	if got := string(pdfHeader([]byte(strings.Repeat("%PDF-", 10)))); got != "%PDF-1.7\n" {
+		t.Errorf("pdfHeader without a version line = %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+// Package watermark stamps identifying text onto document images and PDFs
+// before they are shown to staff. It uses only the standard library: text is
+// drawn with a built-in bitmap font, and PDFs are rewritten with the
+// watermark merged into the content of every page.
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Watermark inks for light and dark backgrounds, so the text stays visible
+// on any part of a scan
+var (
+	darkInk  = color.RGBA{R: 150, G: 0, B: 0, A: 255}
+	lightInk = color.RGBA{R: 255, G: 235, B: 235, A: 255}
+)
+
+const inkAlpha = 0.4
+
+// rect is a filled rectangle in font pixels, y growing downwards
+type rect struct {
+	x, y, w, h int
+}
+
+// textRects turns text into the rectangles that draw it with the bitmap
+// font, merging the lit pixels of each glyph column into vertical runs
+func textRects(text string) (rects []rect, width int) {
+	i := 0
+	for _, r := range text {
+		columns := glyph(r)
+		for c, bits := range columns {
+			for y := 0; y < glyphHeight; {
+				if bits&(1<<y) == 0 {
+					y++
+					continue
+				}
+				start := y
+				for y < glyphHeight && bits&(1<<y) != 0 {
+					y++
+				}
+				rects = append(rects, rect{x: i*glyphAdvance + c, y: start, w: 1, h: y - start})
+			}
+		}
+		i++
+	}
+	return rects, i*glyphAdvance - 1
+}
+
+// Image returns a copy of img with text tiled across it in staggered rows
+func Image(img image.Image, text string) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+
+	w, h := b.Dx(), b.Dy()
+	scale := max(1, min(w, h)/300)
+	rects, width := textRects(text)
+	tw, th := width*scale, glyphHeight*scale
+	rowStep, colStep := th*5, tw+th*4
+
+	for row, y := 0, th; y < h; row, y = row+1, y+rowStep {
+		for x := -(row % 2) * colStep / 2; x < w; x += colStep {
+			for _, r := range rects {
+				blend(dst, image.Rect(x+r.x*scale, y+r.y*scale, x+(r.x+r.w)*scale, y+(r.y+r.h)*scale))
+			}
+		}
+	}
+	return dst
+}
+
+// blend mixes the watermark ink into the pixels of area, choosing the ink
+// that contrasts with each pixel
+func blend(dst *image.RGBA, area image.Rectangle) {
+	area = area.Intersect(dst.Bounds())
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		i := dst.PixOffset(area.Min.X, y)
+		for x := area.Min.X; x < area.Max.X; x++ {
+			p := dst.Pix[i : i+4 : i+4]
+			ink := darkInk
+			if 299*int(p[0])+587*int(p[1])+114*int(p[2]) < 110_000 {
+				ink = lightInk
+			}
+			p[0] = mix(p[0], ink.R)
+			p[1] = mix(p[1], ink.G)
+			p[2] = mix(p[2], ink.B)
+			p[3] = 255
+			i += 4
+		}
+	}
+}
+
+func mix(base, over uint8) uint8 {
+	return uint8(float64(base)*(1-inkAlpha) + float64(over)*inkAlpha)
+}
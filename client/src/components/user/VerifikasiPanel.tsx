@@ -25,7 +25,7 @@ export default function VerifikasiPanel() {
   // Verification status and feedback
   const [verificationStatus, setVerificationStatus] = useState<string | null>(null);
   const [verifikatorMessage, setVerifikatorMessage] = useState("");
-  const [dataCompletenessRank, setDataCompletenessRank] = useState<number | null>(null);
+  const [applicationRank, setApplicationRank] = useState<number | null>(null);
   const [verifiedAt, setVerifiedAt] = useState("");
   const [isLoadingStatus, setIsLoadingStatus] = useState(true);
 
@@ -109,7 +109,7 @@ export default function VerifikasiPanel() {
             
             const verificationData = detailResponse.data;
             setVerifikatorMessage(verificationData.verifikator_message || "");
-            setDataCompletenessRank(verificationData.data_completeness_rank || null);
+            setApplicationRank(verificationData.rank || null);
             setVerifiedAt(verificationData.verified_at || "");
           } catch (detailError) {
             console.error("Failed to fetch verification details:", detailError);
@@ -162,61 +162,19 @@ export default function VerifikasiPanel() {
     setter(file);
   };
 
-  // Convert file to base64 string
-  const fileToBase64 = (file: File): Promise<string> => {
-    return new Promise((resolve, reject) => {
-      // Compress image before converting to base64
-      const reader = new FileReader();
-      const img = new Image();
-      
-      reader.onload = (event) => {
-        img.onload = () => {
-          // Create canvas for image compression
-          const canvas = document.createElement('canvas');
-          
-          // Calculate new dimensions (max width/height 600px - lebih kecil)
-          let width = img.width;
-          let height = img.height;
-          const maxSize = 600;
-          
-          if (width > height && width > maxSize) {
-            height = Math.round((height * maxSize) / width);
-            width = maxSize;
-          } else if (height > maxSize) {
-            width = Math.round((width * maxSize) / height);
-            height = maxSize;
-          }
-          
-          // Resize image
-          canvas.width = width;
-          canvas.height = height;
-          const ctx = canvas.getContext('2d');
-          
-          if (!ctx) {
-            reject(new Error("Could not get canvas context"));
-            return;
-          }
-          
-          ctx.drawImage(img, 0, 0, width, height);
-          
-          // Get compressed image as base64 with kualitas lebih rendah (0.5 = 50%)
-          const compressedBase64 = canvas.toDataURL('image/jpeg', 0.5);
-          resolve(compressedBase64);
-        };
-        
-        img.onerror = () => {
-          reject(new Error("Failed to load image"));
-        };
-        
-        img.src = event.target?.result as string;
-      };
-      
-      reader.onerror = (error) => {
-        reject(error);
-      };
-      
-      reader.readAsDataURL(file);
+  // Upload a document to the document store and return its ID; the
+  // submission references documents by ID instead of carrying the files
+  const uploadDocument = async (file: File, type: string): Promise<number> => {
+    const form = new FormData();
+    form.append("type", type);
+    form.append("file", file);
+    const response = await axios.post(`${API_URL}/api/documents`, form, {
+      headers: {
+        'Authorization': `Bearer ${localStorage.getItem('token')}`
+      },
+      timeout: 30000
     });
+    return response.data.id;
   };
 
   // Validate form data
@@ -347,55 +305,37 @@ export default function VerifikasiPanel() {
         }
       }
       
-      // Convert files to base64 strings
-      let fotoKtpBase64 = "";
-      let fotoKkBase64 = "";
-      let fotoIjazahBase64 = "";
-      let fotoSklBase64 = "";
-      let fotoSertifikatBase64 = "";
-      
+      // Upload the documents first, then reference them in the submission
+      const documents: Array<{ type: string; document_id: number }> = [];
       try {
-        if (fotoKtp) {
-          console.log("Memproses foto KTP...");
-          fotoKtpBase64 = await fileToBase64(fotoKtp);
-        }
-        
-        if (fotoKk) {
-          console.log("Memproses foto KK...");
-          fotoKkBase64 = await fileToBase64(fotoKk);
-        }
-        
-        if (fotoIjazah) {
-          console.log("Memproses foto Ijazah...");
-          fotoIjazahBase64 = await fileToBase64(fotoIjazah);
-        }
-        
-        if (fotoSkl) {
-          console.log("Memproses foto SKL...");
-          fotoSklBase64 = await fileToBase64(fotoSkl);
-        }
-        
-        if (fotoSertifikat) {
-          console.log("Memproses foto Sertifikat...");
-          fotoSertifikatBase64 = await fileToBase64(fotoSertifikat);
+        const files: Array<[File | null, string]> = [
+          [fotoKtp, "ktp"],
+          [fotoKk, "kk"],
+          [fotoIjazah, "ijazah"],
+          [fotoSkl, "skl"],
+          [fotoSertifikat, "sertifikat"],
+        ];
+        for (const [file, type] of files) {
+          if (file) {
+            console.log(`Mengunggah dokumen ${type}...`);
+            documents.push({ type, document_id: await uploadDocument(file, type) });
+          }
         }
-      } catch (imgError) {
-        console.error("Error processing images:", imgError);
-        setError("Gagal memproses gambar. Pastikan file gambar valid dan ukurannya tidak terlalu besar.");
+      } catch (uploadError: any) {
+        console.error("Error uploading documents:", uploadError);
+        setError("Gagal mengunggah dokumen: " + (uploadError.response?.data?.error || uploadError.message));
         setIsSubmitting(false);
         return;
       }
       
       // Prepare data payload
       const payload = {
-        user_id: parseInt(user.id),
         nik,
         nisn,
         nama_lengkap: namaLengkap,
         tanggal_lahir: tanggalLahir,
         tempat_lahir: tempatLahir,
         alamat,
-        foto_ktp: fotoKtpBase64,
         nomor_telepon: nomorTelepon,
         email,
         instagram,
@@ -415,15 +355,12 @@ export default function VerifikasiPanel() {
         pekerjaan_ayah: pekerjaanAyah,
         pendapatan_ayah: pendapatanAyah,
         alamat_keluarga: alamatKeluarga,
-        foto_kk: fotoKkBase64,
         saudara: JSON.stringify(saudara),
         asal_sekolah: asalSekolah,
         tahun_lulus: tahunLulus,
         nilai_semester_1: nilaiSemester1,
         nilai_semester_2: nilaiSemester2,
-        foto_ijazah: fotoIjazahBase64,
-        foto_skl: fotoSklBase64,
-        foto_sertifikat: fotoSertifikatBase64,
+        documents,
       };
       
       // Send data to backend
@@ -617,22 +554,22 @@ export default function VerifikasiPanel() {
                     <p className="text-sm font-medium text-gray-700 mb-2">Feedback dari Verifikator:</p>
                     <p className="text-sm text-gray-600 mb-3">{verifikatorMessage}</p>
                     
-                    {dataCompletenessRank && (
+                    {applicationRank && (
                       <div className="flex items-center">
-                        <span className="text-sm text-gray-600 mr-2">Rating Kelengkapan Data:</span>
+                        <span className="text-sm text-gray-600 mr-2">Peringkat Pengajuan:</span>
                         <div className="flex items-center">
                           {[...Array(10)].map((_, i) => (
                             <div
                               key={i}
                               className={`w-3 h-3 rounded-full mr-1 ${
-                                i < dataCompletenessRank 
+                                i < applicationRank 
                                   ? 'bg-blue-500' 
                                   : 'bg-gray-300'
                               }`}
                             />
                           ))}
                           <span className="text-sm text-gray-600 ml-2">
-                            {dataCompletenessRank}/10
+                            {applicationRank}/10
                           </span>
                         </div>
                       </div>
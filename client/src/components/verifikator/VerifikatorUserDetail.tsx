@@ -51,7 +51,10 @@ interface VerificationData {
   foto_sertifikat: string;
   status: "pending" | "approved" | "rejected";
   verifikator_message?: string;
-  data_completeness_rank?: number;
+  data_completeness?: number;
+  merit_rank?: number;
+  override_rank?: number;
+  rank?: number;
   verifikator_id?: number;
   verified_at?: string;
   created_at: string;